@@ -0,0 +1,32 @@
+// Package api holds the spec of the HTTP API and its docs page.
+package api
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// OpenAPI is the OpenAPI spec of the HTTP API in YAML.
+//
+//go:embed openapi.yml
+var OpenAPI []byte
+
+// Docs is the docs page rendering the spec served at /openapi.json.
+//
+//go:embed docs.html
+var Docs []byte
+
+// LoadOpenAPI parses the spec of the HTTP API and validates it.
+func LoadOpenAPI() (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(OpenAPI)
+	if err != nil {
+		return nil, fmt.Errorf("load spec: %w", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("validate spec: %w", err)
+	}
+	return doc, nil
+}
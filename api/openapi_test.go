@@ -0,0 +1,16 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOpenAPI(t *testing.T) {
+	doc, err := LoadOpenAPI()
+	require.NoError(t, err)
+
+	assert.NotNil(t, doc.Paths.Find("/kv/{key}"))
+	assert.NotNil(t, doc.Components.Schemas["SuccessResponse"])
+}
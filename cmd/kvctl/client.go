@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// watchRetryInterval is the interval of reconnecting an interrupted watch.
+const watchRetryInterval = time.Second
+
+// apiError is an error response of the server.
+type apiError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s (HTTP %d)", e.Message, e.StatusCode)
+}
+
+// connError is a failure to reach the server.
+type connError struct {
+	err error
+}
+
+func (e *connError) Error() string {
+	return e.err.Error()
+}
+
+func (e *connError) Unwrap() error {
+	return e.err
+}
+
+// record is a key with its value. The value of a blob is its data.
+type record struct {
+	Key         string
+	ContentType string
+	Value       any
+	Data        []byte
+}
+
+// blob reports whether the value is a blob.
+func (r *record) blob() bool {
+	return r.ContentType != ""
+}
+
+// event is a change of a key streamed by watch.
+type event struct {
+	ID    uint64    `json:"id"`
+	Type  string    `json:"type"`
+	Key   string    `json:"key"`
+	Value any       `json:"value,omitempty"`
+	Time  time.Time `json:"time"`
+}
+
+// client is the client of the KV HTTP API.
+type client struct {
+	http    *http.Client
+	baseURL string
+	token   string
+}
+
+func newClient(p profile) *client {
+	return &client{
+		http:    &http.Client{},
+		baseURL: strings.TrimSuffix(p.Server, "/") + p.KVPath,
+		token:   p.Token,
+	}
+}
+
+// Get returns the value of the key.
+func (c *client) Get(ctx context.Context, key string) (*record, error) {
+	resp, err := c.do(ctx, http.MethodGet, c.keyURL(key), "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if !isJSON(resp.Header.Get("Content-Type")) {
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, &connError{err: err}
+		}
+		return &record{Key: key, ContentType: resp.Header.Get("Content-Type"), Data: data}, nil
+	}
+
+	var details struct {
+		Value any `json:"value"`
+	}
+	if err := decodeDetails(resp.Body, &details); err != nil {
+		return nil, err
+	}
+	return &record{Key: key, Value: details.Value}, nil
+}
+
+// Set sets the value of a new key.
+func (c *client) Set(ctx context.Context, key string, value any) error {
+	return c.doJSON(ctx, http.MethodPost, c.baseURL, map[string]any{"key": key, "value": value}, nil)
+}
+
+// Update updates the value of an existing key.
+func (c *client) Update(ctx context.Context, key string, value any) error {
+	return c.doJSON(ctx, http.MethodPut, c.keyURL(key), map[string]any{"value": value}, nil)
+}
+
+// PutBlob stores the blob value of the key, creating the key if needed.
+func (c *client) PutBlob(ctx context.Context, key, contentType string, data []byte) error {
+	resp, err := c.do(ctx, http.MethodPut, c.keyURL(key), contentType, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Delete removes the key.
+func (c *client) Delete(ctx context.Context, key string) error {
+	return c.doJSON(ctx, http.MethodDelete, c.keyURL(key), nil, nil)
+}
+
+// Keys returns a page of keys starting with the prefix after the cursor and
+// the cursor of the next page, empty for the last page.
+func (c *client) Keys(ctx context.Context, prefix, cursor string, limit int) ([]string, string, error) {
+	query := url.Values{}
+	query.Set("prefix", prefix)
+	query.Set("cursor", cursor)
+	query.Set("limit", strconv.Itoa(limit))
+
+	var page struct {
+		Keys []string `json:"keys"`
+		Next string   `json:"next"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, c.baseURL+"/_keys?"+query.Encode(), nil, &page); err != nil {
+		return nil, "", err
+	}
+	return page.Keys, page.Next, nil
+}
+
+// Export writes the records of the keys starting with the prefix to w as
+// newline-delimited JSON.
+func (c *client) Export(ctx context.Context, prefix string, w io.Writer) error {
+	resp, err := c.do(ctx, http.MethodGet, c.baseURL+"/_export?"+url.Values{"prefix": {prefix}}.Encode(), "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// The server aborts the stream if it fails to export every key.
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return &connError{err: err}
+	}
+	return nil
+}
+
+// Import writes the newline-delimited JSON records read from r, doing what
+// the mode tells with the keys that exist.
+func (c *client) Import(ctx context.Context, mode string, r io.Reader) (importResult, error) {
+	var res importResult
+	resp, err := c.do(ctx, http.MethodPost, c.baseURL+"/_import?"+url.Values{"mode": {mode}}.Encode(), "application/x-ndjson", r)
+	if err != nil {
+		return res, err
+	}
+	defer resp.Body.Close()
+
+	err = decodeDetails(resp.Body, &res)
+	return res, err
+}
+
+// Watch calls fn for the changes of the key, or of the keys starting with
+// the prefix if the key is empty, following the event with afterID. An
+// interrupted stream is resumed from the last event until ctx is done.
+func (c *client) Watch(ctx context.Context, key, prefix string, afterID uint64, fn func(event) error) error {
+	for {
+		err := c.watch(ctx, key, prefix, &afterID, fn)
+		if ctx.Err() != nil {
+			return nil
+		}
+		var connErr *connError
+		if err != nil && !errors.As(err, &connErr) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(watchRetryInterval):
+		}
+	}
+}
+
+func (c *client) watch(ctx context.Context, key, prefix string, afterID *uint64, fn func(event) error) error {
+	target := c.baseURL + "/_watch?" + url.Values{"prefix": {prefix}}.Encode()
+	if key != "" {
+		target = c.keyURL(key) + "/watch"
+	}
+	if *afterID > 0 {
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target += sep + "last_event_id=" + strconv.FormatUint(*afterID, 10)
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, target, "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), 64<<20)
+
+	var (
+		eventType string
+		data      strings.Builder
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() == 0 {
+				continue
+			}
+			var e event
+			if err := json.Unmarshal([]byte(data.String()), &e); err != nil {
+				return fmt.Errorf("decode event: %w", err)
+			}
+			data.Reset()
+			e.ID, e.Type = *afterID, eventType
+			if err := fn(e); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "id:"):
+			id, err := strconv.ParseUint(strings.TrimSpace(line[len("id:"):]), 10, 64)
+			if err != nil {
+				return fmt.Errorf("decode event ID: %w", err)
+			}
+			*afterID = id
+		case strings.HasPrefix(line, "event:"):
+			eventType = strings.TrimSpace(line[len("event:"):])
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(line[len("data:"):], " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return &connError{err: err}
+	}
+	return &connError{err: io.ErrUnexpectedEOF}
+}
+
+func (c *client) keyURL(key string) string {
+	return c.baseURL + "/" + url.PathEscape(key)
+}
+
+// doJSON sends the request with the JSON body, if any, and decodes the
+// details of the response into res, if any.
+func (c *client) doJSON(ctx context.Context, method, target string, body, res any) error {
+	var (
+		reader      io.Reader
+		contentType string
+	)
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	resp, err := c.do(ctx, method, target, contentType, reader)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if res == nil {
+		return nil
+	}
+	return decodeDetails(resp.Body, res)
+}
+
+// do sends the request and returns the response if it is successful.
+func (c *client) do(ctx context.Context, method, target, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, &connError{err: err}
+	}
+	if resp.StatusCode < http.StatusBadRequest {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	return nil, responseError(resp)
+}
+
+// responseError returns the error of the error response, the message of
+// which is its details.
+func responseError(resp *http.Response) error {
+	apiErr := &apiError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+
+	var body struct {
+		Details any `json:"details"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return apiErr
+	}
+	switch details := body.Details.(type) {
+	case string:
+		apiErr.Message = details
+	case map[string]any:
+		if message, ok := details["message"].(string); ok {
+			apiErr.Message = message
+		}
+	}
+	return apiErr
+}
+
+// decodeDetails decodes the details of the success response. Numbers are
+// kept as json.Number not to lose the precision of large integers.
+func decodeDetails(r io.Reader, res any) error {
+	var body struct {
+		Details json.RawMessage `json:"details"`
+	}
+	if err := json.NewDecoder(r).Decode(&body); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body.Details))
+	dec.UseNumber()
+	if err := dec.Decode(res); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// listPageSize is the number of keys requested at once.
+const listPageSize = 1000
+
+// Import modes for the keys that already exist.
+const (
+	importFail      = "fail"
+	importSkip      = "skip"
+	importOverwrite = "overwrite"
+)
+
+// setup parses the command line of the command, with the flags defined by
+// define, and returns the client, the printer and the arguments.
+func setup(env *env, name string, args []string, define func(fs *flag.FlagSet)) (*client, *printer, []string, error) {
+	var opts options
+	fs := newFlagSet(env, name, &opts)
+	if define != nil {
+		define(fs)
+	}
+
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	p, err := resolveProfile(&opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	out, err := newPrinter(opts.output, env.stdout)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return newClient(p), out, args, nil
+}
+
+func runGet(ctx context.Context, env *env, args []string) error {
+	c, out, args, err := setup(env, "get", args, nil)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("%w: get takes a key", errUsage)
+	}
+
+	rec, err := c.Get(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return out.Record(rec)
+}
+
+// valueFlags are the flags of the commands writing a value.
+type valueFlags struct {
+	file        string
+	asString    bool
+	contentType string
+}
+
+func (f *valueFlags) define(fs *flag.FlagSet) {
+	fs.StringVar(&f.file, "f", "", "file to read the value from, - for stdin")
+	fs.BoolVar(&f.asString, "string", false, "store the value as a string even if it is valid JSON")
+	fs.StringVar(&f.contentType, "content-type", "", "store the value as a blob of the content type")
+}
+
+// value returns the value of the command line arguments, which are the key
+// and an optional value.
+func (f *valueFlags) value(env *env, args []string) (any, error) {
+	var (
+		data []byte
+		err  error
+	)
+	switch {
+	case len(args) == 2 && f.file != "":
+		return nil, fmt.Errorf("%w: the value cannot be given with -f", errUsage)
+	case len(args) == 2 && args[1] != "-":
+		data = []byte(args[1])
+	case f.file != "" && f.file != "-":
+		data, err = os.ReadFile(f.file)
+	default:
+		data, err = io.ReadAll(env.stdin)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read value: %w", err)
+	}
+
+	if f.contentType != "" {
+		return &record{Key: args[0], ContentType: f.contentType, Data: data}, nil
+	}
+	return parseValue(data, f.asString), nil
+}
+
+// parseValue returns the decoded JSON value of the data or the data as a
+// string, without a trailing newline, if it is not valid JSON.
+func parseValue(data []byte, asString bool) any {
+	if !asString {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var value any
+		if err := dec.Decode(&value); err == nil {
+			// The data must hold a single value.
+			if _, err := dec.Token(); errors.Is(err, io.EOF) {
+				return value
+			}
+		}
+	}
+
+	s := strings.TrimSuffix(string(data), "\n")
+	return strings.TrimSuffix(s, "\r")
+}
+
+func runSet(ctx context.Context, env *env, args []string) error {
+	var vf valueFlags
+	c, _, args, err := setup(env, "set", args, vf.define)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 && len(args) != 2 {
+		return fmt.Errorf("%w: set takes a key and an optional value", errUsage)
+	}
+
+	value, err := vf.value(env, args)
+	if err != nil {
+		return err
+	}
+	if _, ok := value.(*record); ok {
+		return fmt.Errorf("%w: blobs are written with update, which creates the key if needed", errUsage)
+	}
+	return c.Set(ctx, args[0], value)
+}
+
+func runUpdate(ctx context.Context, env *env, args []string) error {
+	var vf valueFlags
+	c, _, args, err := setup(env, "update", args, vf.define)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 && len(args) != 2 {
+		return fmt.Errorf("%w: update takes a key and an optional value", errUsage)
+	}
+
+	value, err := vf.value(env, args)
+	if err != nil {
+		return err
+	}
+	if blob, ok := value.(*record); ok {
+		return c.PutBlob(ctx, args[0], blob.ContentType, blob.Data)
+	}
+	return c.Update(ctx, args[0], value)
+}
+
+func runDelete(ctx context.Context, env *env, args []string) error {
+	c, _, args, err := setup(env, "delete", args, nil)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("%w: delete takes a key", errUsage)
+	}
+
+	return c.Delete(ctx, args[0])
+}
+
+func runList(ctx context.Context, env *env, args []string) error {
+	var (
+		prefix string
+		limit  int
+	)
+	c, out, args, err := setup(env, "list", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&prefix, "prefix", "", "prefix of the keys")
+		fs.IntVar(&limit, "limit", 0, "maximum number of keys, all of them if zero")
+	})
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 || limit < 0 {
+		return fmt.Errorf("%w: list takes no arguments and a non-negative limit", errUsage)
+	}
+
+	var keys []string
+	err = scanKeys(ctx, c, prefix, func(key string) error {
+		if limit > 0 && len(keys) == limit {
+			return errStopScan
+		}
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return out.Keys(keys)
+}
+
+// errStopScan stops scanKeys without an error.
+var errStopScan = errors.New("stop scan")
+
+// scanKeys calls fn for every key starting with the prefix.
+func scanKeys(ctx context.Context, c *client, prefix string, fn func(key string) error) error {
+	cursor := ""
+	for {
+		keys, next, err := c.Keys(ctx, prefix, cursor, listPageSize)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := fn(key); err != nil {
+				if errors.Is(err, errStopScan) {
+					return nil
+				}
+				return err
+			}
+		}
+		if next == "" {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+func runWatch(ctx context.Context, env *env, args []string) error {
+	var (
+		prefix string
+		after  uint64
+	)
+	c, out, args, err := setup(env, "watch", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&prefix, "prefix", "", "prefix of the keys, every key if empty")
+		fs.Uint64Var(&after, "after", 0, "ID of the last event received, only new events if zero")
+	})
+	if err != nil {
+		return err
+	}
+	if len(args) > 1 || (len(args) == 1 && prefix != "") {
+		return fmt.Errorf("%w: watch takes either a key or -prefix", errUsage)
+	}
+
+	key := ""
+	if len(args) == 1 {
+		key = args[0]
+	}
+	return c.Watch(ctx, key, prefix, after, out.Event)
+}
+
+func runExport(ctx context.Context, env *env, args []string) error {
+	var (
+		prefix string
+		file   string
+	)
+	c, _, args, err := setup(env, "export", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&prefix, "prefix", "", "prefix of the keys")
+		fs.StringVar(&file, "f", "", "file to write to, stdout by default")
+	})
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("%w: export takes no arguments", errUsage)
+	}
+
+	if file == "" || file == "-" {
+		return c.Export(ctx, prefix, env.stdout)
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := c.Export(ctx, prefix, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// importResult is the summary of an import.
+type importResult struct {
+	Created int            `json:"created"`
+	Updated int            `json:"updated"`
+	Skipped int            `json:"skipped"`
+	Failed  int            `json:"failed"`
+	Errors  []importFailed `json:"errors,omitempty"`
+}
+
+// importFailed is a record that was not imported.
+type importFailed struct {
+	Line    int    `json:"line"`
+	Key     string `json:"key,omitempty"`
+	Message string `json:"message"`
+}
+
+func runImport(ctx context.Context, env *env, args []string) error {
+	var (
+		file string
+		mode string
+	)
+	c, out, args, err := setup(env, "import", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&file, "f", "", "file to read from, stdin by default")
+		fs.StringVar(&mode, "mode", importFail, "what to do with existing keys: fail, skip or overwrite")
+	})
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("%w: import takes no arguments", errUsage)
+	}
+	if mode != importFail && mode != importSkip && mode != importOverwrite {
+		return fmt.Errorf("%w: unknown import mode %q", errUsage, mode)
+	}
+
+	r := env.stdin
+	if file != "" && file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	res, err := c.Import(ctx, mode, r)
+	if err != nil {
+		return err
+	}
+	if err := out.ImportResult(res); err != nil {
+		return err
+	}
+
+	if res.Failed > 0 {
+		return fmt.Errorf("%d records failed to import", res.Failed)
+	}
+	return nil
+}
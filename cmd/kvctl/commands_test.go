@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer is an in-memory KV HTTP API.
+type fakeServer struct {
+	values map[string]any
+	blobs  map[string]string
+	auth   string
+}
+
+func newFakeServer(t *testing.T) (*fakeServer, *httptest.Server) {
+	t.Helper()
+
+	fs := &fakeServer{values: map[string]any{}, blobs: map[string]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/kv", func(w http.ResponseWriter, r *http.Request) {
+		fs.auth = r.Header.Get("Authorization")
+		var req struct {
+			Key   string `json:"key"`
+			Value any    `json:"value"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if _, ok := fs.values[req.Key]; ok {
+			writeTestJSON(w, http.StatusConflict, "error", "key already exists")
+			return
+		}
+		fs.values[req.Key] = req.Value
+		writeTestJSON(w, http.StatusCreated, "success", map[string]any{"key": req.Key})
+	})
+	mux.HandleFunc("GET /api/v1/kv/{key}", func(w http.ResponseWriter, r *http.Request) {
+		key := r.PathValue("key")
+		if key == "boom" {
+			writeTestJSON(w, http.StatusInternalServerError, "error", "internal error")
+			return
+		}
+		if data, ok := fs.blobs[key]; ok {
+			w.Header().Set("Content-Type", "image/png")
+			io.WriteString(w, data)
+			return
+		}
+		value, ok := fs.values[key]
+		if !ok {
+			writeTestJSON(w, http.StatusNotFound, "error", "key not found")
+			return
+		}
+		writeTestJSON(w, http.StatusOK, "success", map[string]any{"key": key, "value": value})
+	})
+	mux.HandleFunc("PUT /api/v1/kv/{key}", func(w http.ResponseWriter, r *http.Request) {
+		key := r.PathValue("key")
+		if r.Header.Get("Content-Type") != "application/json" {
+			data, _ := io.ReadAll(r.Body)
+			fs.blobs[key] = string(data)
+			writeTestJSON(w, http.StatusOK, "success", map[string]any{"key": key})
+			return
+		}
+		if _, ok := fs.values[key]; !ok {
+			writeTestJSON(w, http.StatusNotFound, "error", "key not found")
+			return
+		}
+		var req struct {
+			Value any `json:"value"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		fs.values[key] = req.Value
+		writeTestJSON(w, http.StatusOK, "success", map[string]any{"key": key})
+	})
+	mux.HandleFunc("GET /api/v1/kv/_keys", func(w http.ResponseWriter, r *http.Request) {
+		keys := []string{}
+		for key := range fs.values {
+			if strings.HasPrefix(key, r.URL.Query().Get("prefix")) {
+				keys = append(keys, key)
+			}
+		}
+		writeTestJSON(w, http.StatusOK, "success", map[string]any{"keys": keys})
+	})
+	mux.HandleFunc("GET /api/v1/kv/_export", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for key, value := range fs.values {
+			if strings.HasPrefix(key, r.URL.Query().Get("prefix")) {
+				json.NewEncoder(w).Encode(map[string]any{"key": key, "value": value})
+			}
+		}
+	})
+	mux.HandleFunc("POST /api/v1/kv/_import", func(w http.ResponseWriter, r *http.Request) {
+		res := importResult{}
+		dec := json.NewDecoder(r.Body)
+		for line := 1; ; line++ {
+			var rec struct {
+				Key   string `json:"key"`
+				Value any    `json:"value"`
+			}
+			if err := dec.Decode(&rec); err != nil {
+				break
+			}
+			_, exists := fs.values[rec.Key]
+			switch mode := r.URL.Query().Get("mode"); {
+			case !exists:
+				res.Created++
+			case mode == "skip":
+				res.Skipped++
+				continue
+			case mode == "overwrite":
+				res.Updated++
+			default:
+				res.Failed++
+				res.Errors = append(res.Errors, importFailed{Line: line, Key: rec.Key, Message: "key already exists"})
+				continue
+			}
+			fs.values[rec.Key] = rec.Value
+		}
+		writeTestJSON(w, http.StatusOK, "success", res)
+	})
+	mux.HandleFunc("GET /api/v1/kv/{key}/watch", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, ": heartbeat\n\nid: 7\nevent: set\ndata: {\"key\":\"k\",\"value\":1,\"time\":\"2025-01-01T00:00:00Z\"}\n\n")
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return fs, server
+}
+
+func writeTestJSON(w http.ResponseWriter, code int, status string, details any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]any{"status": status, "code": code, "details": details})
+}
+
+func runTest(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	env := &env{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr}
+	code := run(context.Background(), env, append(args, "-config", filepath.Join(t.TempDir(), "missing.yml")))
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun(t *testing.T) {
+	fs, server := newFakeServer(t)
+
+	code, _, _ := runTest(t, "", "set", "user:1", `{"age": 42}`, "-server", server.URL, "-token", "secret")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, map[string]any{"age": float64(42)}, fs.values["user:1"])
+	assert.Equal(t, "Bearer secret", fs.auth)
+
+	code, _, stderr := runTest(t, "", "set", "user:1", "x", "-server", server.URL)
+	assert.Equal(t, exitConflict, code)
+	assert.Contains(t, stderr, "key already exists")
+
+	code, _, _ = runTest(t, "alice\n", "set", "-server", server.URL, "name")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "alice", fs.values["name"])
+
+	code, stdout, _ := runTest(t, "", "get", "user:1", "-server", server.URL)
+	assert.Equal(t, exitOK, code)
+	assert.JSONEq(t, `{"key":"user:1","value":{"age":42}}`, stdout)
+
+	code, stdout, _ = runTest(t, "", "get", "user:1", "-server", server.URL, "-o", "yaml")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "key: user:1\nvalue:\n  age: 42\n", stdout)
+
+	code, stdout, _ = runTest(t, "", "get", "name", "-server", server.URL, "-o", "raw")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "alice\n", stdout)
+
+	code, _, _ = runTest(t, "", "get", "missing", "-server", server.URL)
+	assert.Equal(t, exitNotFound, code)
+
+	code, _, _ = runTest(t, "\x89PNG", "update", "avatar", "-content-type", "image/png", "-server", server.URL)
+	assert.Equal(t, exitOK, code)
+	code, stdout, _ = runTest(t, "", "get", "avatar", "-server", server.URL, "-o", "raw")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "\x89PNG", stdout)
+
+	code, stdout, _ = runTest(t, "", "list", "-prefix", "user:", "-server", server.URL, "-o", "table")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "KEY\nuser:1\n", stdout)
+
+	code, _, _ = runTest(t, "", "get", "boom", "-server", server.URL)
+	assert.Equal(t, exitServer, code)
+
+	code, _, _ = runTest(t, "", "get", "-server", server.URL)
+	assert.Equal(t, exitUsage, code)
+
+	code, _, _ = runTest(t, "", "frobnicate")
+	assert.Equal(t, exitUsage, code)
+}
+
+func TestRun_import(t *testing.T) {
+	fs, server := newFakeServer(t)
+	fs.values["a"] = "old"
+
+	input := `{"key":"a","value":"new"}` + "\n" + `{"key":"b","value":[1,2]}` + "\n"
+
+	code, stdout, _ := runTest(t, input, "import", "-mode", "skip", "-server", server.URL)
+	assert.Equal(t, exitOK, code)
+	assert.JSONEq(t, `{"created":1,"updated":0,"skipped":1,"failed":0}`, stdout)
+	assert.Equal(t, "old", fs.values["a"])
+
+	code, stdout, stderr := runTest(t, input, "import", "-server", server.URL, "-o", "raw")
+	assert.Equal(t, exitError, code)
+	assert.Equal(t, "created 0, updated 0, skipped 0, failed 2\nline 1: a: key already exists\nline 2: b: key already exists\n", stdout)
+	assert.Contains(t, stderr, "2 records failed to import")
+
+	code, stdout, _ = runTest(t, input, "import", "-mode", "overwrite", "-server", server.URL)
+	assert.Equal(t, exitOK, code)
+	assert.JSONEq(t, `{"created":0,"updated":2,"skipped":0,"failed":0}`, stdout)
+	assert.Equal(t, "new", fs.values["a"])
+
+	code, stdout, _ = runTest(t, "", "export", "-prefix", "b", "-server", server.URL)
+	assert.Equal(t, exitOK, code)
+	assert.JSONEq(t, `{"key":"b","value":[1,2]}`, stdout)
+}
+
+func TestClient_Watch(t *testing.T) {
+	_, server := newFakeServer(t)
+	c := newClient(profile{Server: server.URL, KVPath: defaultKVPath})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var got event
+	err := c.Watch(ctx, "k", "", 0, func(e event) error {
+		got = e
+		cancel()
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), got.ID)
+	assert.Equal(t, "set", got.Type)
+	assert.Equal(t, "k", got.Key)
+	assert.Equal(t, float64(1), got.Value)
+}
+
+func TestParseValue(t *testing.T) {
+	assert.Equal(t, json.Number("42"), parseValue([]byte("42"), false))
+	assert.Equal(t, "42", parseValue([]byte("42"), true))
+	assert.Equal(t, "hello world", parseValue([]byte("hello world\n"), false))
+	assert.Equal(t, "1 2", parseValue([]byte("1 2"), false))
+	assert.Equal(t, map[string]any{"a": true}, parseValue([]byte(`{"a": true}`), false))
+}
+
+func TestLoadProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+current: local
+profiles:
+  local:
+    server: http://localhost:8008
+  prod:
+    server: https://kv.example.com
+    token: secret
+`), 0o600))
+
+	p, err := resolveProfile(&options{config: path})
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8008", p.Server)
+	assert.Equal(t, defaultKVPath, p.KVPath)
+
+	p, err = resolveProfile(&options{config: path, profile: "prod", server: "https://other"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://other", p.Server)
+	assert.Equal(t, "secret", p.Token)
+
+	_, err = resolveProfile(&options{config: path, profile: "staging"})
+	assert.ErrorIs(t, err, errUsage)
+
+	p, err = resolveProfile(&options{config: filepath.Join(t.TempDir(), "missing.yml")})
+	require.NoError(t, err)
+	assert.Equal(t, defaultServer, p.Server)
+}
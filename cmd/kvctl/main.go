@@ -0,0 +1,140 @@
+// Command kvctl is the command-line client of the KV storage HTTP API.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Exit codes.
+const (
+	exitOK       = 0
+	exitError    = 1
+	exitUsage    = 2
+	exitNotFound = 3
+	exitConflict = 4
+	exitServer   = 5
+)
+
+const usage = `kvctl is the command-line client of the KV storage.
+
+Usage:
+  kvctl <command> [flags] [arguments]
+
+Commands:
+  get <key>                 print the value of the key
+  set <key> [value]         set the value of a new key
+  update <key> [value]      update the value of an existing key
+  delete <key>              delete the key
+  list                      list the keys
+  watch [key]               stream the changes of the key or of the keys with a prefix
+  export                    write the keys and values as newline-delimited JSON
+  import                    read the keys and values from newline-delimited JSON
+
+Values are read from the argument, from the file of -f or from stdin if
+neither is given. They are parsed as JSON and stored as strings if they are
+not valid JSON.
+
+Flags of every command:
+  -profile name    profile of the config file, its current one by default
+  -server url      server URL, overrides the profile
+  -token token     bearer token, overrides the profile
+  -config path     config file, $KVCTL_CONFIG or kvctl/config.yml in the user config directory by default
+  -o format        output format: json, yaml, table or raw (default json)
+
+Exit codes:
+  0  success
+  1  error
+  2  invalid usage
+  3  key not found
+  4  conflict, e.g. the key already exists
+  5  server error or the server is unreachable
+`
+
+// errUsage is returned for invalid command lines.
+var errUsage = errors.New("invalid usage")
+
+// command runs a kvctl command with its arguments.
+type command func(ctx context.Context, env *env, args []string) error
+
+var commands = map[string]command{
+	"get":    runGet,
+	"set":    runSet,
+	"update": runUpdate,
+	"delete": runDelete,
+	"list":   runList,
+	"watch":  runWatch,
+	"export": runExport,
+	"import": runImport,
+}
+
+// env is the environment of a command.
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	code := run(ctx, &env{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}, os.Args[1:])
+	stop()
+	os.Exit(code)
+}
+
+func run(ctx context.Context, env *env, args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprint(env.stdout, usage)
+		return exitOK
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(env.stderr, "kvctl: unknown command %q\n\n%s", args[0], usage)
+		return exitUsage
+	}
+
+	err := cmd(ctx, env, args[1:])
+	if err == nil {
+		return exitOK
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	fmt.Fprintf(env.stderr, "kvctl %s: %s\n", args[0], err)
+	return exitCode(err)
+}
+
+// exitCode returns the exit code of the command error.
+func exitCode(err error) int {
+	if errors.Is(err, errUsage) {
+		return exitUsage
+	}
+
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		var connErr *connError
+		if errors.As(err, &connErr) {
+			return exitServer
+		}
+		return exitError
+	}
+
+	switch {
+	case apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusGone:
+		return exitNotFound
+	case apiErr.StatusCode == http.StatusConflict || apiErr.StatusCode == http.StatusPreconditionFailed:
+		return exitConflict
+	case apiErr.StatusCode >= http.StatusInternalServerError:
+		return exitServer
+	default:
+		return exitError
+	}
+}
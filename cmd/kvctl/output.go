@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats.
+const (
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+	formatTable = "table"
+	formatRaw   = "raw"
+)
+
+// printer writes the command results in the output format.
+type printer struct {
+	format string
+	w      io.Writer
+}
+
+func newPrinter(format string, w io.Writer) (*printer, error) {
+	switch format {
+	case formatJSON, formatYAML, formatTable, formatRaw:
+		return &printer{format: format, w: w}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown output format %q", errUsage, format)
+	}
+}
+
+// Record prints the key with its value. The raw format prints only the
+// value: strings and blobs as is and other values in their JSON encoding.
+func (p *printer) Record(r *record) error {
+	if p.format == formatRaw {
+		if r.blob() {
+			_, err := p.w.Write(r.Data)
+			return err
+		}
+		return p.line(rawValue(r.Value))
+	}
+
+	doc := map[string]any{"key": r.Key, "value": r.Value}
+	if r.blob() {
+		doc["content_type"] = r.ContentType
+		doc["value"] = base64.StdEncoding.EncodeToString(r.Data)
+	}
+
+	if p.format == formatTable {
+		tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "KEY\tVALUE")
+		fmt.Fprintf(tw, "%s\t%s\n", r.Key, rawValue(doc["value"]))
+		return tw.Flush()
+	}
+	return p.document(doc)
+}
+
+// Keys prints the keys.
+func (p *printer) Keys(keys []string) error {
+	switch p.format {
+	case formatRaw:
+		for _, key := range keys {
+			if err := p.line(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	case formatTable:
+		tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "KEY")
+		for _, key := range keys {
+			fmt.Fprintln(tw, key)
+		}
+		return tw.Flush()
+	default:
+		if keys == nil {
+			keys = []string{}
+		}
+		return p.document(keys)
+	}
+}
+
+// Event prints the change event as it arrives: JSON events one per line,
+// YAML ones as separate documents and table ones as rows without a header.
+func (p *printer) Event(e event) error {
+	switch p.format {
+	case formatRaw:
+		return p.line(rawValue(e.Value))
+	case formatTable:
+		return p.line(fmt.Sprintf("%d\t%s\t%s\t%s", e.ID, e.Type, e.Key, rawValue(e.Value)))
+	case formatYAML:
+		if _, err := io.WriteString(p.w, "---\n"); err != nil {
+			return err
+		}
+		return p.document(e)
+	default:
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		return p.line(string(data))
+	}
+}
+
+// ImportResult prints the summary of the import with its failed records.
+func (p *printer) ImportResult(res importResult) error {
+	switch p.format {
+	case formatRaw:
+		if err := p.line(fmt.Sprintf("created %d, updated %d, skipped %d, failed %d",
+			res.Created, res.Updated, res.Skipped, res.Failed)); err != nil {
+			return err
+		}
+		for _, f := range res.Errors {
+			if err := p.line(fmt.Sprintf("line %d: %s: %s", f.Line, f.Key, f.Message)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case formatTable:
+		tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "CREATED\tUPDATED\tSKIPPED\tFAILED")
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\n", res.Created, res.Updated, res.Skipped, res.Failed)
+		if len(res.Errors) > 0 {
+			fmt.Fprintln(tw, "\nLINE\tKEY\tERROR")
+			for _, f := range res.Errors {
+				fmt.Fprintf(tw, "%d\t%s\t%s\n", f.Line, f.Key, f.Message)
+			}
+		}
+		return tw.Flush()
+	default:
+		return p.document(res)
+	}
+}
+
+func (p *printer) document(v any) error {
+	if p.format == formatYAML {
+		// The document is converted to plain values through JSON, as the YAML
+		// encoder writes json.Number as a string.
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var doc any
+		if err := dec.Decode(&doc); err != nil {
+			return err
+		}
+		enc := yaml.NewEncoder(p.w)
+		enc.SetIndent(2)
+		if err := enc.Encode(yamlNumbers(doc)); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (p *printer) line(s string) error {
+	_, err := fmt.Fprintln(p.w, s)
+	return err
+}
+
+// rawValue returns the value as is if it is a string and its JSON encoding
+// otherwise.
+func rawValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case nil:
+		return "null"
+	case json.Number:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
+
+// yamlNumbers replaces the JSON numbers in the decoded document with integers
+// or floats.
+func yamlNumbers(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for key, elem := range v {
+			v[key] = yamlNumbers(elem)
+		}
+	case []any:
+		for i, elem := range v {
+			v[i] = yamlNumbers(elem)
+		}
+	}
+	return v
+}
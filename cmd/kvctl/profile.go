@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/knadh/koanf/parsers/yaml"
+	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/v2"
+)
+
+const (
+	defaultServer = "http://localhost:8008"
+	defaultKVPath = "/api/v1/kv"
+)
+
+// fileConfig is the kvctl config file:
+//
+//	current: local
+//	profiles:
+//	  local:
+//	    server: http://localhost:8008
+//	  prod:
+//	    server: https://kv.example.com
+//	    token_file: ~/.kv-token
+type fileConfig struct {
+	Current  string             `koanf:"current"`
+	Profiles map[string]profile `koanf:"profiles"`
+}
+
+// profile is the server and authentication of a kvctl profile.
+type profile struct {
+	Server string `koanf:"server"`
+	// KVPath is the base path of the KV API, /api/v1/kv by default.
+	KVPath string `koanf:"kv_path"`
+	// Token is the bearer token sent in the Authorization header.
+	Token string `koanf:"token"`
+	// TokenFile is the file the token is read from if Token is empty.
+	TokenFile string `koanf:"token_file"`
+}
+
+// options are the flags every command has.
+type options struct {
+	profile string
+	server  string
+	token   string
+	config  string
+	output  string
+}
+
+// newFlagSet returns the flag set of the command with the common flags.
+func newFlagSet(env *env, name string, opts *options) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(env.stderr)
+	fs.StringVar(&opts.profile, "profile", os.Getenv("KVCTL_PROFILE"), "profile of the config file")
+	fs.StringVar(&opts.server, "server", "", "server URL")
+	fs.StringVar(&opts.token, "token", "", "bearer token")
+	fs.StringVar(&opts.config, "config", os.Getenv("KVCTL_CONFIG"), "config file")
+	fs.StringVar(&opts.output, "o", formatJSON, "output format: json, yaml, table or raw")
+	return fs
+}
+
+// parseFlags parses the flags of the command, which may be given before or
+// after the arguments, and returns the arguments.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %s", errUsage, err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// resolveProfile returns the profile selected by the options, with the
+// server and token flags overriding it.
+func resolveProfile(opts *options) (profile, error) {
+	p, err := loadProfile(opts.config, opts.profile)
+	if err != nil {
+		return profile{}, err
+	}
+
+	if opts.server != "" {
+		p.Server = opts.server
+	}
+	if opts.token != "" {
+		p.Token = opts.token
+	}
+	if p.Server == "" {
+		p.Server = defaultServer
+	}
+	if p.KVPath == "" {
+		p.KVPath = defaultKVPath
+	}
+	if p.Token == "" && p.TokenFile != "" {
+		raw, err := os.ReadFile(expandHome(p.TokenFile))
+		if err != nil {
+			return profile{}, fmt.Errorf("read token file: %w", err)
+		}
+		p.Token = strings.TrimSpace(string(raw))
+	}
+
+	return p, nil
+}
+
+// loadProfile loads the named profile, or the current one if name is empty,
+// from the config file. A missing config file gives an empty profile unless
+// a profile is named.
+func loadProfile(path, name string) (profile, error) {
+	if path == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			if name != "" {
+				return profile{}, err
+			}
+			return profile{}, nil
+		}
+		path = filepath.Join(dir, "kvctl", "config.yml")
+	}
+
+	k := koanf.New(":")
+	if err := k.Load(file.Provider(expandHome(path)), yaml.Parser()); err != nil {
+		if errors.Is(err, fs.ErrNotExist) && name == "" {
+			return profile{}, nil
+		}
+		return profile{}, fmt.Errorf("load config: %w", err)
+	}
+
+	var cfg fileConfig
+	if err := k.Unmarshal("", &cfg); err != nil {
+		return profile{}, fmt.Errorf("load config: %w", err)
+	}
+
+	if name == "" {
+		name = cfg.Current
+	}
+	if name == "" {
+		return profile{}, nil
+	}
+
+	p, ok := cfg.Profiles[name]
+	if !ok {
+		return profile{}, fmt.Errorf("%w: profile %q not found in %s", errUsage, name, path)
+	}
+	return p, nil
+}
+
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, rest)
+}
@@ -2,16 +2,23 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
+	"flag"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/tmybsv/tarantool-kv/internal/app"
 	"github.com/tmybsv/tarantool-kv/internal/config"
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+	"github.com/tmybsv/tarantool-kv/internal/transport/grpc"
+	"github.com/tmybsv/tarantool-kv/internal/transport/memcached"
+	"github.com/tmybsv/tarantool-kv/internal/transport/resp"
 )
 
 const (
@@ -27,18 +34,33 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	schemas, err := loadSchemas(cfg.Schemas)
+	if err != nil {
+		log.Error("failed to load schemas", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	var masterKey []byte
+	if cfg.Encryption.Enabled {
+		masterKey, err = readMasterKey(cfg.Encryption.MasterKeyFile)
+		if err != nil {
+			log.Error("failed to read master key", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	opts := appOptions(cfg, schemas, masterKey)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "restore":
+			os.Exit(restore(ctx, log, opts, os.Args[2:]))
+		case "migrate":
+			os.Exit(migrate(ctx, log, opts, os.Args[2:]))
+		}
+	}
+
 	log.Info("starting application", slog.String("env", cfg.Env))
-	app, err := app.New(log, ctx, app.Options{
-		TarantoolAddr:     fmt.Sprintf("%s:%d", cfg.Tarantool.Host, cfg.Tarantool.Port),
-		TarantoolUser:     cfg.Tarantool.User,
-		TarantoolPassword: cfg.Tarantool.Password,
-		TarantoolTimeout:  cfg.Tarantool.Timeout,
-		TarantoolKVSpace:  cfg.Tarantool.KVSpace,
-		TarantoolKVIndex:  cfg.Tarantool.KVIndex,
-		HTTPKVBasePath:    cfg.HTTP.KVBasePath,
-		HTTPAddr:          fmt.Sprintf(":%d", cfg.HTTP.Port),
-		HTTPTimeout:       cfg.HTTP.Timeout,
-	})
+	app, err := app.New(log, ctx, opts)
 	if err != nil {
 		log.Error("failed to init application", slog.String("error", err.Error()))
 		os.Exit(1)
@@ -55,6 +77,45 @@ func main() {
 		}
 	}()
 
+	if app.RESPServer != nil {
+		go func() {
+			log.Info("RESP server is starting", slog.Int("port", cfg.RESP.Port))
+			if err := app.RESPServer.ListenAndServe(); err != nil {
+				if !errors.Is(err, resp.ErrServerClosed) {
+					log.Error("failed to start RESP server", slog.String("error", err.Error()))
+					os.Exit(1)
+				}
+				log.Info("RESP server stopped")
+			}
+		}()
+	}
+
+	if app.MemcachedServer != nil {
+		go func() {
+			log.Info("memcached server is starting", slog.Int("port", cfg.Memcached.Port))
+			if err := app.MemcachedServer.ListenAndServe(); err != nil {
+				if !errors.Is(err, memcached.ErrServerClosed) {
+					log.Error("failed to start memcached server", slog.String("error", err.Error()))
+					os.Exit(1)
+				}
+				log.Info("memcached server stopped")
+			}
+		}()
+	}
+
+	if app.GRPCServer != nil {
+		go func() {
+			log.Info("gRPC server is starting", slog.Int("port", cfg.GRPC.Port))
+			if err := app.GRPCServer.ListenAndServe(); err != nil {
+				if !errors.Is(err, grpc.ErrServerClosed) {
+					log.Error("failed to start gRPC server", slog.String("error", err.Error()))
+					os.Exit(1)
+				}
+				log.Info("gRPC server stopped")
+			}
+		}()
+	}
+
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, syscall.SIGTERM, syscall.SIGINT)
 
@@ -63,6 +124,163 @@ func main() {
 	app.Stop(ctx)
 }
 
+// appOptions returns the application options of the configuration.
+func appOptions(cfg *config.Config, schemas map[string][]byte, masterKey []byte) app.Options {
+	return app.Options{
+		TarantoolAddr:           fmt.Sprintf("%s:%d", cfg.Tarantool.Host, cfg.Tarantool.Port),
+		TarantoolUser:           cfg.Tarantool.User,
+		TarantoolPassword:       cfg.Tarantool.Password,
+		TarantoolTimeout:        cfg.Tarantool.Timeout,
+		TarantoolKVSpace:        cfg.Tarantool.KVSpace,
+		TarantoolKVIndex:        cfg.Tarantool.KVIndex,
+		TarantoolKVDeletedIndex: cfg.Tarantool.KVDeletedIndex,
+		TarantoolEvents:         cfg.Tarantool.EventsSpace,
+		TarantoolHistorySpace:   cfg.Tarantool.HistorySpace,
+		TarantoolHistoryDepth:   cfg.Tarantool.HistoryDepth,
+		TarantoolChunkSpace:     cfg.Tarantool.ChunkSpace,
+		TarantoolChunkSize:      cfg.Tarantool.ChunkSize,
+		TarantoolKeysSpace:      cfg.Tarantool.KeysSpace,
+		TarantoolLocksSpace:     cfg.Tarantool.LocksSpace,
+		TarantoolStructures:     cfg.Tarantool.StructuresSpace,
+		TarantoolExpirySpace:    cfg.Tarantool.ExpirySpace,
+		TarantoolSchemasSpace:   cfg.Tarantool.SchemasSpace,
+		TarantoolJSONIndexes:    jsonIndexes(cfg.Tarantool.JSONIndexes),
+		HTTPKVBasePath:          cfg.HTTP.KVBasePath,
+		HTTPAdminBasePath:       cfg.HTTP.AdminBasePath,
+		HTTPLocksBasePath:       cfg.HTTP.LocksBasePath,
+		HTTPListsBasePath:       cfg.HTTP.ListsBasePath,
+		HTTPHashesBasePath:      cfg.HTTP.HashesBasePath,
+		HTTPSetsBasePath:        cfg.HTTP.SetsBasePath,
+		HTTPV2BasePath:          cfg.HTTP.V2BasePath,
+		HTTPValidateResponses:   cfg.Env == envLocal || cfg.Env == envDevelopment,
+		HTTPAddr:                fmt.Sprintf(":%d", cfg.HTTP.Port),
+		HTTPTimeout:             cfg.HTTP.Timeout,
+		SoftDelete:              cfg.SoftDelete.Enabled,
+		SoftDeleteRetention:     cfg.SoftDelete.Retention,
+		SoftDeletePurgeInterval: cfg.SoftDelete.PurgeInterval,
+		Schemas:                 schemas,
+		Compression:             cfg.Compression.Algorithm,
+		CompressionThreshold:    cfg.Compression.Threshold,
+		Encryption:              cfg.Encryption.Enabled,
+		EncryptionMasterKey:     masterKey,
+		ReencryptInterval:       cfg.Encryption.ReencryptInterval,
+		ExpiryPurgeInterval:     cfg.Expiry.PurgeInterval,
+		RESP:                    cfg.RESP.Enabled,
+		RESPAddr:                fmt.Sprintf(":%d", cfg.RESP.Port),
+		RESPTimeout:             cfg.RESP.Timeout,
+		Memcached:               cfg.Memcached.Enabled,
+		MemcachedAddr:           fmt.Sprintf(":%d", cfg.Memcached.Port),
+		MemcachedTimeout:        cfg.Memcached.Timeout,
+		MemcachedMaxItemSize:    cfg.Memcached.MaxItemSize,
+		GRPC:                    cfg.GRPC.Enabled,
+		GRPCAddr:                fmt.Sprintf(":%d", cfg.GRPC.Port),
+		Backup:                  cfg.Backup.Enabled,
+		BackupDir:               cfg.Backup.Dir,
+		BackupInterval:          cfg.Backup.Interval,
+		BackupKeep:              cfg.Backup.Keep,
+		MigrateOnStart:          cfg.Migrations.Auto,
+	}
+}
+
+// restore runs the restore subcommand, which loads a backup into the KV
+// space and exits:
+//
+//	server restore --from <file> [--mode overwrite|skip|fail]
+func restore(ctx context.Context, log *slog.Logger, opts app.Options, args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	from := fs.String("from", "", "backup file to restore")
+	mode := fs.String("mode", string(storage.ImportOverwrite), "what to do with existing keys: overwrite, skip or fail")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	switch storage.ImportMode(*mode) {
+	case storage.ImportOverwrite, storage.ImportSkip, storage.ImportFail:
+	default:
+		*from = ""
+	}
+	if *from == "" || fs.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "usage: server restore --from <file> [--mode overwrite|skip|fail]")
+		return 2
+	}
+
+	log.Info("restoring backup", slog.String("path", *from), slog.String("mode", *mode))
+	summary, err := app.Restore(ctx, log, opts, *from, storage.ImportMode(*mode))
+	for _, failure := range summary.Failures {
+		log.Warn("failed to restore key", slog.String("error", failure.Error()))
+	}
+	log.Info("restored keys",
+		slog.Int("created", summary.Created),
+		slog.Int("updated", summary.Updated),
+		slog.Int("skipped", summary.Skipped),
+		slog.Int("failed", summary.Failed),
+	)
+	if err != nil {
+		log.Error("failed to restore backup", slog.String("error", err.Error()))
+		return 1
+	}
+	if summary.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// migrate runs the migrate subcommand, which applies the pending schema
+// migrations and exits:
+//
+//	server migrate
+func migrate(ctx context.Context, log *slog.Logger, opts app.Options, args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "usage: server migrate")
+		return 2
+	}
+
+	applied, err := app.Migrate(ctx, log, opts)
+	if err != nil {
+		log.Error("failed to migrate schema", slog.String("error", err.Error()))
+		return 1
+	}
+	log.Info("schema is up to date", slog.Int("applied", len(applied)))
+	return 0
+}
+
+func jsonIndexes(cfgs []config.JSONIndexConfig) []storage.JSONIndex {
+	indexes := make([]storage.JSONIndex, 0, len(cfgs))
+	for _, c := range cfgs {
+		indexes = append(indexes, storage.JSONIndex{
+			Name: c.Name,
+			Path: c.Path,
+			Type: c.Type,
+		})
+	}
+	return indexes
+}
+
+func loadSchemas(cfgs []config.SchemaConfig) (map[string][]byte, error) {
+	schemas := make(map[string][]byte, len(cfgs))
+	for _, c := range cfgs {
+		raw, err := os.ReadFile(c.File)
+		if err != nil {
+			return nil, fmt.Errorf("read schema for prefix %q: %w", c.Prefix, err)
+		}
+		schemas[c.Prefix] = raw
+	}
+	return schemas, nil
+}
+
+// readMasterKey reads the base64 encoded master key from the file.
+func readMasterKey(path string) ([]byte, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(raw)))
+	if err != nil {
+		return nil, fmt.Errorf("decode master key: %w", err)
+	}
+	return key, nil
+}
+
 func setupLogger(env string) *slog.Logger {
 	log := &slog.Logger{}
 	switch env {
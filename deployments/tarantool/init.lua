@@ -1,20 +1,430 @@
+local clock = require("clock")
+local json = require("json")
+
+-- Number of the most recent change events kept in the kv_events space.
+local KV_EVENTS_RETENTION = 100000
+
 box.cfg {
-	listen = 3301
+	listen = 3301,
+	-- Interactive transactions are used to write several spaces atomically.
+	memtx_use_mvcc_engine = true
 }
 
+-- The spaces and indexes are created by the migrations of the application,
+-- see internal/migrate.
 box.once("bootstrap", function()
-	local space = box.schema.space.create("kv", { if_not_exists = true })
-
-	space:create_index("primary", {
-		type = "TREE",
-		parts = { 1, "string" },
-		unique = true,
-		if_not_exists = true
-	})
-
 	box.schema.user.create("probeuser", {
 		password = "1234qwerASDF",
 		if_not_exists = true
 	})
 	box.schema.user.grant('probeuser', 'read,write,execute', 'universe')
 end)
+
+-- A tombstone is a tuple of a soft deleted key, it keeps the deletion time
+-- in the third field.
+local function is_tombstone(tuple)
+	return tuple ~= nil and tuple[3] ~= nil
+end
+
+-- The value of a change event. Blobs keep their content type in the fifth
+-- field and values stored in chunks keep the number of chunks in the sixth
+-- one, their data is not copied to events, only the metadata.
+local function event_value(tuple)
+	if tuple[5] ~= nil or tuple[6] ~= nil then
+		return json.encode({ content_type = tuple[5], chunks = tuple[6] })
+	end
+	return tuple[2]
+end
+
+-- A live value replaced by one of the same version, kept in the eighth field,
+-- is not changed: it is only written anew, as the re-encryption does.
+local function rewritten(old, new)
+	if old == nil or new == nil or is_tombstone(old) or is_tombstone(new) then
+		return false
+	end
+	return new[8] ~= nil and old[8] == new[8]
+end
+
+-- Record every change of the kv space as an event in the events space and
+-- notify watchers once the transaction is committed.
+local function record_event(events, old, new)
+	if is_tombstone(old) and is_tombstone(new) then
+		return
+	end
+	if rewritten(old, new) then
+		return
+	end
+	if is_tombstone(old) and new == nil then
+		-- The tombstone is purged, the deletion is already recorded.
+		return
+	end
+
+	local event_type, key, value
+	if old == nil or is_tombstone(old) then
+		event_type, key, value = "set", new[1], event_value(new)
+	elseif new == nil or is_tombstone(new) then
+		event_type, key, value = "delete", old[1], box.NULL
+	else
+		event_type, key, value = "update", new[1], event_value(new)
+	end
+
+	local event = box.space[events]:insert { box.NULL, key, event_type, value, clock.time() }
+	if event[1] > KV_EVENTS_RETENTION then
+		box.space[events]:delete(event[1] - KV_EVENTS_RETENTION)
+	end
+
+	box.on_commit(function()
+		box.broadcast("kv.events", event[1])
+	end)
+end
+
+-- Expiration times of keys: key and expiration time. The application deletes
+-- the expired keys, the expiration time is dropped along with the key.
+local function drop_expiry(expiry, old, new)
+	if old ~= nil and (new == nil or is_tombstone(new)) then
+		box.space[expiry]:delete(old[1])
+	end
+end
+
+-- Totals of the compressed values: the bytes stored and their size before
+-- compression, which is kept along with the data of the compressed values
+-- only. The totals change by the sizes of the new tuple less the old one.
+local function add_size(sizes, name, bytes)
+	if bytes ~= 0 then
+		box.space[sizes]:upsert({ name, math.max(bytes, 0) }, { { "+", 2, bytes } })
+	end
+end
+
+local function count_sizes(sizes, data, size, old, new)
+	local compressed, uncompressed = 0, 0
+	if old ~= nil and old[size] ~= nil then
+		compressed = compressed - #old[data]
+		uncompressed = uncompressed - old[size]
+	end
+	if new ~= nil and new[size] ~= nil then
+		compressed = compressed + #new[data]
+		uncompressed = uncompressed + new[size]
+	end
+	add_size(sizes, "compressed", compressed)
+	add_size(sizes, "uncompressed", uncompressed)
+end
+
+-- Values keep the size in the ninth field, chunks in the fourth one.
+local function count_value_sizes(sizes, old, new)
+	count_sizes(sizes, 2, 9, old, new)
+end
+
+local function count_chunk_sizes(sizes, old, new)
+	count_sizes(sizes, 3, 4, old, new)
+end
+
+-- Leases of distributed locks: name, token of the holder, fencing token and
+-- expiration time. A lock is held while it has a token and has not expired.
+-- Released locks are kept to keep fencing tokens increasing. The functions
+-- do not yield, so every one of them runs atomically.
+local function lock_held(lock, now)
+	return lock ~= nil and lock[2] ~= nil and lock[4] > now
+end
+
+function kv_lock_acquire(space, name, token, ttl)
+	local s = box.space[space]
+	local now = clock.realtime()
+	local lock = s:get(name)
+	if lock_held(lock, now) then
+		return box.NULL
+	end
+
+	local fencing = 1
+	if lock ~= nil then
+		fencing = lock[3] + 1
+	end
+	return s:replace { name, token, fencing, now + ttl }
+end
+
+function kv_lock_renew(space, name, token, ttl)
+	local s = box.space[space]
+	local now = clock.realtime()
+	local lock = s:get(name)
+	if not lock_held(lock, now) or lock[2] ~= token then
+		return box.NULL
+	end
+
+	return s:update(name, { { "=", 4, now + ttl } })
+end
+
+function kv_lock_release(space, name, token)
+	local s = box.space[space]
+	local now = clock.realtime()
+	local lock = s:get(name)
+	if not lock_held(lock, now) or lock[2] ~= token then
+		return box.NULL
+	end
+
+	return s:update(name, { { "=", 2, box.NULL }, { "=", 4, now } })
+end
+
+function kv_lock_get(space, name)
+	local lock = box.space[space]:get(name)
+	if not lock_held(lock, clock.realtime()) then
+		return box.NULL
+	end
+	return lock
+end
+
+-- Lists, hashes and sets: key, type and the whole structure, an array for
+-- lists and a map for hashes and sets. Empty structures are removed. The
+-- functions do not yield, so every one of them runs atomically. Operations
+-- on a key of another type, plain values included, fail with the
+-- KV_WRONG_TYPE error code.
+local KV_WRONG_TYPE = 10001
+
+-- Plain values cannot take the keys of structures, tombstones do not hold
+-- the keys.
+local function check_structure(structures, old, new)
+	if new == nil or is_tombstone(new) or (old ~= nil and not is_tombstone(old)) then
+		return
+	end
+	if box.space[structures]:get(new[1]) ~= nil then
+		box.error({ code = KV_WRONG_TYPE, reason = "key holds a value of another type" })
+	end
+end
+
+local function structure(space, kv_space, key, kind)
+	local wrong_type = { code = KV_WRONG_TYPE, reason = "key holds a value of another type" }
+	local value = box.space[kv_space]:get(key)
+	if value ~= nil and not is_tombstone(value) then
+		box.error(wrong_type)
+	end
+
+	local t = box.space[space]:get(key)
+	if t == nil then
+		return nil
+	end
+	if t[2] ~= kind then
+		box.error(wrong_type)
+	end
+	return t[3]
+end
+
+local function store_structure(space, key, kind, data, size)
+	if size == 0 then
+		box.space[space]:delete(key)
+	else
+		box.space[space]:replace { key, kind, data }
+	end
+end
+
+local function new_map()
+	return setmetatable({}, { __serialize = "map" })
+end
+
+-- has reports whether the map has the key, JSON nulls included: box.NULL is
+-- equal to nil.
+local function has(map, key)
+	return type(map[key]) ~= "nil"
+end
+
+local function map_size(map)
+	local size = 0
+	for _ in pairs(map) do
+		size = size + 1
+	end
+	return size
+end
+
+function kv_list_push(space, kv_space, key, left, values)
+	local list = structure(space, kv_space, key, "list") or {}
+	for _, value in ipairs(values) do
+		if left then
+			table.insert(list, 1, value)
+		else
+			table.insert(list, value)
+		end
+	end
+	store_structure(space, key, "list", list, #list)
+	return #list
+end
+
+function kv_list_pop(space, kv_space, key, left, count)
+	local list = structure(space, kv_space, key, "list") or {}
+	local popped = {}
+	while #popped < count and #list > 0 do
+		if left then
+			table.insert(popped, table.remove(list, 1))
+		else
+			table.insert(popped, table.remove(list))
+		end
+	end
+	store_structure(space, key, "list", list, #list)
+	return popped
+end
+
+function kv_list_range(space, kv_space, key, start, stop)
+	local list = structure(space, kv_space, key, "list") or {}
+	if start < 0 then
+		start = math.max(#list + start, 0)
+	end
+	if stop < 0 then
+		stop = #list + stop
+	end
+	local values = {}
+	for i = start + 1, math.min(stop + 1, #list) do
+		table.insert(values, list[i])
+	end
+	return values
+end
+
+function kv_hash_set(space, kv_space, key, fields)
+	local hash = structure(space, kv_space, key, "hash") or new_map()
+	local added = 0
+	for field, value in pairs(fields) do
+		if not has(hash, field) then
+			added = added + 1
+		end
+		hash[field] = value
+	end
+	store_structure(space, key, "hash", hash, map_size(hash))
+	return added
+end
+
+function kv_hash_get(space, kv_space, key, field)
+	local hash = structure(space, kv_space, key, "hash") or new_map()
+	return hash[field], has(hash, field)
+end
+
+function kv_hash_get_all(space, kv_space, key)
+	return structure(space, kv_space, key, "hash") or new_map()
+end
+
+function kv_hash_delete(space, kv_space, key, fields)
+	local hash = structure(space, kv_space, key, "hash")
+	if hash == nil then
+		return 0
+	end
+	local removed = 0
+	for _, field in ipairs(fields) do
+		if has(hash, field) then
+			hash[field] = nil
+			removed = removed + 1
+		end
+	end
+	store_structure(space, key, "hash", hash, map_size(hash))
+	return removed
+end
+
+function kv_set_add(space, kv_space, key, members)
+	local set = structure(space, kv_space, key, "set") or new_map()
+	local added = 0
+	for _, member in ipairs(members) do
+		if set[member] == nil then
+			set[member] = true
+			added = added + 1
+		end
+	end
+	store_structure(space, key, "set", set, map_size(set))
+	return added
+end
+
+function kv_set_remove(space, kv_space, key, members)
+	local set = structure(space, kv_space, key, "set")
+	if set == nil then
+		return 0
+	end
+	local removed = 0
+	for _, member in ipairs(members) do
+		if set[member] ~= nil then
+			set[member] = nil
+			removed = removed + 1
+		end
+	end
+	store_structure(space, key, "set", set, map_size(set))
+	return removed
+end
+
+function kv_set_members(space, kv_space, key)
+	local members = {}
+	for member in pairs(structure(space, kv_space, key, "set") or {}) do
+		table.insert(members, member)
+	end
+	table.sort(members)
+	return members
+end
+
+function kv_set_is_member(space, kv_space, key, member)
+	local set = structure(space, kv_space, key, "set") or {}
+	return set[member] ~= nil
+end
+
+-- The triggers set, by the name of the space and of the trigger.
+local triggers = {}
+
+-- set_trigger sets the trigger of the kind on the space in place of the one
+-- set before. The trigger is called with the name of the space it writes or
+-- reads along with the old and new tuples, it is removed if the space is
+-- disabled, its name is nil.
+local function set_trigger(space, kind, name, trigger, target)
+	local id = space.name .. "." .. name
+	local new = nil
+	if target ~= nil then
+		new = function(old, tuple)
+			return trigger(target, old, tuple)
+		end
+	end
+	if new ~= nil or triggers[id] ~= nil then
+		space[kind](space, new, triggers[id])
+	end
+	triggers[id] = new
+end
+
+-- The space keeping the names of the spaces the triggers are set for.
+local TRIGGERS_SPACE = "_kv_triggers"
+
+local function set_triggers(s)
+	local kv = box.space[s.kv]
+	if kv == nil then
+		return
+	end
+	set_trigger(kv, "on_replace", "record_event", record_event, s.events)
+	set_trigger(kv, "on_replace", "drop_expiry", drop_expiry, s.expiry)
+	set_trigger(kv, "before_replace", "check_structure", check_structure, s.structures)
+	set_trigger(kv, "on_replace", "count_sizes", count_value_sizes, s.sizes)
+	local chunks = s.chunks and box.space[s.chunks]
+	if chunks ~= nil then
+		set_trigger(chunks, "on_replace", "count_sizes", count_chunk_sizes, s.sizes)
+	end
+end
+
+-- Triggers are not persisted, so they are set on every start. The spaces are
+-- created by the migrations of the application, which call kv_set_triggers
+-- with the names of the configured spaces once they are applied, the
+-- disabled ones are nil. The names are kept to set the triggers on start.
+function kv_set_triggers(spaces)
+	local saved = box.schema.space.create(TRIGGERS_SPACE, {
+		if_not_exists = true,
+		format = {
+			{ name = "name", type = "string" },
+			{ name = "spaces", type = "map" },
+		},
+	})
+	saved:create_index("primary", { parts = { 1, "string" }, if_not_exists = true })
+	saved:replace { "spaces", spaces }
+	set_triggers(spaces)
+end
+
+if box.space[TRIGGERS_SPACE] ~= nil then
+	local saved = box.space[TRIGGERS_SPACE]:get("spaces")
+	if saved ~= nil then
+		set_triggers(saved[2])
+	end
+elseif box.space.kv ~= nil then
+	-- The spaces of the default configuration, used until the application
+	-- sets the triggers after its migrations.
+	set_triggers({
+		kv = "kv",
+		events = "kv_events",
+		expiry = box.space.kv_expiry and "kv_expiry",
+		structures = box.space.kv_structures and "kv_structures",
+		chunks = box.space.kv_chunks and "kv_chunks",
+		sizes = box.space.kv_sizes and "kv_sizes",
+	})
+end
@@ -2,78 +2,355 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"os"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/tarantool/go-tarantool/v2"
+	"github.com/tmybsv/tarantool-kv/internal/metrics"
+	"github.com/tmybsv/tarantool-kv/internal/schema"
 	"github.com/tmybsv/tarantool-kv/internal/storage"
+	"github.com/tmybsv/tarantool-kv/internal/transport/grpc"
 	"github.com/tmybsv/tarantool-kv/internal/transport/http/handler"
 	"github.com/tmybsv/tarantool-kv/internal/transport/http/middleware"
+	"github.com/tmybsv/tarantool-kv/internal/transport/memcached"
+	"github.com/tmybsv/tarantool-kv/internal/transport/resp"
+	"github.com/tmybsv/tarantool-kv/internal/watch"
 )
 
 // App is an initialized application.
 type App struct {
 	HTTPServer *http.Server
+	// RESPServer is the Redis protocol server, nil if it is disabled.
+	RESPServer *resp.Server
+	// MemcachedServer is the memcached protocol server, nil if it is disabled.
+	MemcachedServer *memcached.Server
+	// GRPCServer is the gRPC server, nil if it is disabled.
+	GRPCServer *grpc.Server
 	conn       *tarantool.Connection
+	watcher    tarantool.Watcher
+	stopJobs   context.CancelFunc
 	opts       Options
 }
 
 // Options is the application options.
 type Options struct {
-	TarantoolAddr     string
-	TarantoolUser     string
-	TarantoolPassword string
-	TarantoolTimeout  time.Duration
-	TarantoolKVSpace  string
-	TarantoolKVIndex  string
-	HTTPKVBasePath    string
-	HTTPAddr          string
-	HTTPTimeout       time.Duration
+	TarantoolAddr           string
+	TarantoolUser           string
+	TarantoolPassword       string
+	TarantoolTimeout        time.Duration
+	TarantoolKVSpace        string
+	TarantoolKVIndex        string
+	TarantoolKVDeletedIndex string
+	TarantoolEvents         string
+	TarantoolHistorySpace   string
+	TarantoolHistoryDepth   int
+	TarantoolChunkSpace     string
+	TarantoolChunkSize      int
+	TarantoolKeysSpace      string
+	TarantoolLocksSpace     string
+	TarantoolStructures     string
+	TarantoolExpirySpace    string
+	TarantoolSchemasSpace   string
+	TarantoolJSONIndexes    []storage.JSONIndex
+	HTTPKVBasePath          string
+	HTTPAdminBasePath       string
+	HTTPLocksBasePath       string
+	HTTPListsBasePath       string
+	HTTPHashesBasePath      string
+	HTTPSetsBasePath        string
+	// HTTPV2BasePath is the base path of the second version of the API,
+	// which answers errors with problem details. Empty disables it.
+	HTTPV2BasePath string
+	// HTTPValidateResponses logs the responses not matching the OpenAPI
+	// spec, the requests are always validated.
+	HTTPValidateResponses   bool
+	HTTPAddr                string
+	HTTPTimeout             time.Duration
+	SoftDelete              bool
+	SoftDeleteRetention     time.Duration
+	SoftDeletePurgeInterval time.Duration
+	Schemas                 map[string][]byte
+	Compression             string
+	CompressionThreshold    int
+	Encryption              bool
+	EncryptionMasterKey     []byte
+	ReencryptInterval       time.Duration
+	ExpiryPurgeInterval     time.Duration
+	RESP                    bool
+	RESPAddr                string
+	RESPTimeout             time.Duration
+	Memcached               bool
+	MemcachedAddr           string
+	MemcachedTimeout        time.Duration
+	MemcachedMaxItemSize    int
+	GRPC                    bool
+	GRPCAddr                string
+	Backup                  bool
+	BackupDir               string
+	BackupInterval          time.Duration
+	BackupKeep              int
+	// MigrateOnStart applies the pending schema migrations on start,
+	// otherwise the application refuses to start until they are applied.
+	MigrateOnStart bool
 }
 
 // New creates a new application.
 func New(log *slog.Logger, ctx context.Context, opts Options) (*App, error) {
-	tarantoolDialer := tarantool.NetDialer{
-		Address:  opts.TarantoolAddr,
-		User:     opts.TarantoolUser,
-		Password: opts.TarantoolPassword,
+	if opts.SoftDelete && opts.SoftDeletePurgeInterval <= 0 {
+		return nil, errors.New("soft delete purge interval must be positive")
 	}
-	tarantoolOpts := tarantool.Opts{
-		Timeout: opts.TarantoolTimeout,
+	if opts.Encryption && opts.ReencryptInterval <= 0 {
+		return nil, errors.New("re-encrypt interval must be positive")
+	}
+	if opts.Memcached && opts.MemcachedMaxItemSize <= 0 {
+		return nil, errors.New("memcached max item size must be positive")
+	}
+	if opts.TarantoolExpirySpace != "" && opts.ExpiryPurgeInterval <= 0 {
+		return nil, errors.New("expiry purge interval must be positive")
 	}
 
-	tarantoolConn, err := tarantool.Connect(ctx, tarantoolDialer, tarantoolOpts)
+	if opts.Backup && (opts.BackupDir == "" || opts.BackupInterval <= 0 || opts.BackupKeep <= 0) {
+		return nil, errors.New("backup dir, interval and keep must be set")
+	}
+	if opts.Backup {
+		if err := os.MkdirAll(opts.BackupDir, 0o750); err != nil {
+			return nil, fmt.Errorf("create backup dir: %w", err)
+		}
+	}
+
+	spec, err := newHTTPSpec(log, opts)
 	if err != nil {
-		return nil, fmt.Errorf("connect to Tarantool: %w", err)
+		return nil, err
+	}
+
+	tarantoolConn, keyring, ts, err := connectStorage(ctx, log, opts)
+	if err != nil {
+		return nil, err
+	}
+	schemas, err := schemaRegistry(tarantoolConn, opts)
+	if err != nil {
+		tarantoolConn.Close()
+		return nil, err
 	}
+	for prefix, raw := range opts.Schemas {
+		if err := schemas.Register(prefix, raw); err != nil {
+			tarantoolConn.Close()
+			return nil, fmt.Errorf("register schema for prefix %q: %w", prefix, err)
+		}
+	}
+	sizes := storage.NewTarantoolSizes(tarantoolConn, sizesSpace(opts))
+	if err := prometheus.Register(metrics.NewCompressionCollector(sizes.Compression)); err != nil {
+		tarantoolConn.Close()
+		return nil, fmt.Errorf("register compression metrics: %w", err)
+	}
+	kvHandler := handler.NewKV(log, ts, opts.HTTPKVBasePath, schemas)
+	schemasHandler := handler.NewSchemas(log, schemas)
+
+	jobsCtx, stopJobs := context.WithCancel(ctx)
+	hub := watch.NewHub(log, storage.NewTarantoolEvents(tarantoolConn, opts.TarantoolEvents, keyring))
+	if err := hub.Start(jobsCtx); err != nil {
+		stopJobs()
+		tarantoolConn.Close()
+		return nil, fmt.Errorf("start events hub: %w", err)
+	}
+	watcher, err := tarantoolConn.NewWatcher(storage.EventsBroadcastKey, func(tarantool.WatchEvent) {
+		hub.Notify()
+	})
+	if err != nil {
+		stopJobs()
+		tarantoolConn.Close()
+		return nil, fmt.Errorf("watch Tarantool events: %w", err)
+	}
+
+	if opts.SoftDelete {
+		go purgeTombstones(jobsCtx, log, ts, opts.SoftDeletePurgeInterval, opts.SoftDeleteRetention)
+	}
+	if opts.Encryption {
+		go reencryptValues(jobsCtx, log, ts, opts.ReencryptInterval)
+	}
+	if opts.TarantoolExpirySpace != "" {
+		go purgeExpired(jobsCtx, log, ts, opts.ExpiryPurgeInterval)
+	}
+	if opts.Backup {
+		go backupSpace(jobsCtx, log, ts, backupKeys(keyring), opts.BackupDir, opts.BackupInterval, opts.BackupKeep)
+	}
+	watchHandler := handler.NewWatch(log, hub)
+	queryHandler := handler.NewQuery(log, ts)
+	keysHandler := handler.NewKeys(log, ts)
+	transferHandler := handler.NewTransfer(log, ts, schemas)
+	locksHandler := handler.NewLocks(log, storage.NewTarantoolLocks(tarantoolConn, opts.TarantoolLocksSpace))
+	structures := storage.NewTarantoolStructures(tarantoolConn, opts.TarantoolStructures, opts.TarantoolKVSpace)
+	listsHandler := handler.NewLists(log, structures)
+	hashesHandler := handler.NewHashes(log, structures)
+	setsHandler := handler.NewSets(log, structures)
 
-	ts := storage.NewTarantool(tarantoolConn, opts.TarantoolKVSpace, opts.TarantoolKVIndex)
-	kvHandler := handler.NewKV(log, ts, opts.HTTPKVBasePath)
+	handlers := httpHandlers{
+		kv:       kvHandler,
+		schemas:  schemasHandler,
+		watch:    watchHandler,
+		query:    queryHandler,
+		keys:     keysHandler,
+		transfer: transferHandler,
+		locks:    locksHandler,
+		lists:    listsHandler,
+		hashes:   hashesHandler,
+		sets:     setsHandler,
+	}
+	if opts.Encryption {
+		handlers.encryption = handler.NewEncryption(log, keyring)
+	}
 
+	v1 := http.NewServeMux()
+	registerRoutes(v1, v1HTTPPaths(opts), handlers)
 	mux := http.NewServeMux()
-	mux.HandleFunc(fmt.Sprintf("%s %s", http.MethodPost, opts.HTTPKVBasePath), kvHandler.Set)
-	mux.HandleFunc(fmt.Sprintf("%s %s/{key}", http.MethodGet, opts.HTTPKVBasePath), kvHandler.Get)
-	mux.HandleFunc(fmt.Sprintf("%s %s/{key}", http.MethodPut, opts.HTTPKVBasePath), kvHandler.Update)
-	mux.HandleFunc(fmt.Sprintf("%s %s/{key}", http.MethodDelete, opts.HTTPKVBasePath), kvHandler.Delete)
+	mux.Handle("/", spec.v1.Handler(v1))
+	if opts.HTTPV2BasePath != "" {
+		// The second version of the API differs in the errors only, which
+		// are problem details.
+		v2Paths := v2HTTPPaths(opts.HTTPV2BasePath)
+		handlers.kv = handler.NewKV(log, ts, v2Paths.kv, schemas)
+		v2 := http.NewServeMux()
+		registerRoutes(v2, v2Paths, handlers)
+		mux.Handle(opts.HTTPV2BasePath+"/", handler.Problems(log, spec.v2.Handler(v2)))
+	}
+	mux.Handle(fmt.Sprintf("%s /metrics", http.MethodGet), promhttp.Handler())
+	mux.HandleFunc(fmt.Sprintf("%s /openapi.yml", http.MethodGet), spec.handler.YAML)
+	mux.HandleFunc(fmt.Sprintf("%s /openapi.json", http.MethodGet), spec.handler.JSON)
+	mux.HandleFunc(fmt.Sprintf("%s /docs", http.MethodGet), spec.handler.Docs)
 	loggingMiddleware := middleware.Logging(log, mux)
 
 	server := &http.Server{
 		Addr:         opts.HTTPAddr,
-		Handler:      loggingMiddleware,
+		Handler:      middleware.RequestID(loggingMiddleware),
 		ReadTimeout:  opts.HTTPTimeout,
 		WriteTimeout: opts.HTTPTimeout,
 	}
 
+	var respServer *resp.Server
+	if opts.RESP {
+		respServer = resp.NewServer(log, opts.RESPAddr, opts.RESPTimeout, ts, schemas)
+	}
+
+	var memcachedServer *memcached.Server
+	if opts.Memcached {
+		memcachedServer = memcached.NewServer(log, opts.MemcachedAddr, opts.MemcachedTimeout, opts.MemcachedMaxItemSize, ts, schemas)
+	}
+
+	var grpcServer *grpc.Server
+	if opts.GRPC {
+		grpcServer = grpc.NewServer(log, opts.GRPCAddr, ts, hub, schemas)
+	}
+
 	return &App{
-		HTTPServer: server,
-		conn:       tarantoolConn,
+		HTTPServer:      server,
+		RESPServer:      respServer,
+		MemcachedServer: memcachedServer,
+		GRPCServer:      grpcServer,
+		conn:            tarantoolConn,
+		watcher:         watcher,
+		stopJobs:        stopJobs,
 	}, nil
 }
 
+// schemaRegistry returns the registry of the schemas stored in the schemas
+// space or an empty one kept in memory if there is no space.
+func schemaRegistry(conn *tarantool.Connection, opts Options) (*schema.Registry, error) {
+	if opts.TarantoolSchemasSpace == "" {
+		return schema.NewRegistry(), nil
+	}
+	return schema.NewStoredRegistry(storage.NewTarantoolSchemas(conn, opts.TarantoolSchemasSpace))
+}
+
+// connect connects to Tarantool.
+func connect(ctx context.Context, opts Options) (*tarantool.Connection, error) {
+	tarantoolDialer := tarantool.NetDialer{
+		Address:  opts.TarantoolAddr,
+		User:     opts.TarantoolUser,
+		Password: opts.TarantoolPassword,
+	}
+	tarantoolOpts := tarantool.Opts{
+		Timeout: opts.TarantoolTimeout,
+	}
+
+	tarantoolConn, err := tarantool.Connect(ctx, tarantoolDialer, tarantoolOpts)
+	if err != nil {
+		return nil, fmt.Errorf("connect to Tarantool: %w", err)
+	}
+	return tarantoolConn, nil
+}
+
+// connectStorage connects to Tarantool, prepares the schema and returns the
+// connection, the keyring if the encryption is enabled and the KV storage.
+func connectStorage(ctx context.Context, log *slog.Logger, opts Options) (*tarantool.Connection, *storage.Keyring, *storage.Tarantool, error) {
+	tarantoolConn, err := connect(ctx, opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := prepareSchema(log, tarantoolConn, opts); err != nil {
+		tarantoolConn.Close()
+		return nil, nil, nil, err
+	}
+
+	var keyring *storage.Keyring
+	if opts.Encryption {
+		keyring, err = storage.NewKeyring(tarantoolConn, opts.TarantoolKeysSpace, opts.EncryptionMasterKey)
+		if err != nil {
+			tarantoolConn.Close()
+			return nil, nil, nil, fmt.Errorf("init keyring: %w", err)
+		}
+	}
+
+	ts, err := storage.NewTarantool(tarantoolConn, storage.TarantoolOptions{
+		Space:                opts.TarantoolKVSpace,
+		Index:                opts.TarantoolKVIndex,
+		HistorySpace:         opts.TarantoolHistorySpace,
+		HistoryDepth:         opts.TarantoolHistoryDepth,
+		ChunkSpace:           opts.TarantoolChunkSpace,
+		ChunkSize:            opts.TarantoolChunkSize,
+		ExpirySpace:          opts.TarantoolExpirySpace,
+		VersionSequence:      versionSequence(opts),
+		SoftDelete:           opts.SoftDelete,
+		DeletedIndex:         opts.TarantoolKVDeletedIndex,
+		JSONIndexes:          opts.TarantoolJSONIndexes,
+		Compression:          opts.Compression,
+		CompressionThreshold: opts.CompressionThreshold,
+		Keyring:              keyring,
+	})
+	if err != nil {
+		tarantoolConn.Close()
+		return nil, nil, nil, fmt.Errorf("init storage: %w", err)
+	}
+	if err := ts.VerifySchema(); err != nil {
+		tarantoolConn.Close()
+		return nil, nil, nil, fmt.Errorf("verify schema: %w", err)
+	}
+	if err := ts.EnsureJSONIndexes(); err != nil {
+		tarantoolConn.Close()
+		return nil, nil, nil, err
+	}
+
+	return tarantoolConn, keyring, ts, nil
+}
+
 // Stop stops the application.
 func (a *App) Stop(ctx context.Context) {
+	a.stopJobs()
+	a.watcher.Unregister()
 	a.conn.Close()
 	a.HTTPServer.Shutdown(ctx)
+	if a.RESPServer != nil {
+		a.RESPServer.Shutdown(ctx)
+	}
+	if a.MemcachedServer != nil {
+		a.MemcachedServer.Shutdown(ctx)
+	}
+	if a.GRPCServer != nil {
+		a.GRPCServer.Shutdown(ctx)
+	}
 }
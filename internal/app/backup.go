@@ -0,0 +1,74 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/tmybsv/tarantool-kv/internal/backup"
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+// backupSpace periodically writes a backup of the KV space into the
+// directory, keeping the last keep backups, until ctx is done. The backups
+// are encrypted unless keys is nil.
+func backupSpace(ctx context.Context, log *slog.Logger, src backup.Exporter, keys backup.KeyWrapper, dir string, interval time.Duration, keep int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		start := time.Now()
+		path, n, err := backup.Create(dir, src, keys, start)
+		if err != nil {
+			log.Error("failed to back up keys", slog.String("error", err.Error()))
+			continue
+		}
+		log.Info("backed up keys",
+			slog.String("path", path),
+			slog.Int("count", n),
+			slog.Duration("duration", time.Since(start)),
+		)
+
+		removed, err := backup.Rotate(dir, keep)
+		if err != nil {
+			log.Error("failed to rotate backups", slog.String("error", err.Error()))
+		}
+		for _, path := range removed {
+			log.Info("removed old backup", slog.String("path", path))
+		}
+	}
+}
+
+// Restore connects to Tarantool and restores the backup into the KV space,
+// see backup.Restore. The backups hold the values decrypted, encrypted as a
+// whole with a data key wrapped by the master key, so the values are
+// encrypted again on restore.
+func Restore(ctx context.Context, log *slog.Logger, opts Options, path string, mode storage.ImportMode) (backup.Summary, error) {
+	conn, keyring, ts, err := connectStorage(ctx, log, opts)
+	if err != nil {
+		return backup.Summary{}, err
+	}
+	defer conn.Close()
+
+	summary, err := backup.Restore(path, ts, backupKeys(keyring), mode, 0)
+	if err != nil {
+		return summary, fmt.Errorf("restore %s: %w", path, err)
+	}
+	return summary, nil
+}
+
+// backupKeys returns the keys encrypting the backups, nil if the encryption
+// is disabled.
+func backupKeys(keyring *storage.Keyring) backup.KeyWrapper {
+	if keyring == nil {
+		return nil
+	}
+	return keyring
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/tarantool/go-tarantool/v2"
+	"github.com/tmybsv/tarantool-kv/internal/migrate"
+)
+
+// Migrate connects to Tarantool and applies the pending schema migrations,
+// see migrate.Migrator.Up.
+func Migrate(ctx context.Context, log *slog.Logger, opts Options) ([]migrate.Migration, error) {
+	conn, err := connect(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	migrator, err := migrate.New(conn, migrationSpaces(opts))
+	if err != nil {
+		return nil, fmt.Errorf("load migrations: %w", err)
+	}
+	applied, err := migrator.Up()
+	logMigrations(log, applied)
+	if err != nil {
+		return applied, fmt.Errorf("migrate schema: %w", err)
+	}
+	return applied, nil
+}
+
+// prepareSchema applies the pending schema migrations if they are applied on
+// start or checks there are none otherwise. A schema migrated by a newer
+// binary is refused either way.
+func prepareSchema(log *slog.Logger, conn tarantool.Doer, opts Options) error {
+	migrator, err := migrate.New(conn, migrationSpaces(opts))
+	if err != nil {
+		return fmt.Errorf("load migrations: %w", err)
+	}
+
+	if opts.MigrateOnStart {
+		applied, err := migrator.Up()
+		logMigrations(log, applied)
+		if err != nil {
+			return fmt.Errorf("migrate schema: %w", err)
+		}
+		return nil
+	}
+
+	err = migrator.Check()
+	if errors.Is(err, migrate.ErrPending) {
+		return fmt.Errorf("%w, run the migrate command to apply them", err)
+	}
+	if err != nil {
+		return fmt.Errorf("check schema: %w", err)
+	}
+	return nil
+}
+
+func logMigrations(log *slog.Logger, applied []migrate.Migration) {
+	for _, m := range applied {
+		log.Info("applied schema migration",
+			slog.Uint64("version", m.Version),
+			slog.String("name", m.Name),
+		)
+	}
+}
+
+// migrationSpaces returns the names of the spaces and indexes the migrations
+// create.
+func migrationSpaces(opts Options) migrate.Spaces {
+	return migrate.Spaces{
+		KV:             opts.TarantoolKVSpace,
+		KVIndex:        opts.TarantoolKVIndex,
+		KVDeletedIndex: opts.TarantoolKVDeletedIndex,
+		Events:         opts.TarantoolEvents,
+		History:        opts.TarantoolHistorySpace,
+		Chunks:         opts.TarantoolChunkSpace,
+		Keys:           opts.TarantoolKeysSpace,
+		Locks:          opts.TarantoolLocksSpace,
+		Structures:     opts.TarantoolStructures,
+		Expiry:         opts.TarantoolExpirySpace,
+		Versions:       versionSequence(opts),
+		Schemas:        opts.TarantoolSchemasSpace,
+		Sizes:          sizesSpace(opts),
+	}
+}
+
+// sizesSpace returns the name of the space of the totals of the compressed
+// values, named after the KV space.
+func sizesSpace(opts Options) string {
+	return opts.TarantoolKVSpace + "_sizes"
+}
+
+// versionSequence returns the name of the sequence of value versions, named
+// after the KV space.
+func versionSequence(opts Options) string {
+	return opts.TarantoolKVSpace + "_versions"
+}
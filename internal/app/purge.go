@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// tombstonePurger is the contract for the storage keeping tombstones of
+// deleted keys.
+type tombstonePurger interface {
+	PurgeDeleted(before time.Time) (int, error)
+}
+
+// purgeTombstones periodically removes the tombstones older than retention
+// until ctx is done.
+func purgeTombstones(ctx context.Context, log *slog.Logger, purger tombstonePurger, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		purged, err := purger.PurgeDeleted(time.Now().Add(-retention))
+		if err != nil {
+			log.Error("failed to purge tombstones", slog.String("error", err.Error()))
+			continue
+		}
+		if purged > 0 {
+			log.Info("purged tombstones", slog.Int("count", purged))
+		}
+	}
+}
+
+// expiredPurger is the contract for the storage expiring keys.
+type expiredPurger interface {
+	PurgeExpired(now time.Time) (int, error)
+}
+
+// purgeExpired periodically deletes the expired keys until ctx is done.
+func purgeExpired(ctx context.Context, log *slog.Logger, purger expiredPurger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		purged, err := purger.PurgeExpired(time.Now())
+		if err != nil {
+			log.Error("failed to purge expired keys", slog.String("error", err.Error()))
+			continue
+		}
+		if purged > 0 {
+			log.Info("purged expired keys", slog.Int("count", purged))
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// reencrypter is the contract for the storage encrypting values.
+type reencrypter interface {
+	Reencrypt() (int, error)
+}
+
+// reencryptValues periodically re-encrypts the values encrypted with
+// rotated data keys until ctx is done.
+func reencryptValues(ctx context.Context, log *slog.Logger, r reencrypter, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reencrypted, err := r.Reencrypt()
+		if err != nil {
+			log.Error("failed to re-encrypt values", slog.String("error", err.Error()))
+			continue
+		}
+		if reencrypted > 0 {
+			log.Info("re-encrypted values", slog.Int("count", reencrypted))
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/tmybsv/tarantool-kv/api"
+	"github.com/tmybsv/tarantool-kv/internal/transport/http/handler"
+)
+
+// httpPaths are the base paths of the routes of a version of the HTTP API.
+type httpPaths struct {
+	kv     string
+	admin  string
+	locks  string
+	lists  string
+	hashes string
+	sets   string
+}
+
+// v1HTTPPaths returns the paths of the first version of the HTTP API, which
+// are configured one by one.
+func v1HTTPPaths(opts Options) httpPaths {
+	return httpPaths{
+		kv:     opts.HTTPKVBasePath,
+		admin:  opts.HTTPAdminBasePath,
+		locks:  opts.HTTPLocksBasePath,
+		lists:  opts.HTTPListsBasePath,
+		hashes: opts.HTTPHashesBasePath,
+		sets:   opts.HTTPSetsBasePath,
+	}
+}
+
+// v2HTTPPaths returns the paths of the second version of the HTTP API under
+// the base path.
+func v2HTTPPaths(base string) httpPaths {
+	return httpPaths{
+		kv:     base + "/kv",
+		admin:  base + "/admin",
+		locks:  base + "/locks",
+		lists:  base + "/lists",
+		hashes: base + "/hashes",
+		sets:   base + "/sets",
+	}
+}
+
+// specPaths maps the paths to the ones of the OpenAPI spec, which are
+// relative to the base path of the API version.
+func (p httpPaths) specPaths() map[string]string {
+	return map[string]string{
+		p.kv:     "/kv",
+		p.admin:  "/admin",
+		p.locks:  "/locks",
+		p.lists:  "/lists",
+		p.hashes: "/hashes",
+		p.sets:   "/sets",
+	}
+}
+
+// httpHandlers are the handlers of the HTTP API, encryption is nil if the
+// encryption is disabled.
+type httpHandlers struct {
+	kv         *handler.KV
+	schemas    *handler.Schemas
+	watch      *handler.Watch
+	query      *handler.Query
+	keys       *handler.Keys
+	transfer   *handler.Transfer
+	locks      *handler.Locks
+	lists      *handler.Lists
+	hashes     *handler.Hashes
+	sets       *handler.Sets
+	encryption *handler.Encryption
+}
+
+// routeMux is the mux the routes are registered on, http.ServeMux.
+type routeMux interface {
+	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
+}
+
+// registerRoutes registers the routes of the HTTP API under the paths. Every
+// route must be described in the OpenAPI spec and vice versa.
+func registerRoutes(mux routeMux, p httpPaths, h httpHandlers) {
+	mux.HandleFunc(fmt.Sprintf("%s %s", http.MethodPost, p.kv), h.kv.Set)
+	mux.HandleFunc(fmt.Sprintf("%s %s/{key}", http.MethodGet, p.kv), h.kv.Get)
+	mux.HandleFunc(fmt.Sprintf("%s %s/{key}", http.MethodHead, p.kv), h.kv.Head)
+	mux.HandleFunc(fmt.Sprintf("%s %s/{key}", http.MethodPut, p.kv), h.kv.Update)
+	mux.HandleFunc(fmt.Sprintf("%s %s/{key}", http.MethodPatch, p.kv), h.kv.Patch)
+	mux.HandleFunc(fmt.Sprintf("%s %s/{key}", http.MethodDelete, p.kv), h.kv.Delete)
+	mux.HandleFunc(fmt.Sprintf("%s %s/{key}/history", http.MethodGet, p.kv), h.kv.History)
+	mux.HandleFunc(fmt.Sprintf("%s %s/{key}/restore", http.MethodPost, p.kv), h.kv.Restore)
+	mux.HandleFunc(fmt.Sprintf("%s %s/{key}/undelete", http.MethodPost, p.kv), h.kv.Undelete)
+	mux.HandleFunc(fmt.Sprintf("%s %s/_query", http.MethodGet, p.kv), h.query.List)
+	mux.HandleFunc(fmt.Sprintf("%s %s/_keys", http.MethodGet, p.kv), h.keys.List)
+	mux.HandleFunc(fmt.Sprintf("%s %s/_export", http.MethodGet, p.kv), h.transfer.Export)
+	mux.HandleFunc(fmt.Sprintf("%s %s/_import", http.MethodPost, p.kv), h.transfer.Import)
+	mux.HandleFunc(fmt.Sprintf("%s %s/_watch", http.MethodGet, p.kv), h.watch.Prefix)
+	mux.HandleFunc(fmt.Sprintf("%s %s/{key}/watch", http.MethodGet, p.kv), h.watch.Key)
+	mux.HandleFunc(fmt.Sprintf("%s %s/schemas", http.MethodGet, p.admin), h.schemas.List)
+	mux.HandleFunc(fmt.Sprintf("%s %s/schemas", http.MethodPut, p.admin), h.schemas.Put)
+	mux.HandleFunc(fmt.Sprintf("%s %s/schemas", http.MethodDelete, p.admin), h.schemas.Delete)
+	mux.HandleFunc(fmt.Sprintf("%s %s/{name}", http.MethodPost, p.locks), h.locks.Acquire)
+	mux.HandleFunc(fmt.Sprintf("%s %s/{name}", http.MethodGet, p.locks), h.locks.Get)
+	mux.HandleFunc(fmt.Sprintf("%s %s/{name}/renew", http.MethodPost, p.locks), h.locks.Renew)
+	mux.HandleFunc(fmt.Sprintf("%s %s/{name}/release", http.MethodPost, p.locks), h.locks.Release)
+	mux.HandleFunc(fmt.Sprintf("%s %s/{key}", http.MethodGet, p.lists), h.lists.Range)
+	mux.HandleFunc(fmt.Sprintf("%s %s/{key}/push", http.MethodPost, p.lists), h.lists.Push)
+	mux.HandleFunc(fmt.Sprintf("%s %s/{key}/pop", http.MethodPost, p.lists), h.lists.Pop)
+	mux.HandleFunc(fmt.Sprintf("%s %s/{key}", http.MethodGet, p.hashes), h.hashes.GetAll)
+	mux.HandleFunc(fmt.Sprintf("%s %s/{key}", http.MethodPut, p.hashes), h.hashes.Set)
+	mux.HandleFunc(fmt.Sprintf("%s %s/{key}/{field}", http.MethodGet, p.hashes), h.hashes.Get)
+	mux.HandleFunc(fmt.Sprintf("%s %s/{key}/{field}", http.MethodDelete, p.hashes), h.hashes.Delete)
+	mux.HandleFunc(fmt.Sprintf("%s %s/{key}", http.MethodGet, p.sets), h.sets.Members)
+	mux.HandleFunc(fmt.Sprintf("%s %s/{key}", http.MethodPost, p.sets), h.sets.Add)
+	mux.HandleFunc(fmt.Sprintf("%s %s/{key}/{member}", http.MethodGet, p.sets), h.sets.IsMember)
+	mux.HandleFunc(fmt.Sprintf("%s %s/{key}/{member}", http.MethodDelete, p.sets), h.sets.Remove)
+	if h.encryption != nil {
+		mux.HandleFunc(fmt.Sprintf("%s %s/encryption/rotate", http.MethodPost, p.admin), h.encryption.Rotate)
+	}
+}
+
+// httpSpec is the OpenAPI spec of the HTTP API, the validators of the
+// versions of the API against it and the handler serving it.
+type httpSpec struct {
+	v1      *handler.SpecValidator
+	v2      *handler.SpecValidator
+	handler *handler.Spec
+}
+
+// newHTTPSpec loads the spec and builds the validators of the versions of
+// the API, v2 is nil if the second version is disabled.
+func newHTTPSpec(log *slog.Logger, opts Options) (httpSpec, error) {
+	doc, err := api.LoadOpenAPI()
+	if err != nil {
+		return httpSpec{}, fmt.Errorf("load OpenAPI spec: %w", err)
+	}
+
+	var spec httpSpec
+	spec.v1, err = handler.NewSpecValidator(log, doc, v1HTTPPaths(opts).specPaths(), opts.HTTPValidateResponses)
+	if err != nil {
+		return httpSpec{}, err
+	}
+	if opts.HTTPV2BasePath != "" {
+		spec.v2, err = handler.NewSpecValidator(log, doc, v2HTTPPaths(opts.HTTPV2BasePath).specPaths(), opts.HTTPValidateResponses)
+		if err != nil {
+			return httpSpec{}, err
+		}
+	}
+	spec.handler, err = handler.NewSpec(log, api.OpenAPI, doc, api.Docs)
+	if err != nil {
+		return httpSpec{}, err
+	}
+	return spec, nil
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmybsv/tarantool-kv/api"
+	"github.com/tmybsv/tarantool-kv/internal/transport/http/handler"
+)
+
+// patternMux records the patterns of the routes.
+type patternMux struct {
+	patterns []string
+}
+
+func (m *patternMux) HandleFunc(pattern string, _ func(http.ResponseWriter, *http.Request)) {
+	m.patterns = append(m.patterns, pattern)
+}
+
+// TestRegisterRoutes_spec fails when a route is missing from the OpenAPI spec
+// or an operation of the spec has no route.
+func TestRegisterRoutes_spec(t *testing.T) {
+	doc, err := api.LoadOpenAPI()
+	require.NoError(t, err)
+
+	paths := v2HTTPPaths("/api/v1")
+	mux := &patternMux{}
+	registerRoutes(mux, paths, httpHandlers{encryption: &handler.Encryption{}})
+
+	var routes []string
+	for _, pattern := range mux.patterns {
+		method, path, ok := strings.Cut(pattern, " ")
+		require.True(t, ok, "pattern %q has no method", pattern)
+		routes = append(routes, method+" "+strings.TrimPrefix(path, "/api/v1"))
+	}
+
+	var operations []string
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			operations = append(operations, method+" "+path)
+		}
+	}
+
+	for _, route := range routes {
+		assert.Contains(t, operations, route, "route is missing from the spec")
+	}
+	for _, operation := range operations {
+		assert.Contains(t, routes, operation, "operation of the spec has no route")
+	}
+	assert.Len(t, routes, len(slices.Compact(slices.Sorted(slices.Values(routes)))), "routes are registered twice")
+}
+
+func TestHTTPPaths_specPaths(t *testing.T) {
+	paths := v2HTTPPaths("/api/v2")
+
+	for path, specPath := range paths.specPaths() {
+		assert.Equal(t, "/api/v2"+specPath, path)
+	}
+	assert.Len(t, paths.specPaths(), 6)
+}
@@ -0,0 +1,337 @@
+// Package backup writes and restores backups of the KV space: gzip
+// compressed newline-delimited JSON dumps of every live key, each along with
+// a checksum file in the format of sha256sum. The dumps hold the values
+// decrypted, so they are encrypted as a whole if the values are.
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+// The backup files are named kv-<UTC time>.ndjson.gz, the checksum files
+// have the .sha256 suffix added.
+const (
+	filePrefix  = "kv-"
+	fileExt     = ".ndjson.gz"
+	checksumExt = ".sha256"
+	fileTimeFmt = "20060102T150405.000Z"
+)
+
+const (
+	// defaultBatchSize is the number of records restored in a single
+	// transaction by default.
+	defaultBatchSize = 256
+	// maxLineSize is the maximum length of a record, enough for the largest
+	// blob encoded in base64.
+	maxLineSize = 128 << 20
+	// maxFailures is the maximum number of failed records kept in Summary.
+	maxFailures = 100
+)
+
+// ErrChecksumMismatch is returned when a backup does not match its checksum.
+var ErrChecksumMismatch = errors.New("backup checksum mismatch")
+
+// Exporter is the contract for the storage exporting keys.
+type Exporter interface {
+	// Export calls fn for every live key starting with the prefix.
+	Export(prefix string, fn func(storage.ExportRecord) error) error
+}
+
+// Importer is the contract for the storage importing keys.
+type Importer interface {
+	// Import writes the batch of records and returns the result of every
+	// record or an error if the batch as a whole failed.
+	Import(records []storage.ImportRecord, mode storage.ImportMode) ([]storage.ImportResult, error)
+}
+
+// Write writes the compressed dump of every key to w and returns the number
+// of keys written. The dump is encrypted with a new data key wrapped by keys
+// unless keys is nil.
+func Write(w io.Writer, src Exporter, keys KeyWrapper) (int, error) {
+	var ew *encryptWriter
+	if keys != nil {
+		var err error
+		if ew, err = newEncryptWriter(w, keys); err != nil {
+			return 0, err
+		}
+		w = ew
+	}
+
+	zw := gzip.NewWriter(w)
+	bw := bufio.NewWriter(zw)
+	enc := json.NewEncoder(bw)
+
+	n := 0
+	err := src.Export("", func(rec storage.ExportRecord) error {
+		n++
+		return enc.Encode(NewRecord(rec))
+	})
+	if err != nil {
+		return n, err
+	}
+	if err := bw.Flush(); err != nil {
+		return n, err
+	}
+	if err := zw.Close(); err != nil {
+		return n, err
+	}
+	if ew != nil {
+		return n, ew.Close()
+	}
+	return n, nil
+}
+
+// Create writes a backup into a new file of the directory named after the
+// time, along with its checksum file, and returns the path of the backup
+// and the number of keys in it. The backup is encrypted unless keys is nil.
+// The files appear only once they are complete.
+func Create(dir string, src Exporter, keys KeyWrapper, now time.Time) (string, int, error) {
+	name := filePrefix + now.UTC().Format(fileTimeFmt) + fileExt
+	path := filepath.Join(dir, name)
+
+	tmp, err := os.CreateTemp(dir, "."+name+".*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	n, err := Write(io.MultiWriter(tmp, h), src, keys)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", n, err
+	}
+
+	checksum := fmt.Sprintf("%s  %s\n", hex.EncodeToString(h.Sum(nil)), name)
+	if err := writeFile(path+checksumExt, []byte(checksum)); err != nil {
+		return "", n, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(path + checksumExt)
+		return "", n, err
+	}
+	return path, n, nil
+}
+
+// writeFile writes the file through a temporary one, so it never appears
+// partially written.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// List returns the paths of the backups in the directory, the oldest first.
+func List(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.Type().IsRegular() && strings.HasPrefix(name, filePrefix) && strings.HasSuffix(name, fileExt) {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+	// The names hold the time in a sortable format.
+	slices.Sort(paths)
+	return paths, nil
+}
+
+// Rotate removes all but the last keep backups in the directory along with
+// their checksum files and returns the paths of the removed backups.
+func Rotate(dir string, keep int) ([]string, error) {
+	paths, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) <= keep {
+		return nil, nil
+	}
+
+	removed := paths[:len(paths)-keep]
+	for i, path := range removed {
+		if err := os.Remove(path); err != nil {
+			return removed[:i], err
+		}
+		if err := os.Remove(path + checksumExt); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed[:i+1], err
+		}
+	}
+	return removed, nil
+}
+
+// Verify checks the backup against the checksum file next to it.
+func Verify(path string) error {
+	raw, err := os.ReadFile(path + checksumExt)
+	if err != nil {
+		return fmt.Errorf("read checksum: %w", err)
+	}
+	fields := strings.Fields(string(raw))
+	if len(fields) == 0 {
+		return fmt.Errorf("read checksum: %w", ErrChecksumMismatch)
+	}
+	want, err := hex.DecodeString(fields[0])
+	if err != nil {
+		return fmt.Errorf("read checksum: %w", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if !bytes.Equal(h.Sum(nil), want) {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+// Summary is the result of a restore.
+type Summary struct {
+	Created int
+	Updated int
+	Skipped int
+	Failed  int
+	// Failures are the errors of the first failed records.
+	Failures []error
+}
+
+func (s *Summary) fail(line int, key string, err error) {
+	s.Failed++
+	if len(s.Failures) < maxFailures {
+		s.Failures = append(s.Failures, fmt.Errorf("line %d: key %q: %w", line, key, err))
+	}
+}
+
+// Restore verifies the checksum of the backup and imports its keys in
+// batches of batchSize, a default size if it is not positive. Encrypted
+// backups are decrypted with keys, ErrEncrypted is returned if it is nil.
+// The mode tells what to do with the keys that exist. The keys missing from
+// the backup are left as they are. An error is returned if the backup
+// cannot be read or a batch fails as a whole, along with the summary of the
+// keys restored before it.
+func Restore(path string, dst Importer, keys KeyWrapper, mode storage.ImportMode, batchSize int) (Summary, error) {
+	var summary Summary
+	if err := Verify(path); err != nil {
+		return summary, err
+	}
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return summary, err
+	}
+	defer f.Close()
+
+	r, err := openBackup(f, keys)
+	if err != nil {
+		return summary, err
+	}
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return summary, err
+	}
+	defer zr.Close()
+
+	var (
+		batch []storage.ImportRecord
+		lines []int
+	)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		results, err := dst.Import(batch, mode)
+		if err != nil {
+			return err
+		}
+		for i, res := range results {
+			switch res.Outcome {
+			case storage.ImportCreated:
+				summary.Created++
+			case storage.ImportUpdated:
+				summary.Updated++
+			case storage.ImportSkipped:
+				summary.Skipped++
+			default:
+				summary.fail(lines[i], batch[i].Key, res.Err)
+			}
+		}
+		batch, lines = nil, nil
+		return nil
+	}
+
+	scanner := bufio.NewScanner(zr)
+	scanner.Buffer(make([]byte, 64<<10), maxLineSize)
+	for n := 1; scanner.Scan(); n++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var raw Record
+		if err := json.Unmarshal(scanner.Bytes(), &raw); err != nil {
+			summary.fail(n, "", errors.New("invalid record"))
+			continue
+		}
+		rec, err := raw.ImportRecord()
+		if err != nil {
+			summary.fail(n, rec.Key, err)
+			continue
+		}
+
+		batch, lines = append(batch, rec), append(lines, n)
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return summary, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return summary, fmt.Errorf("read backup: %w", err)
+	}
+	return summary, flush()
+}
@@ -0,0 +1,126 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+// fakeStorage is an in-memory storage of exported and imported keys.
+type fakeStorage struct {
+	records []storage.ExportRecord
+	batches [][]storage.ImportRecord
+	exists  map[string]bool
+}
+
+func (s *fakeStorage) Export(prefix string, fn func(storage.ExportRecord) error) error {
+	for _, rec := range s.records {
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *fakeStorage) Import(records []storage.ImportRecord, mode storage.ImportMode) ([]storage.ImportResult, error) {
+	s.batches = append(s.batches, records)
+	results := make([]storage.ImportResult, len(records))
+	for i, rec := range records {
+		switch {
+		case !s.exists[rec.Key]:
+			results[i].Outcome = storage.ImportCreated
+		case mode == storage.ImportOverwrite:
+			results[i].Outcome = storage.ImportUpdated
+		case mode == storage.ImportSkip:
+			results[i].Outcome = storage.ImportSkipped
+		default:
+			results[i] = storage.ImportResult{Outcome: storage.ImportFailed, Err: storage.ErrKeyAlreadyExists}
+		}
+	}
+	return results, nil
+}
+
+func TestCreateRestore(t *testing.T) {
+	dir := t.TempDir()
+	expiresAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	src := &fakeStorage{records: []storage.ExportRecord{
+		{Key: "a", Value: []byte(`{"n":1}`), ExpiresAt: expiresAt},
+		{Key: "b", Value: []byte("\x89PNG"), ContentType: "image/png"},
+		{Key: "c", Value: []byte(`"x"`)},
+	}}
+
+	path, n, err := Create(dir, src, nil, time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, filepath.Join(dir, "kv-20250102T030405.000Z.ndjson.gz"), path)
+	require.NoError(t, Verify(path))
+
+	dst := &fakeStorage{exists: map[string]bool{"c": true}}
+	summary, err := Restore(path, dst, nil, storage.ImportFail, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.Created)
+	assert.Equal(t, 1, summary.Failed)
+	require.Len(t, summary.Failures, 1)
+	assert.ErrorIs(t, summary.Failures[0], storage.ErrKeyAlreadyExists)
+
+	require.Len(t, dst.batches, 2)
+	assert.Equal(t, []storage.ImportRecord{
+		{Key: "a", Value: map[string]any{"n": float64(1)}, ExpiresAt: expiresAt},
+		{Key: "b", Value: storage.Blob{ContentType: "image/png", Data: []byte("\x89PNG")}},
+	}, dst.batches[0])
+	assert.Equal(t, []storage.ImportRecord{{Key: "c", Value: "x"}}, dst.batches[1])
+}
+
+func TestRestore_checksumMismatch(t *testing.T) {
+	dir := t.TempDir()
+	src := &fakeStorage{records: []storage.ExportRecord{{Key: "a", Value: []byte("1")}}}
+
+	path, _, err := Create(dir, src, nil, time.Now())
+	require.NoError(t, err)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString("garbage")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	dst := &fakeStorage{}
+	_, err = Restore(path, dst, nil, storage.ImportOverwrite, 0)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.Empty(t, dst.batches)
+
+	require.NoError(t, os.Remove(path+checksumExt))
+	_, err = Restore(path, dst, nil, storage.ImportOverwrite, 0)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRotate(t *testing.T) {
+	dir := t.TempDir()
+	src := &fakeStorage{}
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var paths []string
+	for i := range 4 {
+		path, _, err := Create(dir, src, nil, start.Add(time.Duration(i)*time.Hour))
+		require.NoError(t, err)
+		paths = append(paths, path)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o600))
+
+	removed, err := Rotate(dir, 2)
+	require.NoError(t, err)
+	assert.Equal(t, paths[:2], removed)
+
+	left, err := List(dir)
+	require.NoError(t, err)
+	assert.Equal(t, paths[2:], left)
+	assert.NoFileExists(t, paths[0]+checksumExt)
+	assert.FileExists(t, paths[2]+checksumExt)
+	assert.FileExists(t, filepath.Join(dir, "notes.txt"))
+}
@@ -0,0 +1,214 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// An encrypted backup is the magic, the big-endian length of the wrapped
+// data key, the wrapped key and the compressed dump sealed with the key in
+// frames. A frame is the big-endian length of the sealed data and the data
+// sealed with the number of the frame as the nonce and whether it is the
+// last one as additional data, so frames cannot be reordered or cut off.
+const (
+	encryptedMagic = "KVBACKUP-AESGCM\n"
+	backupKeySize  = 32
+	frameSize      = 64 << 10
+	// maxWrappedKeySize bounds the length of the wrapped key read from a
+	// backup.
+	maxWrappedKeySize = 1 << 10
+)
+
+var (
+	// ErrEncrypted is returned when restoring an encrypted backup without
+	// the keys to decrypt it.
+	ErrEncrypted = errors.New("backup is encrypted but encryption is disabled")
+	// ErrCorrupted is returned when an encrypted backup cannot be decrypted.
+	ErrCorrupted = errors.New("backup is corrupted")
+)
+
+// KeyWrapper is the contract for the master key sealing the data keys of
+// encrypted backups.
+type KeyWrapper interface {
+	// WrapKey seals the data key.
+	WrapKey(key []byte) []byte
+	// UnwrapKey opens the data key sealed by WrapKey.
+	UnwrapKey(wrapped []byte) ([]byte, error)
+}
+
+// encryptWriter seals the data written to it with a new data key, see
+// encryptedMagic. Close writes the last frame.
+type encryptWriter struct {
+	w     io.Writer
+	aead  cipher.AEAD
+	buf   []byte
+	frame uint64
+}
+
+func newEncryptWriter(w io.Writer, keys KeyWrapper) (*encryptWriter, error) {
+	key := make([]byte, backupKeySize)
+	_, _ = rand.Read(key)
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped := keys.WrapKey(key)
+	header := make([]byte, 0, len(encryptedMagic)+4+len(wrapped))
+	header = append(header, encryptedMagic...)
+	header = binary.BigEndian.AppendUint32(header, uint32(len(wrapped)))
+	header = append(header, wrapped...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &encryptWriter{w: w, aead: aead}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	e.buf = append(e.buf, p...)
+	for len(e.buf) > frameSize {
+		if err := e.seal(e.buf[:frameSize], false); err != nil {
+			return 0, err
+		}
+		e.buf = e.buf[frameSize:]
+	}
+	return len(p), nil
+}
+
+// Close writes the rest of the data as the last frame, it does not close the
+// underlying writer.
+func (e *encryptWriter) Close() error {
+	err := e.seal(e.buf, true)
+	e.buf = nil
+	return err
+}
+
+func (e *encryptWriter) seal(data []byte, last bool) error {
+	sealed := e.aead.Seal(nil, frameNonce(e.aead, e.frame), data, frameAD(last))
+	e.frame++
+
+	frame := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(sealed)), uint32(len(sealed)))
+	_, err := e.w.Write(append(frame, sealed...))
+	return err
+}
+
+// decryptReader opens the frames sealed by encryptWriter.
+type decryptReader struct {
+	r     *bufio.Reader
+	aead  cipher.AEAD
+	buf   []byte
+	frame uint64
+	last  bool
+}
+
+// openBackup returns the reader of the dump in the backup, decrypting it if
+// it is encrypted.
+func openBackup(r io.Reader, keys KeyWrapper) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(encryptedMagic))
+	if err != nil || !bytes.Equal(magic, []byte(encryptedMagic)) {
+		// Backups are only encrypted if the encryption is enabled.
+		return br, nil
+	}
+	if keys == nil {
+		return nil, ErrEncrypted
+	}
+	if _, err := br.Discard(len(encryptedMagic)); err != nil {
+		return nil, err
+	}
+
+	var size uint32
+	if err := binary.Read(br, binary.BigEndian, &size); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCorrupted, err)
+	}
+	if size > maxWrappedKeySize {
+		return nil, fmt.Errorf("%w: wrapped key is too long", ErrCorrupted)
+	}
+	wrapped := make([]byte, size)
+	if _, err := io.ReadFull(br, wrapped); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCorrupted, err)
+	}
+
+	key, err := keys.UnwrapKey(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("unwrap backup key: %w", err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{r: br, aead: aead}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.last {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+func (d *decryptReader) open() error {
+	var size uint32
+	if err := binary.Read(d.r, binary.BigEndian, &size); err != nil {
+		return fmt.Errorf("%w: %w", ErrCorrupted, io.ErrUnexpectedEOF)
+	}
+	if size > frameSize+uint32(d.aead.Overhead()) {
+		return fmt.Errorf("%w: frame is too long", ErrCorrupted)
+	}
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(d.r, sealed); err != nil {
+		return fmt.Errorf("%w: %w", ErrCorrupted, io.ErrUnexpectedEOF)
+	}
+
+	nonce := frameNonce(d.aead, d.frame)
+	data, err := d.aead.Open(nil, nonce, sealed, frameAD(false))
+	if err != nil {
+		if data, err = d.aead.Open(nil, nonce, sealed, frameAD(true)); err != nil {
+			return fmt.Errorf("%w: %w", ErrCorrupted, err)
+		}
+		d.last = true
+		if _, err := d.r.Peek(1); err != io.EOF {
+			return fmt.Errorf("%w: data after the last frame", ErrCorrupted)
+		}
+	}
+	d.frame++
+	d.buf = data
+	return nil
+}
+
+func frameNonce(aead cipher.AEAD, frame uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], frame)
+	return nonce
+}
+
+func frameAD(last bool) []byte {
+	if last {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
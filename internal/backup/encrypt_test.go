@@ -0,0 +1,130 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/cipher"
+	"io"
+	"math/rand/v2"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+// testKeys wraps the data keys with a master key of the test.
+type testKeys struct {
+	master cipher.AEAD
+}
+
+func newTestKeys(t *testing.T, seed byte) *testKeys {
+	master, err := newAEAD(bytes.Repeat([]byte{seed}, 32))
+	require.NoError(t, err)
+	return &testKeys{master: master}
+}
+
+func (k *testKeys) WrapKey(key []byte) []byte {
+	nonce := make([]byte, k.master.NonceSize())
+	return k.master.Seal(nonce, nonce, key, nil)
+}
+
+func (k *testKeys) UnwrapKey(wrapped []byte) ([]byte, error) {
+	nonce, sealed := wrapped[:k.master.NonceSize()], wrapped[k.master.NonceSize():]
+	return k.master.Open(nil, nonce, sealed, nil)
+}
+
+func TestCreateRestore_encrypted(t *testing.T) {
+	dir := t.TempDir()
+	keys := newTestKeys(t, 1)
+
+	// The blob does not compress, so the dump spans several frames.
+	blob := make([]byte, 3*frameSize)
+	rng := rand.NewChaCha8([32]byte{})
+	_, _ = rng.Read(blob)
+	src := &fakeStorage{records: []storage.ExportRecord{
+		{Key: "secret", Value: []byte(`"top secret value"`)},
+		{Key: "blob", Value: blob, ContentType: "application/octet-stream"},
+	}}
+
+	path, n, err := Create(dir, src, keys, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	require.NoError(t, Verify(path))
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(raw, []byte(encryptedMagic)))
+	assert.NotContains(t, string(raw), "top secret value")
+	assert.NotContains(t, string(raw), "secret")
+	assert.False(t, bytes.Contains(raw, blob[:64]), "blob data must not reach the file")
+	_, err = gzip.NewReader(bytes.NewReader(raw))
+	assert.Error(t, err, "the dump must not be readable without the key")
+
+	dst := &fakeStorage{}
+	summary, err := Restore(path, dst, keys, storage.ImportOverwrite, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.Created)
+	require.Len(t, dst.batches, 1)
+	assert.Equal(t, []storage.ImportRecord{
+		{Key: "secret", Value: "top secret value"},
+		{Key: "blob", Value: storage.Blob{ContentType: "application/octet-stream", Data: blob}},
+	}, dst.batches[0])
+
+	_, err = Restore(path, &fakeStorage{}, nil, storage.ImportOverwrite, 0)
+	assert.ErrorIs(t, err, ErrEncrypted)
+
+	_, err = Restore(path, &fakeStorage{}, newTestKeys(t, 2), storage.ImportOverwrite, 0)
+	assert.Error(t, err, "a backup cannot be decrypted with another master key")
+}
+
+func TestOpenBackup_corrupted(t *testing.T) {
+	keys := newTestKeys(t, 1)
+	blob := make([]byte, 2*frameSize)
+	rng := rand.NewChaCha8([32]byte{})
+	_, _ = rng.Read(blob)
+	src := &fakeStorage{records: []storage.ExportRecord{{Key: "blob", Value: blob, ContentType: "image/png"}}}
+
+	var buf bytes.Buffer
+	_, err := Write(&buf, src, keys)
+	require.NoError(t, err)
+	dump := buf.Bytes()
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "truncated at a frame",
+			data: dump[:len(encryptedMagic)+4+len(keys.WrapKey(make([]byte, backupKeySize)))+4+frameSize+keys.master.Overhead()],
+		},
+		{
+			name: "truncated in a frame",
+			data: dump[:len(dump)-1],
+		},
+		{
+			name: "tampered",
+			data: func() []byte {
+				tampered := bytes.Clone(dump)
+				tampered[len(tampered)-1] ^= 0xff
+				return tampered
+			}(),
+		},
+		{
+			name: "data after the last frame",
+			data: append(bytes.Clone(dump), 0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := openBackup(bytes.NewReader(tt.data), keys)
+			require.NoError(t, err)
+			_, err = io.ReadAll(r)
+			assert.ErrorIs(t, err, ErrCorrupted)
+		})
+	}
+}
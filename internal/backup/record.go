@@ -0,0 +1,81 @@
+package backup
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+// Record is a line of the newline-delimited JSON of exported keys, the format
+// of backups as well as of the export and import of the HTTP API. The value
+// of a blob is its base64 encoded data.
+type Record struct {
+	Key      string          `json:"key"`
+	Value    json.RawMessage `json:"value"`
+	Metadata *Metadata       `json:"metadata,omitempty"`
+}
+
+// Metadata is the metadata of an exported key, omitted if it has none.
+type Metadata struct {
+	// ContentType is the content type of a blob, empty for JSON values.
+	ContentType string `json:"content_type,omitempty"`
+	// ExpiresAt is the expiration time of the key, nil if it does not expire.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// NewRecord returns the record of the exported key.
+func NewRecord(rec storage.ExportRecord) Record {
+	r := Record{Key: rec.Key, Value: rec.Value}
+	if rec.ContentType != "" {
+		// A base64 string needs no escaping.
+		r.Value = json.RawMessage(`"` + base64.StdEncoding.EncodeToString(rec.Value) + `"`)
+	}
+	if rec.ContentType != "" || !rec.ExpiresAt.IsZero() {
+		r.Metadata = &Metadata{ContentType: rec.ContentType}
+		if !rec.ExpiresAt.IsZero() {
+			r.Metadata.ExpiresAt = &rec.ExpiresAt
+		}
+	}
+	return r
+}
+
+// ImportRecord returns the record to import with the decoded value. The key
+// of the returned record is set even if the record is invalid, to report it.
+func (r Record) ImportRecord() (storage.ImportRecord, error) {
+	rec := storage.ImportRecord{Key: r.Key}
+	if r.Key == "" {
+		return rec, errors.New("key cannot be empty")
+	}
+	if len(r.Value) == 0 {
+		return rec, errors.New("value is missing")
+	}
+
+	var metadata Metadata
+	if r.Metadata != nil {
+		metadata = *r.Metadata
+	}
+	if metadata.ExpiresAt != nil {
+		rec.ExpiresAt = *metadata.ExpiresAt
+	}
+
+	if metadata.ContentType != "" {
+		var encoded string
+		if err := json.Unmarshal(r.Value, &encoded); err != nil {
+			return rec, errors.New("blob value must be a base64 string")
+		}
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return rec, errors.New("blob value must be a base64 string")
+		}
+		rec.Value = storage.Blob{ContentType: metadata.ContentType, Data: data}
+		return rec, nil
+	}
+
+	if err := json.Unmarshal(r.Value, &rec.Value); err != nil {
+		return rec, errors.New("invalid value")
+	}
+	return rec, nil
+}
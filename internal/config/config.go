@@ -11,27 +11,133 @@ import (
 
 // Config is the main configuration struct.
 type Config struct {
-	Env       string          `koanf:"env"`
-	Tarantool TarantoolConfig `koanf:"tarantool"`
-	HTTP      HTTPConfig      `koanf:"http"`
+	Env         string            `koanf:"env"`
+	Tarantool   TarantoolConfig   `koanf:"tarantool"`
+	HTTP        HTTPConfig        `koanf:"http"`
+	SoftDelete  SoftDeleteConfig  `koanf:"soft_delete"`
+	Schemas     []SchemaConfig    `koanf:"schemas"`
+	Compression CompressionConfig `koanf:"compression"`
+	Encryption  EncryptionConfig  `koanf:"encryption"`
+	Expiry      ExpiryConfig      `koanf:"expiry"`
+	RESP        RESPConfig        `koanf:"resp"`
+	Memcached   MemcachedConfig   `koanf:"memcached"`
+	GRPC        GRPCConfig        `koanf:"grpc"`
+	Backup      BackupConfig      `koanf:"backup"`
+	Migrations  MigrationsConfig  `koanf:"migrations"`
 }
 
 // TarantoolConfig is the configuration for the Tarantool instance.
 type TarantoolConfig struct {
-	Host     string        `koanf:"host"`
-	Port     int           `koanf:"port"`
-	User     string        `koanf:"user"`
-	Password string        `koanf:"password"`
-	Timeout  time.Duration `koanf:"timeout"`
-	KVSpace  string        `koanf:"kv_space"`
-	KVIndex  string        `koanf:"kv_index"`
+	Host            string            `koanf:"host"`
+	Port            int               `koanf:"port"`
+	User            string            `koanf:"user"`
+	Password        string            `koanf:"password"`
+	Timeout         time.Duration     `koanf:"timeout"`
+	KVSpace         string            `koanf:"kv_space"`
+	KVIndex         string            `koanf:"kv_index"`
+	KVDeletedIndex  string            `koanf:"kv_deleted_index"`
+	EventsSpace     string            `koanf:"events_space"`
+	HistorySpace    string            `koanf:"history_space"`
+	HistoryDepth    int               `koanf:"history_depth"`
+	ChunkSpace      string            `koanf:"chunk_space"`
+	ChunkSize       int               `koanf:"chunk_size"`
+	KeysSpace       string            `koanf:"keys_space"`
+	LocksSpace      string            `koanf:"locks_space"`
+	StructuresSpace string            `koanf:"structures_space"`
+	ExpirySpace     string            `koanf:"expiry_space"`
+	SchemasSpace    string            `koanf:"schemas_space"`
+	JSONIndexes     []JSONIndexConfig `koanf:"json_indexes"`
+}
+
+// JSONIndexConfig is the configuration for a secondary index on a field of
+// JSON object values.
+type JSONIndexConfig struct {
+	Name string `koanf:"name"`
+	Path string `koanf:"path"`
+	Type string `koanf:"type"`
 }
 
 // HTTPConfig is the configuration for the HTTP server.
 type HTTPConfig struct {
-	Port       int           `koanf:"port"`
-	Timeout    time.Duration `koanf:"timeout"`
-	KVBasePath string        `koanf:"kv_base_path"`
+	Port           int           `koanf:"port"`
+	Timeout        time.Duration `koanf:"timeout"`
+	KVBasePath     string        `koanf:"kv_base_path"`
+	AdminBasePath  string        `koanf:"admin_base_path"`
+	LocksBasePath  string        `koanf:"locks_base_path"`
+	ListsBasePath  string        `koanf:"lists_base_path"`
+	HashesBasePath string        `koanf:"hashes_base_path"`
+	SetsBasePath   string        `koanf:"sets_base_path"`
+	// V2BasePath is the base path of the second version of the API, which
+	// answers errors with problem details. Empty disables it.
+	V2BasePath string `koanf:"v2_base_path"`
+}
+
+// SoftDeleteConfig is the configuration for keeping tombstones of deleted
+// keys.
+type SoftDeleteConfig struct {
+	Enabled       bool          `koanf:"enabled"`
+	Retention     time.Duration `koanf:"retention"`
+	PurgeInterval time.Duration `koanf:"purge_interval"`
+}
+
+// SchemaConfig is the configuration for a JSON Schema bound to a key prefix.
+type SchemaConfig struct {
+	Prefix string `koanf:"prefix"`
+	File   string `koanf:"file"`
+}
+
+// CompressionConfig is the configuration for compressing large values.
+type CompressionConfig struct {
+	Algorithm string `koanf:"algorithm"`
+	Threshold int    `koanf:"threshold"`
+}
+
+// EncryptionConfig is the configuration for encrypting values at rest.
+type EncryptionConfig struct {
+	Enabled           bool          `koanf:"enabled"`
+	MasterKeyFile     string        `koanf:"master_key_file"`
+	ReencryptInterval time.Duration `koanf:"reencrypt_interval"`
+}
+
+// ExpiryConfig is the configuration for deleting expired keys.
+type ExpiryConfig struct {
+	PurgeInterval time.Duration `koanf:"purge_interval"`
+}
+
+// RESPConfig is the configuration for the Redis protocol server.
+type RESPConfig struct {
+	Enabled bool          `koanf:"enabled"`
+	Port    int           `koanf:"port"`
+	Timeout time.Duration `koanf:"timeout"`
+}
+
+// MemcachedConfig is the configuration for the memcached protocol server.
+type MemcachedConfig struct {
+	Enabled     bool          `koanf:"enabled"`
+	Port        int           `koanf:"port"`
+	Timeout     time.Duration `koanf:"timeout"`
+	MaxItemSize int           `koanf:"max_item_size"`
+}
+
+// GRPCConfig is the configuration for the gRPC server.
+type GRPCConfig struct {
+	Enabled bool `koanf:"enabled"`
+	Port    int  `koanf:"port"`
+}
+
+// BackupConfig is the configuration for periodic backups of the KV space.
+type BackupConfig struct {
+	Enabled  bool          `koanf:"enabled"`
+	Dir      string        `koanf:"dir"`
+	Interval time.Duration `koanf:"interval"`
+	Keep     int           `koanf:"keep"`
+}
+
+// MigrationsConfig is the configuration for the schema migrations.
+type MigrationsConfig struct {
+	// Auto applies the pending migrations on start, otherwise they are
+	// applied by the migrate command.
+	Auto bool `koanf:"auto"`
 }
 
 // MustLoad returns the configuration loaded from the environment, in case of
@@ -20,10 +20,66 @@ tarantool:
   timeout: 5s
   kv_space: kv
   kv_index: primary
+  kv_deleted_index: deleted_at
+  events_space: kv_events
+  history_space: kv_history
+  history_depth: 5
+  chunk_space: kv_chunks
+  chunk_size: 1024
+  keys_space: kv_keys
+  locks_space: kv_locks
+  structures_space: kv_structures
+  expiry_space: kv_expiry
+  schemas_space: kv_schemas
+  json_indexes:
+    - name: country
+      path: country
+      type: string
 http:
   port: 8080
   timeout: 30s
   kv_base_path: /api/kv
+  admin_base_path: /api/admin
+  locks_base_path: /api/locks
+  lists_base_path: /api/lists
+  hashes_base_path: /api/hashes
+  sets_base_path: /api/sets
+  v2_base_path: /api/v2
+soft_delete:
+  enabled: true
+  retention: 24h
+  purge_interval: 1m
+schemas:
+  - prefix: "user:"
+    file: schemas/user.json
+compression:
+  algorithm: zstd
+  threshold: 1024
+encryption:
+  enabled: true
+  master_key_file: secrets/master.key
+  reencrypt_interval: 10m
+expiry:
+  purge_interval: 2s
+resp:
+  enabled: true
+  port: 6380
+  timeout: 5m
+memcached:
+  enabled: true
+  port: 11212
+  timeout: 1m
+  max_item_size: 2048
+grpc:
+  enabled: true
+  port: 9091
+backup:
+  enabled: true
+  dir: /var/backups/kv
+  interval: 6h
+  keep: 3
+migrations:
+  auto: true
 `
 
 	tmpFile, err := os.CreateTemp("", "test-config-*.yml")
@@ -49,9 +105,51 @@ http:
 	assert.Equal(t, 5*time.Second, cfg.Tarantool.Timeout)
 	assert.Equal(t, "kv", cfg.Tarantool.KVSpace)
 	assert.Equal(t, "primary", cfg.Tarantool.KVIndex)
+	assert.Equal(t, "deleted_at", cfg.Tarantool.KVDeletedIndex)
+	assert.Equal(t, "kv_events", cfg.Tarantool.EventsSpace)
+	assert.Equal(t, "kv_history", cfg.Tarantool.HistorySpace)
+	assert.Equal(t, "kv_chunks", cfg.Tarantool.ChunkSpace)
+	assert.Equal(t, 1024, cfg.Tarantool.ChunkSize)
+	assert.Equal(t, "kv_keys", cfg.Tarantool.KeysSpace)
+	assert.Equal(t, "kv_locks", cfg.Tarantool.LocksSpace)
+	assert.Equal(t, "kv_structures", cfg.Tarantool.StructuresSpace)
+	assert.Equal(t, "kv_expiry", cfg.Tarantool.ExpirySpace)
+	assert.Equal(t, "kv_schemas", cfg.Tarantool.SchemasSpace)
+	assert.Equal(t, 5, cfg.Tarantool.HistoryDepth)
+	assert.Equal(t, []JSONIndexConfig{{Name: "country", Path: "country", Type: "string"}}, cfg.Tarantool.JSONIndexes)
 	assert.Equal(t, 8080, cfg.HTTP.Port)
 	assert.Equal(t, 30*time.Second, cfg.HTTP.Timeout)
 	assert.Equal(t, "/api/kv", cfg.HTTP.KVBasePath)
+	assert.Equal(t, "/api/admin", cfg.HTTP.AdminBasePath)
+	assert.Equal(t, "/api/locks", cfg.HTTP.LocksBasePath)
+	assert.Equal(t, "/api/lists", cfg.HTTP.ListsBasePath)
+	assert.Equal(t, "/api/hashes", cfg.HTTP.HashesBasePath)
+	assert.Equal(t, "/api/sets", cfg.HTTP.SetsBasePath)
+	assert.Equal(t, "/api/v2", cfg.HTTP.V2BasePath)
+	assert.True(t, cfg.SoftDelete.Enabled)
+	assert.Equal(t, 24*time.Hour, cfg.SoftDelete.Retention)
+	assert.Equal(t, time.Minute, cfg.SoftDelete.PurgeInterval)
+	assert.Equal(t, []SchemaConfig{{Prefix: "user:", File: "schemas/user.json"}}, cfg.Schemas)
+	assert.Equal(t, "zstd", cfg.Compression.Algorithm)
+	assert.Equal(t, 1024, cfg.Compression.Threshold)
+	assert.True(t, cfg.Encryption.Enabled)
+	assert.Equal(t, "secrets/master.key", cfg.Encryption.MasterKeyFile)
+	assert.Equal(t, 10*time.Minute, cfg.Encryption.ReencryptInterval)
+	assert.Equal(t, 2*time.Second, cfg.Expiry.PurgeInterval)
+	assert.True(t, cfg.RESP.Enabled)
+	assert.Equal(t, 6380, cfg.RESP.Port)
+	assert.Equal(t, 5*time.Minute, cfg.RESP.Timeout)
+	assert.True(t, cfg.Memcached.Enabled)
+	assert.Equal(t, 11212, cfg.Memcached.Port)
+	assert.Equal(t, time.Minute, cfg.Memcached.Timeout)
+	assert.Equal(t, 2048, cfg.Memcached.MaxItemSize)
+	assert.True(t, cfg.GRPC.Enabled)
+	assert.Equal(t, 9091, cfg.GRPC.Port)
+	assert.True(t, cfg.Backup.Enabled)
+	assert.Equal(t, "/var/backups/kv", cfg.Backup.Dir)
+	assert.Equal(t, 6*time.Hour, cfg.Backup.Interval)
+	assert.Equal(t, 3, cfg.Backup.Keep)
+	assert.True(t, cfg.Migrations.Auto)
 }
 
 func TestLoad_FileNotFound(t *testing.T) {
@@ -0,0 +1,331 @@
+// Package jsonpatch applies JSON Patch (RFC 6902) and JSON Merge Patch
+// (RFC 7396) documents to decoded JSON values, the ones encoding/json
+// decodes into any.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidPatch is returned when decoding a malformed patch.
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrPathNotFound is returned when a path of the patch does not point to
+	// a location of the value.
+	ErrPathNotFound = errors.New("path not found")
+	// ErrTestFailed is returned when the test operation of the patch does
+	// not match the value.
+	ErrTestFailed = errors.New("test failed")
+)
+
+// Operation is an operation of a JSON Patch.
+type Operation struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+	// From is the source path of the move and copy operations.
+	From string `json:"from,omitempty"`
+	// Value is the value of the add, replace and test operations.
+	Value any `json:"value,omitempty"`
+}
+
+// Patch is a JSON Patch, the operations of which are applied in order.
+type Patch []Operation
+
+// Decode decodes the JSON Patch and checks its operations are well formed.
+func Decode(data []byte) (Patch, error) {
+	var raw []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+
+	patch := make(Patch, 0, len(raw))
+	for i, fields := range raw {
+		op, err := decodeOperation(fields)
+		if err != nil {
+			return nil, fmt.Errorf("%w: operation %d: %w", ErrInvalidPatch, i, err)
+		}
+		patch = append(patch, op)
+	}
+	return patch, nil
+}
+
+// decodeOperation decodes the operation from its members, the value is
+// required by the operations that use it, even if it is null.
+func decodeOperation(fields map[string]json.RawMessage) (Operation, error) {
+	var op Operation
+	for name, target := range map[string]*string{"op": &op.Op, "path": &op.Path, "from": &op.From} {
+		raw, ok := fields[name]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(raw, target); err != nil {
+			return Operation{}, fmt.Errorf("%s must be a string", name)
+		}
+	}
+	if _, ok := fields["path"]; !ok {
+		return Operation{}, errors.New("path is required")
+	}
+	if _, err := parsePointer(op.Path); err != nil {
+		return Operation{}, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		raw, ok := fields["value"]
+		if !ok {
+			return Operation{}, fmt.Errorf("value is required by %s", op.Op)
+		}
+		if err := json.Unmarshal(raw, &op.Value); err != nil {
+			return Operation{}, err
+		}
+	case "move", "copy":
+		if _, ok := fields["from"]; !ok {
+			return Operation{}, fmt.Errorf("from is required by %s", op.Op)
+		}
+		if _, err := parsePointer(op.From); err != nil {
+			return Operation{}, err
+		}
+		if op.Op == "move" && strings.HasPrefix(op.Path, op.From+"/") {
+			return Operation{}, errors.New("cannot move a value into itself")
+		}
+	case "remove":
+	case "":
+		return Operation{}, errors.New("op is required")
+	default:
+		return Operation{}, fmt.Errorf("unknown op %q", op.Op)
+	}
+	return op, nil
+}
+
+// Apply applies the patch to the value and returns the patched value. The
+// value is left as it is, the patch is applied to a copy of it, so either
+// every operation is applied or none is.
+func (p Patch) Apply(value any) (any, error) {
+	doc := deepCopy(value)
+	for i, op := range p {
+		var err error
+		if doc, err = op.apply(doc); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return doc, nil
+}
+
+func (op Operation) apply(doc any) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add":
+		return add(doc, path, deepCopy(op.Value))
+	case "remove":
+		return remove(doc, path)
+	case "replace":
+		if _, err := get(doc, path); err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return deepCopy(op.Value), nil
+		}
+		doc, err := remove(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, deepCopy(op.Value))
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, fmt.Errorf("from: %w", err)
+		}
+		if op.Op == "move" {
+			if op.From == op.Path {
+				return doc, nil
+			}
+			if doc, err = remove(doc, from); err != nil {
+				return nil, err
+			}
+		} else {
+			value = deepCopy(value)
+		}
+		return add(doc, path, value)
+	case "test":
+		value, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(value, op.Value) {
+			return nil, ErrTestFailed
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, op.Op)
+}
+
+// parsePointer returns the reference tokens of the JSON pointer, none for
+// the whole value.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("path %q must be empty or start with /", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// get returns the value at the path.
+func get(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, ErrPathNotFound
+			}
+			doc = value
+		case []any:
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, ErrPathNotFound
+		}
+	}
+	return doc, nil
+}
+
+// add adds the value at the path and returns the new value of doc, arrays
+// get a new backing array when the value is inserted into them.
+func add(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	token, rest := path[0], path[1:]
+	switch node := doc.(type) {
+	case map[string]any:
+		if len(rest) == 0 {
+			node[token] = value
+			return node, nil
+		}
+		child, ok := node[token]
+		if !ok {
+			return nil, ErrPathNotFound
+		}
+		child, err := add(child, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		node[token] = child
+		return node, nil
+	case []any:
+		if len(rest) == 0 {
+			i := len(node)
+			if token != "-" {
+				var err error
+				if i, err = arrayIndex(token, len(node)); err != nil {
+					return nil, err
+				}
+			}
+			return append(node[:i:i], append([]any{value}, node[i:]...)...), nil
+		}
+		i, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		if node[i], err = add(node[i], rest, value); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+	return nil, ErrPathNotFound
+}
+
+// remove removes the value at the path and returns the new value of doc.
+func remove(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: cannot remove the whole value", ErrInvalidPatch)
+	}
+
+	token, rest := path[0], path[1:]
+	switch node := doc.(type) {
+	case map[string]any:
+		child, ok := node[token]
+		if !ok {
+			return nil, ErrPathNotFound
+		}
+		if len(rest) == 0 {
+			delete(node, token)
+			return node, nil
+		}
+		child, err := remove(child, rest)
+		if err != nil {
+			return nil, err
+		}
+		node[token] = child
+		return node, nil
+	case []any:
+		i, err := arrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		if len(rest) == 0 {
+			return append(node[:i:i], node[i+1:]...), nil
+		}
+		if node[i], err = remove(node[i], rest); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+	return nil, ErrPathNotFound
+}
+
+// arrayIndex parses the array index of the token, which must be at most
+// limit. Leading zeros are not allowed.
+func arrayIndex(token string, limit int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.TrimLeft(token, "0123456789") != "" {
+		return 0, fmt.Errorf("%w: %q is not an array index", ErrPathNotFound, token)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i > limit {
+		return 0, fmt.Errorf("%w: array index %s is out of range", ErrPathNotFound, token)
+	}
+	return i, nil
+}
+
+// deepCopy returns a copy of the decoded JSON value sharing nothing with it.
+func deepCopy(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(value))
+		for k, v := range value {
+			c[k] = deepCopy(v)
+		}
+		return c
+	case []any:
+		c := make([]any, len(value))
+		for i, v := range value {
+			c[i] = deepCopy(v)
+		}
+		return c
+	}
+	return value
+}
@@ -0,0 +1,189 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeJSON(t *testing.T, data string) any {
+	t.Helper()
+
+	var value any
+	require.NoError(t, json.Unmarshal([]byte(data), &value))
+	return value
+}
+
+func TestPatch_Apply(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		patch   string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "add member",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"add","path":"/baz","value":"qux"}]`,
+			want:  `{"baz":"qux","foo":"bar"}`,
+		},
+		{
+			name:  "add array element",
+			doc:   `{"foo":["bar","baz"]}`,
+			patch: `[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			want:  `{"foo":["bar","qux","baz"]}`,
+		},
+		{
+			name:  "append array element",
+			doc:   `{"foo":["bar"]}`,
+			patch: `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`,
+			want:  `{"foo":["bar",["abc","def"]]}`,
+		},
+		{
+			name:  "add null",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"add","path":"/baz","value":null}]`,
+			want:  `{"baz":null,"foo":"bar"}`,
+		},
+		{
+			name:  "remove member",
+			doc:   `{"baz":"qux","foo":"bar"}`,
+			patch: `[{"op":"remove","path":"/baz"}]`,
+			want:  `{"foo":"bar"}`,
+		},
+		{
+			name:  "remove array element",
+			doc:   `{"foo":["bar","qux","baz"]}`,
+			patch: `[{"op":"remove","path":"/foo/1"}]`,
+			want:  `{"foo":["bar","baz"]}`,
+		},
+		{
+			name:  "replace",
+			doc:   `{"baz":"qux","foo":"bar"}`,
+			patch: `[{"op":"replace","path":"/baz","value":"boo"}]`,
+			want:  `{"baz":"boo","foo":"bar"}`,
+		},
+		{
+			name:  "replace whole value",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"replace","path":"","value":[1]}]`,
+			want:  `[1]`,
+		},
+		{
+			name:  "move",
+			doc:   `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			patch: `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			want:  `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{
+			name:  "move array element",
+			doc:   `{"foo":["all","grass","cows","eat"]}`,
+			patch: `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			want:  `{"foo":["all","cows","eat","grass"]}`,
+		},
+		{
+			name:  "copy",
+			doc:   `{"foo":{"bar":1}}`,
+			patch: `[{"op":"copy","from":"/foo","path":"/baz"},{"op":"replace","path":"/baz/bar","value":2}]`,
+			want:  `{"baz":{"bar":2},"foo":{"bar":1}}`,
+		},
+		{
+			name:  "escaped tokens",
+			doc:   `{"a/b":{"m~n":1}}`,
+			patch: `[{"op":"replace","path":"/a~1b/m~0n","value":2}]`,
+			want:  `{"a/b":{"m~n":2}}`,
+		},
+		{
+			name:  "test passes",
+			doc:   `{"baz":"qux","foo":["a",2,"c"]}`,
+			patch: `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+			want:  `{"baz":"qux","foo":["a",2,"c"]}`,
+		},
+		{
+			name:    "test fails",
+			doc:     `{"baz":"qux"}`,
+			patch:   `[{"op":"test","path":"/baz","value":"bar"}]`,
+			wantErr: ErrTestFailed,
+		},
+		{
+			name:    "add to missing parent",
+			doc:     `{"foo":"bar"}`,
+			patch:   `[{"op":"add","path":"/baz/bat","value":"qux"}]`,
+			wantErr: ErrPathNotFound,
+		},
+		{
+			name:    "remove missing member",
+			doc:     `{"foo":"bar"}`,
+			patch:   `[{"op":"remove","path":"/baz"}]`,
+			wantErr: ErrPathNotFound,
+		},
+		{
+			name:    "array index out of range",
+			doc:     `{"foo":["bar"]}`,
+			patch:   `[{"op":"add","path":"/foo/2","value":"qux"}]`,
+			wantErr: ErrPathNotFound,
+		},
+		{
+			name:    "array index with leading zero",
+			doc:     `{"foo":["bar","baz"]}`,
+			patch:   `[{"op":"replace","path":"/foo/01","value":"qux"}]`,
+			wantErr: ErrPathNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := decodeJSON(t, tt.doc)
+
+			patch, err := Decode([]byte(tt.patch))
+			require.NoError(t, err)
+
+			got, err := patch.Apply(doc)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, decodeJSON(t, tt.want), got)
+			assert.Equal(t, decodeJSON(t, tt.doc), doc, "value must be left as it is")
+		})
+	}
+}
+
+func TestPatch_Apply_atomic(t *testing.T) {
+	doc := decodeJSON(t, `{"foo":"bar"}`)
+
+	patch, err := Decode([]byte(`[{"op":"add","path":"/baz","value":1},{"op":"test","path":"/foo","value":"qux"}]`))
+	require.NoError(t, err)
+
+	_, err = patch.Apply(doc)
+	require.ErrorIs(t, err, ErrTestFailed)
+	assert.Equal(t, decodeJSON(t, `{"foo":"bar"}`), doc)
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+	}{
+		{name: "not an array", patch: `{"op":"add"}`},
+		{name: "missing op", patch: `[{"path":"/a","value":1}]`},
+		{name: "unknown op", patch: `[{"op":"merge","path":"/a"}]`},
+		{name: "missing path", patch: `[{"op":"remove"}]`},
+		{name: "relative path", patch: `[{"op":"remove","path":"a"}]`},
+		{name: "missing value", patch: `[{"op":"add","path":"/a"}]`},
+		{name: "missing from", patch: `[{"op":"copy","path":"/a"}]`},
+		{name: "move into itself", patch: `[{"op":"move","from":"/a","path":"/a/b"}]`},
+		{name: "path not a string", patch: `[{"op":"remove","path":1}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode([]byte(tt.patch))
+			assert.ErrorIs(t, err, ErrInvalidPatch)
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package jsonpatch
+
+// MergePatch applies the JSON Merge Patch to the value and returns the
+// patched value. Members of the patch set to null are removed from the
+// value, a patch that is not an object replaces the value. The value is left
+// as it is.
+func MergePatch(value, patch any) any {
+	return mergePatch(deepCopy(value), patch)
+}
+
+func mergePatch(value, patch any) any {
+	members, ok := patch.(map[string]any)
+	if !ok {
+		return deepCopy(patch)
+	}
+
+	target, ok := value.(map[string]any)
+	if !ok {
+		target = make(map[string]any, len(members))
+	}
+	for name, member := range members {
+		if member == nil {
+			delete(target, name)
+			continue
+		}
+		target[name] = mergePatch(target[name], member)
+	}
+	return target
+}
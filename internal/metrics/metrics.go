@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "kv"
+
+var (
+	compressedBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "compression", "compressed_bytes"),
+		"Size of the compressed values stored.",
+		nil, nil,
+	)
+	uncompressedBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "compression", "uncompressed_bytes"),
+		"Size of the compressed values stored before compression.",
+		nil, nil,
+	)
+	savedBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "compression", "saved_bytes"),
+		"Number of bytes saved by compressing the values stored.",
+		nil, nil,
+	)
+)
+
+// CompressionSizes returns the size of the compressed values stored and
+// their size before compression.
+type CompressionSizes func() (compressed, uncompressed uint64, err error)
+
+// CompressionCollector collects the gauges of the compressed values stored,
+// reading their sizes on every collection.
+type CompressionCollector struct {
+	sizes CompressionSizes
+}
+
+// NewCompressionCollector creates a new collector of the gauges of the
+// compressed values stored.
+func NewCompressionCollector(sizes CompressionSizes) *CompressionCollector {
+	return &CompressionCollector{sizes: sizes}
+}
+
+// Describe implements prometheus.Collector.
+func (c *CompressionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- compressedBytesDesc
+	ch <- uncompressedBytesDesc
+	ch <- savedBytesDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *CompressionCollector) Collect(ch chan<- prometheus.Metric) {
+	compressed, uncompressed, err := c.sizes()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(compressedBytesDesc, err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(compressedBytesDesc, prometheus.GaugeValue, float64(compressed))
+	ch <- prometheus.MustNewConstMetric(uncompressedBytesDesc, prometheus.GaugeValue, float64(uncompressed))
+	ch <- prometheus.MustNewConstMetric(savedBytesDesc, prometheus.GaugeValue, float64(uncompressed)-float64(compressed))
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressionCollector(t *testing.T) {
+	c := NewCompressionCollector(func() (uint64, uint64, error) {
+		return 300, 1000, nil
+	})
+
+	err := testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP kv_compression_compressed_bytes Size of the compressed values stored.
+# TYPE kv_compression_compressed_bytes gauge
+kv_compression_compressed_bytes 300
+# HELP kv_compression_saved_bytes Number of bytes saved by compressing the values stored.
+# TYPE kv_compression_saved_bytes gauge
+kv_compression_saved_bytes 700
+# HELP kv_compression_uncompressed_bytes Size of the compressed values stored before compression.
+# TYPE kv_compression_uncompressed_bytes gauge
+kv_compression_uncompressed_bytes 1000
+`))
+	require.NoError(t, err)
+
+	failing := NewCompressionCollector(func() (uint64, uint64, error) {
+		return 0, 0, errors.New("boom")
+	})
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(failing))
+	_, err = registry.Gather()
+	assert.ErrorContains(t, err, "boom")
+}
@@ -0,0 +1,326 @@
+// Package migrate provisions the Tarantool schema of the KV storage with
+// numbered migrations embedded in the binary. A migration is a Lua chunk run
+// by eval with the table of the configured space names as its argument, it
+// is recorded in the _kv_migrations space once applied. Migrations must be
+// idempotent, as a migration failing halfway is run again in whole. The
+// spaces of the optional features are provisioned on every start instead,
+// so a feature can be enabled at any time.
+package migrate
+
+import (
+	"cmp"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/tarantool/go-iproto"
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+// Space is the name of the space recording the applied migrations: version,
+// name and the time it was applied at.
+const Space = "_kv_migrations"
+
+// ErrSchemaTooNew is returned when the schema was migrated by a newer binary.
+var ErrSchemaTooNew = errors.New("schema is newer than the binary supports")
+
+// ErrPending is returned when the schema has migrations to apply.
+var ErrPending = errors.New("schema has pending migrations")
+
+//go:embed migrations/*.lua
+var embedded embed.FS
+
+// provisionScript creates the spaces of the enabled optional features, run
+// by eval with the table of the configured space names as its argument.
+//
+//go:embed provision.lua
+var provisionScript string
+
+// Migration is a numbered schema change.
+type Migration struct {
+	Version uint64
+	Name    string
+	Script  string
+}
+
+// Spaces are the names of the spaces, indexes and sequences passed to the
+// migrations, empty names are passed as nil for the disabled features.
+type Spaces struct {
+	KV             string
+	KVIndex        string
+	KVDeletedIndex string
+	Events         string
+	History        string
+	Chunks         string
+	Keys           string
+	Locks          string
+	Structures     string
+	Expiry         string
+	Versions       string
+	Schemas        string
+	Sizes          string
+}
+
+func (s Spaces) args() map[string]string {
+	args := map[string]string{}
+	for name, value := range map[string]string{
+		"kv":               s.KV,
+		"kv_index":         s.KVIndex,
+		"kv_deleted_index": s.KVDeletedIndex,
+		"events":           s.Events,
+		"history":          s.History,
+		"chunks":           s.Chunks,
+		"keys":             s.Keys,
+		"locks":            s.Locks,
+		"structures":       s.Structures,
+		"expiry":           s.Expiry,
+		"versions":         s.Versions,
+		"schemas":          s.Schemas,
+		"sizes":            s.Sizes,
+	} {
+		if value != "" {
+			args[name] = value
+		}
+	}
+	return args
+}
+
+// Load returns the embedded migrations in the order of their versions.
+func Load() ([]Migration, error) {
+	return load(embedded, "migrations")
+}
+
+// load reads the migrations named <version>_<name>.lua from the directory.
+// The versions must go up by one from 1.
+func load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	for _, e := range entries {
+		base, ok := strings.CutSuffix(e.Name(), ".lua")
+		if !ok || e.IsDir() {
+			continue
+		}
+		rawVersion, name, ok := strings.Cut(base, "_")
+		version, err := strconv.ParseUint(rawVersion, 10, 64)
+		if !ok || err != nil || name == "" {
+			return nil, fmt.Errorf("migration %s: name must be <version>_<name>.lua", e.Name())
+		}
+
+		script, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, Script: string(script)})
+	}
+
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	for i, m := range migrations {
+		if m.Version != uint64(i+1) {
+			return nil, fmt.Errorf("migration %d_%s: versions must go up by one from 1", m.Version, m.Name)
+		}
+	}
+	return migrations, nil
+}
+
+// pending returns the migrations following the applied version or
+// ErrSchemaTooNew if the version is unknown to the migrations.
+func pending(migrations []Migration, applied uint64) ([]Migration, error) {
+	if applied > uint64(len(migrations)) {
+		return nil, fmt.Errorf("%w: schema version %d, latest known %d", ErrSchemaTooNew, applied, len(migrations))
+	}
+	return migrations[applied:], nil
+}
+
+// Migrator applies the migrations to Tarantool.
+type Migrator struct {
+	conn       tarantool.Doer
+	spaces     Spaces
+	migrations []Migration
+}
+
+// New creates a new migrator of the embedded migrations.
+func New(conn tarantool.Doer, spaces Spaces) (*Migrator, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{conn: conn, spaces: spaces, migrations: migrations}, nil
+}
+
+// Latest returns the version of the latest migration.
+func (m *Migrator) Latest() uint64 {
+	return uint64(len(m.migrations))
+}
+
+// Version returns the version of the last applied migration, zero if none
+// was applied.
+func (m *Migrator) Version() (uint64, error) {
+	req := tarantool.NewEvalRequest(`
+		local space = box.space[...]
+		if space == nil then
+			return 0
+		end
+		local last = space.index.primary:max()
+		if last == nil then
+			return 0
+		end
+		return last[1]
+	`).Args([]any{Space})
+
+	var version []uint64
+	if err := m.conn.Do(req).GetTyped(&version); err != nil {
+		return 0, fmt.Errorf("read schema version: %w", err)
+	}
+	if len(version) == 0 {
+		return 0, nil
+	}
+	return version[0], nil
+}
+
+// Check returns ErrSchemaTooNew if the schema was migrated by a newer binary
+// and ErrPending if it has migrations to apply.
+func (m *Migrator) Check() error {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	todo, err := pending(m.migrations, version)
+	if err != nil {
+		return err
+	}
+	if len(todo) > 0 {
+		return fmt.Errorf("%w: schema version %d, latest %d", ErrPending, version, m.Latest())
+	}
+	return nil
+}
+
+// Up applies the pending migrations in order and returns the applied ones,
+// then provisions the spaces of the enabled optional features. It fails
+// with ErrSchemaTooNew without applying any if the schema was migrated by a
+// newer binary. The migrations applied meanwhile by another instance are
+// skipped.
+func (m *Migrator) Up() ([]Migration, error) {
+	if err := m.ensureSpace(); err != nil {
+		return nil, err
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+	todo, err := pending(m.migrations, version)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, migration := range todo {
+		ok, err := m.apply(migration)
+		if err != nil {
+			return applied, fmt.Errorf("apply migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		if ok {
+			applied = append(applied, migration)
+		}
+	}
+
+	if err := m.provision(); err != nil {
+		return applied, err
+	}
+	if err := m.setTriggers(); err != nil {
+		return applied, err
+	}
+	return applied, nil
+}
+
+func (m *Migrator) ensureSpace() error {
+	req := tarantool.NewEvalRequest(`
+		local space = box.schema.space.create(..., {
+			if_not_exists = true,
+			format = {
+				{ name = "version", type = "unsigned" },
+				{ name = "name", type = "string" },
+				{ name = "applied_at", type = "number" },
+			},
+		})
+		space:create_index("primary", { parts = { 1, "unsigned" }, if_not_exists = true })
+	`).Args([]any{Space})
+	if _, err := m.conn.Do(req).Get(); err != nil {
+		return fmt.Errorf("create %s space: %w", Space, err)
+	}
+	return nil
+}
+
+// apply runs the migration and records it unless it is already recorded,
+// and reports whether it was run. The instances applying the migration
+// concurrently may all run it, as its script yields, but only one records
+// it: for the others it is applied already.
+func (m *Migrator) apply(migration Migration) (bool, error) {
+	req := tarantool.NewEvalRequest(`
+		local space, version, name, script, spaces = ...
+		if box.space[space]:get(version) ~= nil then
+			return false
+		end
+		local migration = assert(load(script, "=" .. name))
+		migration(spaces)
+		box.space[space]:insert { version, name, require("clock").time() }
+		return true
+	`).Args([]any{Space, migration.Version, migration.Name, migration.Script, m.spaces.args()})
+
+	var ok []bool
+	err := m.conn.Do(req).GetTyped(&ok)
+	if recordedMeanwhile(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return len(ok) > 0 && ok[0], nil
+}
+
+// recordedMeanwhile reports whether the migration failed to be recorded as
+// another instance has recorded it meanwhile.
+func recordedMeanwhile(err error) bool {
+	var tntErr tarantool.Error
+	return errors.As(err, &tntErr) && tntErr.Code == iproto.ER_TUPLE_FOUND
+}
+
+// provision creates the missing spaces of the enabled optional features,
+// see provisionScript.
+func (m *Migrator) provision() error {
+	req := tarantool.NewEvalRequest(`
+		local script, spaces = ...
+		assert(load(script, "=provision"))(spaces)
+	`).Args([]any{provisionScript, m.spaces.args()})
+	if _, err := m.conn.Do(req).Get(); err != nil {
+		return fmt.Errorf("provision spaces: %w", err)
+	}
+	return nil
+}
+
+// setTriggers sets the triggers of the KV space defined by init.lua on the
+// configured spaces. init.lua keeps the names of the spaces to set the
+// triggers again on start.
+func (m *Migrator) setTriggers() error {
+	req := tarantool.NewEvalRequest(`
+		if kv_set_triggers ~= nil then
+			kv_set_triggers(...)
+		end
+	`).Args([]any{m.spaces.args()})
+	if _, err := m.conn.Do(req).Get(); err != nil {
+		return fmt.Errorf("set triggers: %w", err)
+	}
+	return nil
+}
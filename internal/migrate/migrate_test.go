@@ -0,0 +1,135 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tarantool/go-iproto"
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	assert.Equal(t, "initial_schema", migrations[0].Name)
+}
+
+func TestLoad_files(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "sorted by version",
+			files: []string{"0002_b.lua", "0010_j.lua", "0001_a.lua", "0003_c.lua", "0004_d.lua", "0005_e.lua", "0006_f.lua", "0007_g.lua", "0008_h.lua", "0009_i.lua", "README.md"},
+			want:  []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"},
+		},
+		{
+			name:    "gap",
+			files:   []string{"0001_a.lua", "0003_c.lua"},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate version",
+			files:   []string{"0001_a.lua", "0001_b.lua"},
+			wantErr: true,
+		},
+		{
+			name:    "no name",
+			files:   []string{"0001.lua"},
+			wantErr: true,
+		},
+		{
+			name:    "no version",
+			files:   []string{"initial_schema.lua"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			for _, name := range tt.files {
+				fsys["migrations/"+name] = &fstest.MapFile{Data: []byte("-- " + name)}
+			}
+
+			migrations, err := load(fsys, "migrations")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var names []string
+			for i, m := range migrations {
+				assert.Equal(t, uint64(i+1), m.Version)
+				names = append(names, m.Name)
+			}
+			assert.Equal(t, tt.want, names)
+			assert.Equal(t, "-- 0001_a.lua", migrations[0].Script)
+		})
+	}
+}
+
+func TestPending(t *testing.T) {
+	migrations := []Migration{{Version: 1, Name: "a"}, {Version: 2, Name: "b"}}
+
+	todo, err := pending(migrations, 0)
+	require.NoError(t, err)
+	assert.Equal(t, migrations, todo)
+
+	todo, err = pending(migrations, 1)
+	require.NoError(t, err)
+	assert.Equal(t, migrations[1:], todo)
+
+	todo, err = pending(migrations, 2)
+	require.NoError(t, err)
+	assert.Empty(t, todo)
+
+	_, err = pending(migrations, 3)
+	assert.ErrorIs(t, err, ErrSchemaTooNew)
+}
+
+func TestSpaces_args(t *testing.T) {
+	args := Spaces{KV: "kv", KVIndex: "primary", Events: "kv_events"}.args()
+	assert.Equal(t, map[string]string{"kv": "kv", "kv_index": "primary", "events": "kv_events"}, args)
+}
+
+// errDoer fails every request with err.
+type errDoer struct {
+	err error
+}
+
+func (d errDoer) Do(req tarantool.Request) *tarantool.Future {
+	fut := tarantool.NewFuture(req)
+	fut.SetError(d.err)
+	return fut
+}
+
+func TestMigrator_apply_concurrent(t *testing.T) {
+	migration := Migration{Version: 1, Name: "a", Script: "return"}
+
+	duplicate := tarantool.Error{Code: iproto.ER_TUPLE_FOUND, Msg: "Duplicate key exists"}
+	m := &Migrator{conn: errDoer{err: duplicate}}
+	ok, err := m.apply(migration)
+	require.NoError(t, err, "migration recorded by another instance is applied")
+	assert.False(t, ok)
+
+	failed := tarantool.Error{Code: iproto.ER_PROC_LUA, Msg: "syntax error"}
+	m = &Migrator{conn: errDoer{err: failed}}
+	_, err = m.apply(migration)
+	assert.ErrorIs(t, err, failed)
+}
+
+func TestMigrator_provision(t *testing.T) {
+	require.Contains(t, provisionScript, "if_not_exists = true")
+
+	failed := tarantool.Error{Code: iproto.ER_PROC_LUA, Msg: "syntax error"}
+	m := &Migrator{conn: errDoer{err: failed}}
+	assert.ErrorIs(t, m.provision(), failed)
+}
@@ -0,0 +1,33 @@
+-- The spaces and indexes of the KV storage. They were created by init.lua
+-- before the migrations, so every one of them may exist already. The names
+-- of the spaces are those of the configuration.
+local s = ...
+
+local function space(name)
+	return box.schema.space.create(name, { if_not_exists = true })
+end
+
+local kv = space(s.kv)
+kv:create_index(s.kv_index, {
+	type = "TREE",
+	parts = { 1, "string" },
+	unique = true,
+	if_not_exists = true
+})
+-- Deletion time of tombstones, used to purge them.
+kv:create_index(s.kv_deleted_index, {
+	type = "TREE",
+	parts = { { 3, "number", is_nullable = true, exclude_null = true } },
+	unique = false,
+	if_not_exists = true
+})
+
+space(s.events):create_index("primary", {
+	type = "TREE",
+	parts = { 1, "unsigned" },
+	sequence = true,
+	if_not_exists = true
+})
+
+-- The spaces of the optional features are provisioned on every start, see
+-- provision.lua.
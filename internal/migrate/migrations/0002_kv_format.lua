@@ -0,0 +1,13 @@
+-- The format of the KV space: the fields are named, the trailing ones are
+-- optional and not stored when empty. A value is a string of JSON or the
+-- binary data of a blob, encoded if compression or encryption is enabled.
+local s = ...
+
+box.space[s.kv]:format({
+	{ name = "key", type = "string" },
+	{ name = "value", type = "any", is_nullable = true },
+	{ name = "deleted_at", type = "number", is_nullable = true },
+	{ name = "doc", type = "any", is_nullable = true },
+	{ name = "content_type", type = "string", is_nullable = true },
+	{ name = "chunks", type = "unsigned", is_nullable = true },
+})
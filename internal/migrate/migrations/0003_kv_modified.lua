@@ -0,0 +1,13 @@
+-- The time the value of a key was last set and the version of the value, the
+-- hash of its content type and data, for the conditional requests. The
+-- values stored before have neither of them until they are set again.
+local s = ...
+
+local format = box.space[s.kv]:format()
+if #format < 7 then
+	table.insert(format, { name = "modified_at", type = "number", is_nullable = true })
+end
+if #format < 8 then
+	table.insert(format, { name = "version", type = "unsigned", is_nullable = true })
+end
+box.space[s.kv]:format(format)
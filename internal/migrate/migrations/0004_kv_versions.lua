@@ -0,0 +1,7 @@
+-- The sequence the versions of values are taken from, every write of a value
+-- takes the next one, so a version is never given to two values. The values
+-- stored before keep the versions derived from their content until they are
+-- set again.
+local s = ...
+
+box.schema.sequence.create(s.versions, { if_not_exists = true })
@@ -0,0 +1,3 @@
+-- The space of the JSON Schemas of values by key prefix, registered through
+-- the admin API, is provisioned on every start along with the other optional
+-- spaces, see provision.lua.
@@ -0,0 +1,25 @@
+-- The size of compressed values before compression, stored next to them in
+-- the KV and chunk spaces, and the space of the totals of the compressed
+-- values, kept by the triggers of the spaces. The values compressed before
+-- have no size and are not counted until they are set again.
+local s = ...
+
+local format = box.space[s.kv]:format()
+if #format < 9 then
+	table.insert(format, { name = "size", type = "unsigned", is_nullable = true })
+end
+box.space[s.kv]:format(format)
+
+local sizes = box.schema.space.create(s.sizes, {
+	format = {
+		{ name = "name", type = "string" },
+		{ name = "bytes", type = "unsigned" },
+	},
+	if_not_exists = true
+})
+sizes:create_index("primary", {
+	type = "TREE",
+	parts = { 1, "string" },
+	unique = true,
+	if_not_exists = true
+})
@@ -0,0 +1,87 @@
+-- The spaces of the optional features, run on every start after the
+-- migrations, as a feature may be enabled after the migration that would
+-- have created its space was applied. The names of the spaces are those of
+-- the configuration, the disabled ones are nil. Like the migrations, it must
+-- be idempotent.
+local s = ...
+
+local function space(name)
+	return box.schema.space.create(name, { if_not_exists = true })
+end
+
+if s.history ~= nil then
+	space(s.history):create_index("primary", {
+		type = "TREE",
+		parts = { { 1, "string" }, { 2, "unsigned" } },
+		unique = true,
+		if_not_exists = true
+	})
+end
+
+if s.chunks ~= nil then
+	space(s.chunks):create_index("primary", {
+		type = "TREE",
+		parts = { { 1, "string" }, { 2, "unsigned" } },
+		unique = true,
+		if_not_exists = true
+	})
+end
+
+if s.keys ~= nil then
+	space(s.keys):create_index("primary", {
+		type = "TREE",
+		parts = { 1, "unsigned" },
+		sequence = true,
+		if_not_exists = true
+	})
+end
+
+if s.locks ~= nil then
+	space(s.locks):create_index("primary", {
+		type = "TREE",
+		parts = { 1, "string" },
+		unique = true,
+		if_not_exists = true
+	})
+end
+
+if s.expiry ~= nil then
+	local expiry = space(s.expiry)
+	expiry:create_index("primary", {
+		type = "TREE",
+		parts = { 1, "string" },
+		unique = true,
+		if_not_exists = true
+	})
+	expiry:create_index("expires_at", {
+		type = "TREE",
+		parts = { 2, "number" },
+		unique = false,
+		if_not_exists = true
+	})
+end
+
+if s.structures ~= nil then
+	space(s.structures):create_index("primary", {
+		type = "TREE",
+		parts = { 1, "string" },
+		unique = true,
+		if_not_exists = true
+	})
+end
+
+if s.schemas ~= nil then
+	local schemas = box.schema.space.create(s.schemas, {
+		format = {
+			{ name = "prefix", type = "string" },
+			{ name = "schema", type = "string" },
+		},
+		if_not_exists = true
+	})
+	schemas:create_index("primary", {
+		type = "TREE",
+		parts = { 1, "string" },
+		unique = true,
+		if_not_exists = true
+	})
+end
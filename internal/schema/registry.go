@@ -0,0 +1,221 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+// ErrInvalidSchema is returned when registering a malformed JSON Schema.
+var ErrInvalidSchema = errors.New("invalid schema")
+
+// Violation is a mismatch between a value and its schema.
+type Violation struct {
+	// Path is the JSON pointer to the mismatching part of the value.
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when a value does not match the schema bound
+// to its key.
+type ValidationError struct {
+	Prefix     string
+	Violations []Violation
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("value does not match schema for prefix %q", e.Prefix)
+}
+
+// Schema is a JSON Schema bound to a key prefix.
+type Schema struct {
+	Prefix string          `json:"prefix"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+type entry struct {
+	raw      json.RawMessage
+	compiled *jsonschema.Schema
+}
+
+// Store is the contract for the persistent storage of the schemas.
+type Store interface {
+	// Save stores the schema of the key prefix, replacing the previous one.
+	Save(prefix string, schema []byte) error
+	// Delete removes the schema of the key prefix and reports whether there
+	// was one.
+	Delete(prefix string) (bool, error)
+	// Load returns the stored schemas by their key prefixes.
+	Load() (map[string][]byte, error)
+}
+
+// Registry holds JSON Schemas bound to key prefixes. A value is validated
+// against the schema with the longest prefix of its key.
+type Registry struct {
+	mu      sync.RWMutex
+	schemas map[string]entry
+	store   Store
+}
+
+// NewRegistry creates a new empty schema registry kept in memory only.
+func NewRegistry() *Registry {
+	return &Registry{
+		schemas: make(map[string]entry),
+	}
+}
+
+// NewStoredRegistry creates a new schema registry with the schemas of the
+// store. The schemas registered and removed later are saved to the store.
+func NewStoredRegistry(store Store) (*Registry, error) {
+	stored, err := store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("load schemas: %w", err)
+	}
+
+	r := NewRegistry()
+	for prefix, schema := range stored {
+		e, err := compile(schema)
+		if err != nil {
+			return nil, fmt.Errorf("stored schema for prefix %q: %w", prefix, err)
+		}
+		r.schemas[prefix] = e
+	}
+	r.store = store
+	return r, nil
+}
+
+// Register binds the JSON Schema to the key prefix, replacing the previous
+// one.
+func (r *Registry) Register(prefix string, schema []byte) error {
+	e, err := compile(schema)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.store != nil {
+		if err := r.store.Save(prefix, e.raw); err != nil {
+			return fmt.Errorf("save schema: %w", err)
+		}
+	}
+	r.schemas[prefix] = e
+	return nil
+}
+
+// Remove unbinds the schema from the key prefix and reports whether there
+// was one.
+func (r *Registry) Remove(prefix string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.schemas[prefix]
+	if r.store != nil {
+		deleted, err := r.store.Delete(prefix)
+		if err != nil {
+			return false, fmt.Errorf("delete schema: %w", err)
+		}
+		ok = ok || deleted
+	}
+	delete(r.schemas, prefix)
+	return ok, nil
+}
+
+// List returns the registered schemas sorted by prefix.
+func (r *Registry) List() []Schema {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schemas := make([]Schema, 0, len(r.schemas))
+	for prefix, e := range r.schemas {
+		schemas = append(schemas, Schema{Prefix: prefix, Schema: e.raw})
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Prefix < schemas[j].Prefix })
+	return schemas
+}
+
+// Validate validates the value against the schema bound to the longest prefix
+// of the key. Values of keys without a schema are always valid, blobs of keys
+// with one never are.
+func (r *Registry) Validate(key string, value any) error {
+	prefix, compiled, ok := r.lookup(key)
+	if !ok {
+		return nil
+	}
+	if _, ok := value.(storage.Blob); ok {
+		return &ValidationError{
+			Prefix:     prefix,
+			Violations: []Violation{{Message: "value must be JSON, blobs cannot match a schema"}},
+		}
+	}
+
+	err := compiled.Validate(value)
+	if err == nil {
+		return nil
+	}
+
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return err
+	}
+
+	verr := &ValidationError{Prefix: prefix}
+	for _, unit := range validationErr.BasicOutput().Errors {
+		if unit.Error == nil {
+			continue
+		}
+		verr.Violations = append(verr.Violations, Violation{
+			Path:    unit.InstanceLocation,
+			Message: unit.Error.String(),
+		})
+	}
+	return verr
+}
+
+// compile compiles the JSON Schema.
+func compile(schema []byte) (entry, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+	if err != nil {
+		return entry{}, fmt.Errorf("%w: %s", ErrInvalidSchema, err.Error())
+	}
+
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource("schema.json", doc); err != nil {
+		return entry{}, fmt.Errorf("%w: %s", ErrInvalidSchema, err.Error())
+	}
+	compiled, err := c.Compile("schema.json")
+	if err != nil {
+		return entry{}, fmt.Errorf("%w: %s", ErrInvalidSchema, err.Error())
+	}
+
+	return entry{
+		raw:      json.RawMessage(bytes.Clone(schema)),
+		compiled: compiled,
+	}, nil
+}
+
+func (r *Registry) lookup(key string) (string, *jsonschema.Schema, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var (
+		best     string
+		compiled *jsonschema.Schema
+		found    bool
+	)
+	for prefix, e := range r.schemas {
+		if strings.HasPrefix(key, prefix) && (!found || len(prefix) > len(best)) {
+			best, compiled, found = prefix, e.compiled, true
+		}
+	}
+	return best, compiled, found
+}
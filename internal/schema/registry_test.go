@@ -0,0 +1,160 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+const userSchema = `{
+	"type": "object",
+	"required": ["name"],
+	"properties": {
+		"name": {"type": "string"},
+		"age": {"type": "integer", "minimum": 0}
+	}
+}`
+
+func TestRegistry_Validate(t *testing.T) {
+	r := NewRegistry()
+	require.NoError(t, r.Register("user:", []byte(userSchema)))
+	require.NoError(t, r.Register("user:admin:", []byte(`{"type": "object", "required": ["role"]}`)))
+
+	tests := []struct {
+		name       string
+		key        string
+		value      any
+		prefix     string
+		violations int
+	}{
+		{
+			name:  "valid value",
+			key:   "user:1",
+			value: map[string]any{"name": "John", "age": float64(20)},
+		},
+		{
+			name:  "key without schema",
+			key:   "order:1",
+			value: "anything",
+		},
+		{
+			name:       "invalid value",
+			key:        "user:1",
+			value:      map[string]any{"age": float64(-1)},
+			prefix:     "user:",
+			violations: 2,
+		},
+		{
+			name:  "blob of key without schema",
+			key:   "order:1",
+			value: storage.Blob{ContentType: "text/plain", Data: []byte("anything")},
+		},
+		{
+			name:       "blob of key with schema",
+			key:        "user:1",
+			value:      storage.Blob{ContentType: "application/octet-stream", Data: []byte{1}},
+			prefix:     "user:",
+			violations: 1,
+		},
+		{
+			name:       "longest prefix wins",
+			key:        "user:admin:1",
+			value:      map[string]any{"name": "John"},
+			prefix:     "user:admin:",
+			violations: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.Validate(tt.key, tt.value)
+			if tt.violations == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			var verr *ValidationError
+			require.True(t, errors.As(err, &verr))
+			assert.Equal(t, tt.prefix, verr.Prefix)
+			assert.Len(t, verr.Violations, tt.violations)
+		})
+	}
+}
+
+func TestRegistry_Register(t *testing.T) {
+	r := NewRegistry()
+
+	err := r.Register("user:", []byte(`{"type": 1}`))
+	assert.ErrorIs(t, err, ErrInvalidSchema)
+
+	require.NoError(t, r.Register("user:", []byte(userSchema)))
+	assert.Len(t, r.List(), 1)
+
+	removed, err := r.Remove("user:")
+	require.NoError(t, err)
+	assert.True(t, removed)
+	removed, err = r.Remove("user:")
+	require.NoError(t, err)
+	assert.False(t, removed)
+	assert.Empty(t, r.List())
+}
+
+// memoryStore is a Store keeping the schemas in a map.
+type memoryStore struct {
+	schemas map[string][]byte
+	err     error
+}
+
+func (s *memoryStore) Save(prefix string, schema []byte) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.schemas[prefix] = schema
+	return nil
+}
+
+func (s *memoryStore) Delete(prefix string) (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+	_, ok := s.schemas[prefix]
+	delete(s.schemas, prefix)
+	return ok, nil
+}
+
+func (s *memoryStore) Load() (map[string][]byte, error) {
+	return s.schemas, s.err
+}
+
+func TestNewStoredRegistry(t *testing.T) {
+	store := &memoryStore{schemas: map[string][]byte{"user:": []byte(userSchema)}}
+
+	r, err := NewStoredRegistry(store)
+	require.NoError(t, err)
+	assert.Error(t, r.Validate("user:1", map[string]any{}))
+
+	require.NoError(t, r.Register("order/", []byte(`{"type": "object"}`)))
+	assert.JSONEq(t, `{"type": "object"}`, string(store.schemas["order/"]))
+
+	removed, err := r.Remove("user:")
+	require.NoError(t, err)
+	assert.True(t, removed)
+	assert.NotContains(t, store.schemas, "user:")
+
+	reloaded, err := NewStoredRegistry(store)
+	require.NoError(t, err)
+	assert.Equal(t, r.List(), reloaded.List())
+
+	store.err = errors.New("boom")
+	assert.Error(t, r.Register("user:", []byte(userSchema)))
+	_, err = r.Remove("order/")
+	assert.Error(t, err)
+	assert.Len(t, r.List(), 1, "failed changes are not applied")
+
+	_, err = NewStoredRegistry(&memoryStore{schemas: map[string][]byte{"user:": []byte(`{"type": 1}`)}})
+	assert.ErrorIs(t, err, ErrInvalidSchema)
+}
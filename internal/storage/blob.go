@@ -0,0 +1,44 @@
+package storage
+
+import "encoding/json"
+
+// DefaultBlobContentType is the content type of blobs stored without one.
+const DefaultBlobContentType = "application/octet-stream"
+
+// Blob is a binary value stored verbatim along with its content type. Set,
+// Update and Get take and return blobs in place of JSON values.
+type Blob struct {
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
+}
+
+// marshalValue returns the stored form of the value: the JSON encoding and
+// an empty content type or the data and the content type of a blob.
+func marshalValue(value any) (data, contentType string, err error) {
+	if blob, ok := value.(Blob); ok {
+		contentType = blob.ContentType
+		if contentType == "" {
+			contentType = DefaultBlobContentType
+		}
+		return string(blob.Data), contentType, nil
+	}
+
+	jsonValue, err := json.Marshal(value)
+	if err != nil {
+		return "", "", err
+	}
+	return string(jsonValue), "", nil
+}
+
+// unmarshalValue is the reverse of marshalValue.
+func unmarshalValue(data, contentType string) (any, error) {
+	if contentType != "" {
+		return Blob{ContentType: contentType, Data: []byte(data)}, nil
+	}
+
+	var value any
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestMarshalValue(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       any
+		data        string
+		contentType string
+	}{
+		{
+			name:  "json value",
+			value: map[string]any{"a": float64(1)},
+			data:  `{"a":1}`,
+		},
+		{
+			name:        "blob",
+			value:       Blob{ContentType: "image/png", Data: []byte{0x01, 0x02}},
+			data:        "\x01\x02",
+			contentType: "image/png",
+		},
+		{
+			name:        "blob without content type",
+			value:       Blob{Data: []byte("raw")},
+			data:        "raw",
+			contentType: DefaultBlobContentType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, contentType, err := marshalValue(tt.value)
+			require.NoError(t, err)
+			assert.Equal(t, tt.data, data)
+			assert.Equal(t, tt.contentType, contentType)
+
+			value, err := unmarshalValue(data, contentType)
+			require.NoError(t, err)
+			if blob, ok := tt.value.(Blob); ok {
+				blob.ContentType = contentType
+				assert.Equal(t, blob, value)
+			} else {
+				assert.Equal(t, tt.value, value)
+			}
+		})
+	}
+}
+
+func TestTarantool_tuple(t *testing.T) {
+	s := &Tarantool{}
+
+	assert.Equal(t, []any{"k", `"v"`}, s.tuple(s.newTuple("k", `"v"`, "")))
+
+	tuple := s.tuple(s.newTuple("k", "\x01data", "application/octet-stream"))
+	assert.Equal(t, []any{"k", []byte("\x00\x01data"), nil, nil, "application/octet-stream"}, tuple)
+
+	decoded, err := s.decodeKVTuple([]any{"k", "\x00\x01data", nil, nil, "application/octet-stream"})
+	require.NoError(t, err)
+	assert.Equal(t, "\x01data", decoded.Value)
+	assert.True(t, decoded.blob())
+
+	codec, err := newCodec(CompressionZstd, 10, nil)
+	require.NoError(t, err)
+	s.codec = codec
+	large := `"` + strings.Repeat("a", 100) + `"`
+	tuple = s.tuple(s.newTuple("k", large, ""))
+	require.Len(t, tuple, 9)
+	assert.Equal(t, len(large), tuple[8], "compressed values keep their size")
+
+	decoded, err = s.decodeKVTuple([]any{"k", string(tuple[1].([]byte)), nil, nil, nil, nil, nil, nil, len(large)})
+	require.NoError(t, err)
+	assert.Equal(t, large, decoded.Value)
+}
+
+func TestRevisionTuple_DecodeMsgpack(t *testing.T) {
+	tests := []struct {
+		name  string
+		tuple []any
+		want  revisionTuple
+	}{
+		{
+			name:  "json value",
+			tuple: []any{"k", uint64(1), `"v"`, 1.5},
+			want:  revisionTuple{Key: "k", Revision: 1, Value: ptr(`"v"`), Time: 1.5},
+		},
+		{
+			name:  "deletion",
+			tuple: []any{"k", uint64(2), nil, 1.5},
+			want:  revisionTuple{Key: "k", Revision: 2, Time: 1.5},
+		},
+		{
+			name:  "blob",
+			tuple: []any{"k", uint64(3), []byte("\x00data"), 1.5, "text/plain"},
+			want:  revisionTuple{Key: "k", Revision: 3, Value: ptr("\x00data"), Time: 1.5, ContentType: "text/plain"},
+		},
+		{
+			name:  "truncated",
+			tuple: []any{"k", uint64(4), nil, 1.5, nil, true},
+			want:  revisionTuple{Key: "k", Revision: 4, Time: 1.5, Truncated: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := msgpack.Marshal(tt.tuple)
+			require.NoError(t, err)
+
+			var got revisionTuple
+			require.NoError(t, msgpack.Unmarshal(data, &got))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"io"
+	"strings"
+
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+// chunkTuple is a tuple of the chunk space: key, number of the chunk and its
+// marked, possibly compressed and encrypted, data.
+type chunkTuple struct {
+	_msgpack struct{} `msgpack:",asArray"`
+
+	Key  string
+	N    uint64
+	Data string
+}
+
+// splitChunks splits the value into chunks of the given size.
+func splitChunks(value string, size int) []string {
+	chunks := make([]string, 0, (len(value)+size-1)/size)
+	for len(value) > size {
+		chunks = append(chunks, value[:size])
+		value = value[size:]
+	}
+	return append(chunks, value)
+}
+
+// writeChunks stores the chunks of the value of the key, the compressed ones
+// along with their size before compression.
+func (s *Tarantool) writeChunks(doer tarantool.Doer, key string, chunks []string) error {
+	for i, chunk := range chunks {
+		data, compressed := s.codec.encodeBinary(chunk)
+		fields := []any{key, i, data}
+		if compressed {
+			fields = append(fields, len(chunk))
+		}
+		req := tarantool.NewReplaceRequest(s.chunkSpace).Tuple(fields)
+		if _, err := doer.Do(req).Get(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropChunks removes the chunks of the key numbered from from up to to.
+func (s *Tarantool) dropChunks(doer tarantool.Doer, key string, from, to int) error {
+	for i := from; i < to; i++ {
+		req := tarantool.NewDeleteRequest(s.chunkSpace).Key([]any{key, i})
+		if _, err := doer.Do(req).Get(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readChunk returns the data of the chunk of the key.
+func (s *Tarantool) readChunk(doer tarantool.Doer, key string, n int) (string, error) {
+	raw, err := s.rawChunk(doer, key, n)
+	if err != nil {
+		return "", err
+	}
+	return s.codec.decode(raw)
+}
+
+// rawChunk returns the chunk of the key as it is stored.
+func (s *Tarantool) rawChunk(doer tarantool.Doer, key string, n int) (string, error) {
+	req := tarantool.NewSelectRequest(s.chunkSpace).Key([]any{key, n})
+
+	var tuples []chunkTuple
+	if err := doer.Do(req).GetTyped(&tuples); err != nil {
+		return "", storageError(err)
+	}
+	if len(tuples) == 0 {
+		return "", ErrInvalidDataFormat
+	}
+
+	return tuples[0].Data, nil
+}
+
+// readValue returns the whole stored value of the tuple, reading its chunks
+// if it has any.
+func (s *Tarantool) readValue(doer tarantool.Doer, t kvTuple) (string, error) {
+	if !t.chunked() {
+		return t.Value, nil
+	}
+
+	var b strings.Builder
+	for i := range t.Chunks {
+		chunk, err := s.readChunk(doer, t.Key, i)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(chunk)
+	}
+	return b.String(), nil
+}
+
+// OpenValue opens the stored value of the key for reading along with its
+// metadata: the JSON encoding of a JSON value or the data of a blob. A value
+// stored in chunks is read one chunk at a time in a transaction, so it is
+// consistent even if it is overwritten meanwhile. The value must be closed
+// to end the transaction. An expired key is not found.
+func (s *Tarantool) OpenValue(key string) (io.ReadCloser, Metadata, error) {
+	t, err := s.getTuple(s.conn, key)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	if t.deleted() {
+		return nil, Metadata{}, ErrKeyDeleted
+	}
+	if err := s.checkExpired(s.conn, key); err != nil {
+		return nil, Metadata{}, err
+	}
+	if !t.chunked() {
+		return io.NopCloser(strings.NewReader(t.Value)), t.metadata(), nil
+	}
+	return s.openLargeValue(key)
+}
+
+// largeValue is a value stored in chunks, read one chunk at a time in the
+// transaction of the stream.
+type largeValue struct {
+	s      *Tarantool
+	stream *tarantool.Stream
+	key    string
+	chunks int
+	next   int
+	buf    string
+}
+
+// openLargeValue begins the transaction reading the value of the key. The
+// value is read from the tuple if it is no longer stored in chunks.
+func (s *Tarantool) openLargeValue(key string) (io.ReadCloser, Metadata, error) {
+	stream, err := s.conn.NewStream()
+	if err != nil {
+		return nil, Metadata{}, storageError(err)
+	}
+
+	if _, err := stream.Do(tarantool.NewBeginRequest()).Get(); err != nil {
+		return nil, Metadata{}, storageError(err)
+	}
+
+	t, err := s.getTuple(stream, key)
+	if err == nil && t.deleted() {
+		err = ErrKeyDeleted
+	}
+	if err != nil || !t.chunked() {
+		_, _ = stream.Do(tarantool.NewRollbackRequest()).Get()
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		return io.NopCloser(strings.NewReader(t.Value)), t.metadata(), nil
+	}
+
+	return &largeValue{
+		s:      s,
+		stream: stream,
+		key:    key,
+		chunks: t.Chunks,
+	}, t.metadata(), nil
+}
+
+// Read reads the value.
+func (v *largeValue) Read(p []byte) (int, error) {
+	for len(v.buf) == 0 {
+		if v.next == v.chunks {
+			return 0, io.EOF
+		}
+
+		chunk, err := v.s.readChunk(v.stream, v.key, v.next)
+		if err != nil {
+			return 0, err
+		}
+		v.buf = chunk
+		v.next++
+	}
+
+	n := copy(p, v.buf)
+	v.buf = v.buf[n:]
+	return n, nil
+}
+
+// Close ends the transaction reading the value.
+func (v *largeValue) Close() error {
+	_, err := v.stream.Do(tarantool.NewCommitRequest()).Get()
+	return storageError(err)
+}
+
+// decodeInt returns the integer decoded from MessagePack into an interface.
+func decodeInt(v any) (int, bool) {
+	switch v := v.(type) {
+	case int8:
+		return int(v), true
+	case int16:
+		return int(v), true
+	case int32:
+		return int(v), true
+	case int64:
+		return int(v), true
+	case uint8:
+		return int(v), true
+	case uint16:
+		return int(v), true
+	case uint32:
+		return int(v), true
+	case uint64:
+		return int(v), true
+	case int:
+		return v, true
+	case uint:
+		return int(v), true
+	default:
+		return 0, false
+	}
+}
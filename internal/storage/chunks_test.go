@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitChunks(t *testing.T) {
+	assert.Equal(t, []string{"abc", "def", "g"}, splitChunks("abcdefg", 3))
+	assert.Equal(t, []string{"abc", "def"}, splitChunks("abcdef", 3))
+	assert.Equal(t, []string{"ab"}, splitChunks("ab", 3))
+}
+
+func TestChunkedTuple(t *testing.T) {
+	s := &Tarantool{}
+
+	tuple := s.tuple(kvTuple{Key: "k", Value: `"large"`, Chunks: 3})
+	assert.Equal(t, []any{"k", nil, nil, nil, nil, 3}, tuple)
+
+	tombstone := s.tuple(kvTuple{Key: "k", DeletedAt: 1.5, ContentType: "image/png", Chunks: 3})
+	assert.Equal(t, []any{"k", nil, 1.5, nil, "image/png", 3}, tombstone)
+
+	decoded, err := s.decodeKVTuple([]any{"k", nil, 1.5, nil, "image/png", uint8(3)})
+	require.NoError(t, err)
+	assert.Equal(t, kvTuple{Key: "k", DeletedAt: 1.5, ContentType: "image/png", Chunks: 3}, decoded)
+
+	_, err = s.decodeKVTuple([]any{"k", nil})
+	assert.ErrorIs(t, err, ErrInvalidDataFormat)
+}
+
+func TestTarantool_newTuple_chunked(t *testing.T) {
+	s := &Tarantool{chunkSize: 16, jsonIndexes: []JSONIndex{{Name: "country", Path: "country", Type: JSONIndexString}}}
+
+	chunked := s.newTuple("k", `{"country":"NL","bio":"long text"}`, "")
+	assert.Equal(t, map[string]any{"country": "NL"}, chunked.Doc, "the chunked value must not be stored in full")
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression algorithms.
+const (
+	CompressionNone = ""
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// Markers of stored binary data. A compressed value is stored as binary data
+// starting with the marker, while plain JSON values are stored as strings,
+// which never start with these bytes. Blobs may start with any byte, so they
+// are always stored with a marker, the raw one if they are not compressed.
+const (
+	markerRaw  byte = 0x00
+	markerGzip byte = 0x01
+	markerZstd byte = 0x02
+)
+
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// codec compresses the values above the threshold and, if there is a
+// keyring, encrypts all of them.
+type codec struct {
+	algorithm string
+	threshold int
+	keys      *Keyring
+}
+
+func newCodec(algorithm string, threshold int, keys *Keyring) (codec, error) {
+	switch algorithm {
+	case CompressionNone, CompressionGzip, CompressionZstd:
+		return codec{algorithm: algorithm, threshold: threshold, keys: keys}, nil
+	default:
+		return codec{}, fmt.Errorf("unsupported compression %q", algorithm)
+	}
+}
+
+// encode returns the value to store in a tuple: the JSON string itself or
+// the compressed binary data if compressing pays off, encrypted if the
+// encryption is enabled. It reports whether the value is compressed.
+func (c codec) encode(value string) (any, bool) {
+	compressed := c.compress(value)
+	switch {
+	case c.keys != nil && compressed != nil:
+		return c.keys.encrypt(compressed), true
+	case c.keys != nil:
+		return c.keys.encrypt([]byte(value)), false
+	case compressed != nil:
+		return compressed, true
+	default:
+		return value, false
+	}
+}
+
+// encodeBinary returns the blob data to store in a tuple, compressed if
+// compressing pays off and encrypted if the encryption is enabled. It
+// reports whether the data is compressed.
+func (c codec) encodeBinary(data string) ([]byte, bool) {
+	encoded := c.compress(data)
+	compressed := encoded != nil
+	if !compressed {
+		encoded = append([]byte{markerRaw}, data...)
+	}
+
+	if c.keys != nil {
+		return c.keys.encrypt(encoded), compressed
+	}
+	return encoded, compressed
+}
+
+// decode returns the stored value without its markers, decrypting and
+// decompressing it if need be.
+func (c codec) decode(raw string) (string, error) {
+	return decodeValue(raw, c.keys)
+}
+
+// compress returns the marked compressed value or nil if the value is below
+// the threshold or does not get smaller.
+func (c codec) compress(value string) []byte {
+	if c.algorithm == CompressionNone || len(value) < c.threshold {
+		return nil
+	}
+
+	var compressed []byte
+	switch c.algorithm {
+	case CompressionGzip:
+		var buf bytes.Buffer
+		buf.WriteByte(markerGzip)
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write([]byte(value)); err != nil {
+			return nil
+		}
+		if err := zw.Close(); err != nil {
+			return nil
+		}
+		compressed = buf.Bytes()
+	case CompressionZstd:
+		compressed = zstdEncoder.EncodeAll([]byte(value), []byte{markerZstd})
+	}
+
+	if len(compressed) >= len(value) {
+		return nil
+	}
+	return compressed
+}
+
+// decodeValue returns the stored value without its marker, decrypting it
+// with the keys if it is encrypted and decompressing it if it has a
+// compression marker.
+func decodeValue(raw string, keys *Keyring) (string, error) {
+	if len(raw) == 0 {
+		return raw, nil
+	}
+
+	switch raw[0] {
+	case markerEncrypted:
+		if keys == nil {
+			return "", ErrEncryptionDisabled
+		}
+		value, err := keys.decrypt(raw)
+		if err != nil {
+			return "", err
+		}
+		return decodeValue(value, nil)
+	case markerRaw:
+		return raw[1:], nil
+	case markerGzip:
+		zr, err := gzip.NewReader(bytes.NewReader([]byte(raw[1:])))
+		if err != nil {
+			return "", fmt.Errorf("decompress value: %w", err)
+		}
+		defer zr.Close()
+		value, err := io.ReadAll(zr)
+		if err != nil {
+			return "", fmt.Errorf("decompress value: %w", err)
+		}
+		return string(value), nil
+	case markerZstd:
+		value, err := zstdDecoder.DecodeAll([]byte(raw[1:]), nil)
+		if err != nil {
+			return "", fmt.Errorf("decompress value: %w", err)
+		}
+		return string(value), nil
+	default:
+		return raw, nil
+	}
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodec(t *testing.T) {
+	large := `{"data":"` + strings.Repeat("abc", 1000) + `"}`
+	small := `{"a":1}`
+
+	for _, algorithm := range []string{CompressionGzip, CompressionZstd} {
+		t.Run(algorithm, func(t *testing.T) {
+			c, err := newCodec(algorithm, 100, nil)
+			require.NoError(t, err)
+
+			stored, compressed := c.encode(small)
+			assert.Equal(t, small, stored, "small values are stored as is")
+			assert.False(t, compressed)
+
+			stored, compressed = c.encode(large)
+			encoded, ok := stored.([]byte)
+			require.True(t, ok, "large values are compressed")
+			assert.True(t, compressed)
+			assert.Less(t, len(encoded), len(large))
+
+			decoded, err := decodeValue(string(encoded), nil)
+			require.NoError(t, err)
+			assert.Equal(t, large, decoded)
+		})
+	}
+
+	t.Run("binary value", func(t *testing.T) {
+		c, err := newCodec(CompressionNone, 0, nil)
+		require.NoError(t, err)
+
+		data := "\x01not compressed"
+		encoded, compressed := c.encodeBinary(data)
+		assert.Equal(t, markerRaw, encoded[0])
+		assert.False(t, compressed)
+
+		decoded, err := decodeValue(string(encoded), nil)
+		require.NoError(t, err)
+		assert.Equal(t, data, decoded)
+	})
+
+	t.Run("legacy value", func(t *testing.T) {
+		decoded, err := decodeValue(large, nil)
+		require.NoError(t, err)
+		assert.Equal(t, large, decoded)
+	})
+
+	t.Run("unsupported algorithm", func(t *testing.T) {
+		_, err := newCodec("lz4", 100, nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestTarantool_tuple_compressedDocument(t *testing.T) {
+	c, err := newCodec(CompressionGzip, 10, nil)
+	require.NoError(t, err)
+	s := &Tarantool{codec: c, jsonIndexes: []JSONIndex{{Name: "country", Path: "country", Type: JSONIndexString}}}
+
+	value := `{"country":"NL","bio":"` + strings.Repeat("abc", 100) + `"}`
+	fields := s.tuple(s.newTuple("k", value, ""))
+	_, compressed := fields[1].([]byte)
+	assert.True(t, compressed)
+	assert.Equal(t, map[string]any{"country": "NL"}, fields[3], "the document must not hold the value uncompressed")
+}
@@ -0,0 +1,411 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+var (
+	// ErrInvalidMasterKey is returned when the master key is not a 256-bit
+	// key.
+	ErrInvalidMasterKey = errors.New("master key must be 32 bytes long")
+	// ErrUnknownDataKey is returned when a value is encrypted with a data key
+	// that is not in the keys space.
+	ErrUnknownDataKey = errors.New("unknown data key")
+	// ErrEncryptionDisabled is returned when an encrypted value is read while
+	// the encryption is disabled.
+	ErrEncryptionDisabled = errors.New("value is encrypted but encryption is disabled")
+)
+
+// markerEncrypted marks encrypted values. An encrypted value is the marker,
+// the big-endian ID of the data key, the nonce and the sealed value as it
+// would be stored without encryption, compressed and marked if need be.
+const markerEncrypted byte = 0x04
+
+// reencryptBatchSize is the maximum number of tuples read at once while
+// re-encrypting.
+const reencryptBatchSize = 512
+
+const (
+	keyIDSize    = 4
+	dataKeySize  = 32
+	headerSize   = 1 + keyIDSize
+	masterKeyLen = 32
+)
+
+// keyTuple is a tuple of the keys space: ID, data key sealed with the master
+// key and creation time.
+type keyTuple struct {
+	_msgpack struct{} `msgpack:",asArray"`
+
+	ID      uint32
+	Wrapped string
+	Created float64
+}
+
+// Keyring encrypts values with AES-GCM data keys. The data keys are stored
+// in a Tarantool space wrapped by the master key, which is never stored.
+// New values are encrypted with the latest data key, the older ones are kept
+// to decrypt the values written before the rotation.
+type Keyring struct {
+	conn   *tarantool.Connection
+	space  string
+	master cipher.AEAD
+
+	mu     sync.RWMutex
+	keys   map[uint32]cipher.AEAD
+	active uint32
+}
+
+// NewKeyring loads the data keys from the keys space, creating the first one
+// if there are none.
+func NewKeyring(conn *tarantool.Connection, space string, masterKey []byte) (*Keyring, error) {
+	if len(masterKey) != masterKeyLen {
+		return nil, ErrInvalidMasterKey
+	}
+
+	master, err := newAEAD(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	k := &Keyring{
+		conn:   conn,
+		space:  space,
+		master: master,
+		keys:   make(map[uint32]cipher.AEAD),
+	}
+	if err := k.Reload(); err != nil {
+		return nil, err
+	}
+
+	if k.ActiveKeyID() == 0 {
+		if _, err := k.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return k, nil
+}
+
+// Reload loads the data keys added since the last load, the latest one
+// becomes the active key.
+func (k *Keyring) Reload() error {
+	req := tarantool.NewSelectRequest(k.space).Iterator(tarantool.IterAll)
+
+	var tuples []keyTuple
+	if err := k.conn.Do(req).GetTyped(&tuples); err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for _, t := range tuples {
+		if _, ok := k.keys[t.ID]; ok {
+			continue
+		}
+
+		aead, err := k.unwrap(t)
+		if err != nil {
+			return err
+		}
+		k.keys[t.ID] = aead
+		k.active = max(k.active, t.ID)
+	}
+
+	return nil
+}
+
+// Rotate creates a new data key and makes it the active one. It returns the
+// ID of the new key.
+func (k *Keyring) Rotate() (uint32, error) {
+	dataKey := make([]byte, dataKeySize)
+	_, _ = rand.Read(dataKey)
+	wrapped := k.WrapKey(dataKey)
+
+	req := tarantool.NewInsertRequest(k.space).Tuple([]any{nil, wrapped, timeToUnixFloat(time.Now())})
+	var tuples []keyTuple
+	if err := k.conn.Do(req).GetTyped(&tuples); err != nil {
+		return 0, err
+	}
+	if len(tuples) == 0 {
+		return 0, ErrInvalidDataFormat
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return 0, err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	id := tuples[0].ID
+	k.keys[id] = aead
+	k.active = max(k.active, id)
+	return id, nil
+}
+
+// ActiveKeyID returns the ID of the data key encrypting new values.
+func (k *Keyring) ActiveKeyID() uint32 {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.active
+}
+
+// encrypt seals the data with the active data key.
+func (k *Keyring) encrypt(data []byte) []byte {
+	k.mu.RLock()
+	id, aead := k.active, k.keys[k.active]
+	k.mu.RUnlock()
+
+	out := make([]byte, headerSize, headerSize+aead.NonceSize()+len(data)+aead.Overhead())
+	out[0] = markerEncrypted
+	binary.BigEndian.PutUint32(out[1:headerSize], id)
+
+	nonce := make([]byte, aead.NonceSize())
+	_, _ = rand.Read(nonce)
+	out = append(out, nonce...)
+
+	return aead.Seal(out, nonce, data, out[:headerSize])
+}
+
+// decrypt opens the encrypted value, loading the data key if it was created
+// by another instance.
+func (k *Keyring) decrypt(raw string) (string, error) {
+	id := encryptionKeyID(raw)
+	if id == 0 {
+		return "", ErrInvalidDataFormat
+	}
+
+	k.mu.RLock()
+	aead, ok := k.keys[id]
+	k.mu.RUnlock()
+
+	if !ok {
+		if err := k.Reload(); err != nil {
+			return "", err
+		}
+
+		k.mu.RLock()
+		aead, ok = k.keys[id]
+		k.mu.RUnlock()
+
+		if !ok {
+			return "", fmt.Errorf("%w %d", ErrUnknownDataKey, id)
+		}
+	}
+
+	sealed := []byte(raw[headerSize:])
+	if len(sealed) < aead.NonceSize() {
+		return "", ErrInvalidDataFormat
+	}
+	nonce, sealed := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+
+	data, err := aead.Open(nil, nonce, sealed, []byte(raw[:headerSize]))
+	if err != nil {
+		return "", fmt.Errorf("decrypt value: %w", err)
+	}
+	return string(data), nil
+}
+
+func (k *Keyring) unwrap(t keyTuple) (cipher.AEAD, error) {
+	dataKey, err := k.UnwrapKey([]byte(t.Wrapped))
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key %d: %w", t.ID, err)
+	}
+
+	return newAEAD(dataKey)
+}
+
+// WrapKey seals the data key with the master key, the data keys kept out of
+// the keys space, as the ones of backups, are stored wrapped.
+func (k *Keyring) WrapKey(key []byte) []byte {
+	nonce := make([]byte, k.master.NonceSize())
+	_, _ = rand.Read(nonce)
+	return k.master.Seal(nonce, nonce, key, nil)
+}
+
+// UnwrapKey opens the data key sealed by WrapKey.
+func (k *Keyring) UnwrapKey(wrapped []byte) ([]byte, error) {
+	if len(wrapped) < k.master.NonceSize() {
+		return nil, ErrInvalidDataFormat
+	}
+
+	nonce, sealed := wrapped[:k.master.NonceSize()], wrapped[k.master.NonceSize():]
+	return k.master.Open(nil, nonce, sealed, nil)
+}
+
+// encryptionKeyID returns the ID of the data key the stored value is
+// encrypted with or zero if it is not encrypted.
+func encryptionKeyID(raw string) uint32 {
+	if len(raw) < headerSize || raw[0] != markerEncrypted {
+		return 0
+	}
+	return binary.BigEndian.Uint32([]byte(raw[1:headerSize]))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Reencrypt encrypts the values and revisions that are not encrypted with the
+// active data key with it and returns the number of re-encrypted tuples. The
+// events are not re-encrypted, they are dropped as new ones come in. The
+// re-encrypted values keep their versions, so they are not recorded as
+// changes in the events.
+func (s *Tarantool) Reencrypt() (int, error) {
+	keys := s.codec.keys
+	if keys == nil {
+		return 0, nil
+	}
+
+	if err := keys.Reload(); err != nil {
+		return 0, err
+	}
+	active := keys.ActiveKeyID()
+
+	values, err := s.reencryptValues(active)
+	if err != nil {
+		return values, err
+	}
+
+	if s.historyDepth <= 0 {
+		return values, nil
+	}
+
+	revisions, err := s.reencryptRevisions(active)
+	return values + revisions, err
+}
+
+func (s *Tarantool) reencryptValues(active uint32) (int, error) {
+	reencrypted := 0
+	req := tarantool.NewSelectRequest(s.space).Index(s.index).Iterator(tarantool.IterAll).Limit(reencryptBatchSize)
+
+	for {
+		resp, err := s.conn.Do(req).Get()
+		if err != nil {
+			return reencrypted, err
+		}
+
+		for _, data := range resp {
+			t, err := s.decodeKVTuple(data)
+			if err != nil {
+				return reencrypted, err
+			}
+			req = req.Iterator(tarantool.IterGt).Key([]any{t.Key})
+
+			if stale, err := s.staleKeyID(s.conn, t, active); err != nil || !stale {
+				if err != nil {
+					return reencrypted, err
+				}
+				continue
+			}
+
+			// The value may be changed since it was selected.
+			err = s.inTx(func(doer tarantool.Doer) error {
+				current, err := s.getTuple(doer, t.Key)
+				if err != nil {
+					return err
+				}
+				if stale, err := s.staleKeyID(doer, current, active); err != nil || !stale {
+					return err
+				}
+
+				value, err := s.readValue(doer, current)
+				if err != nil {
+					return err
+				}
+
+				updated := s.newTuple(current.Key, value, current.ContentType)
+				updated.DeletedAt = current.DeletedAt
+				// The value is the same, only encrypted with another key, so
+				// it keeps its version, which keeps the write from being
+				// recorded as a change event.
+				updated.ModifiedAt = current.ModifiedAt
+				updated.Version = current.version(value)
+				return s.store(doer, &updated, current.Chunks, false)
+			})
+			switch {
+			case err == nil:
+				reencrypted++
+			case errors.Is(err, ErrKeyNotFound):
+			default:
+				return reencrypted, err
+			}
+		}
+
+		if len(resp) < reencryptBatchSize {
+			return reencrypted, nil
+		}
+	}
+}
+
+func (s *Tarantool) reencryptRevisions(active uint32) (int, error) {
+	reencrypted := 0
+	req := tarantool.NewSelectRequest(s.historySpace).Iterator(tarantool.IterAll).Limit(reencryptBatchSize)
+
+	for {
+		var tuples []revisionTuple
+		if err := s.conn.Do(req).GetTyped(&tuples); err != nil {
+			return reencrypted, err
+		}
+
+		for _, t := range tuples {
+			req = req.Iterator(tarantool.IterGt).Key([]any{t.Key, t.Revision})
+
+			if t.Value == nil || encryptionKeyID(*t.Value) == active {
+				continue
+			}
+
+			value, err := s.codec.decode(*t.Value)
+			if err != nil {
+				return reencrypted, err
+			}
+
+			// Updating rather than replacing the revision does not bring it
+			// back if it is trimmed meanwhile.
+			stored, _ := s.storedValue(value, t.ContentType)
+			update := tarantool.NewUpdateRequest(s.historySpace).
+				Key([]any{t.Key, t.Revision}).
+				Operations(tarantool.NewOperations().Assign(2, stored))
+			if _, err := s.conn.Do(update).Get(); err != nil {
+				return reencrypted, err
+			}
+			reencrypted++
+		}
+
+		if len(tuples) < reencryptBatchSize {
+			return reencrypted, nil
+		}
+	}
+}
+
+// staleKeyID reports whether the value of the tuple is not encrypted with
+// the active data key. The chunks of a value are always encrypted with the
+// same key, so only the first one is checked.
+func (s *Tarantool) staleKeyID(doer tarantool.Doer, t kvTuple, active uint32) (bool, error) {
+	if !t.chunked() {
+		return t.KeyID != active, nil
+	}
+
+	raw, err := s.rawChunk(doer, t.Key, 0)
+	if err != nil {
+		return false, err
+	}
+	return encryptionKeyID(raw) != active, nil
+}
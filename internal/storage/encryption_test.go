@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKeyring(t *testing.T, ids ...uint32) *Keyring {
+	t.Helper()
+
+	k := &Keyring{keys: make(map[uint32]cipher.AEAD)}
+	for _, id := range ids {
+		aead, err := newAEAD(bytes.Repeat([]byte{byte(id)}, dataKeySize))
+		require.NoError(t, err)
+		k.keys[id] = aead
+		k.active = max(k.active, id)
+	}
+	return k
+}
+
+// encoded returns the binary data encoded by the codec.
+func encoded[T any](value T, _ bool) []byte {
+	return any(value).([]byte)
+}
+
+func TestCodec_Encryption(t *testing.T) {
+	keys := newTestKeyring(t, 1)
+	large := `{"data":"` + strings.Repeat("abc", 1000) + `"}`
+
+	tests := []struct {
+		name   string
+		encode func(c codec) []byte
+		want   string
+	}{
+		{
+			name:   "json value",
+			encode: func(c codec) []byte { return encoded(c.encode(`{"a":1}`)) },
+			want:   `{"a":1}`,
+		},
+		{
+			name:   "compressed json value",
+			encode: func(c codec) []byte { return encoded(c.encode(large)) },
+			want:   large,
+		},
+		{
+			name:   "blob",
+			encode: func(c codec) []byte { return encoded(c.encodeBinary("\x04raw")) },
+			want:   "\x04raw",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := newCodec(CompressionZstd, 100, keys)
+			require.NoError(t, err)
+
+			encoded := tt.encode(c)
+			assert.Equal(t, uint32(1), encryptionKeyID(string(encoded)))
+			assert.NotContains(t, string(encoded), tt.want)
+
+			decoded, err := c.decode(string(encoded))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, decoded)
+		})
+	}
+
+	t.Run("plaintext value", func(t *testing.T) {
+		c, err := newCodec(CompressionNone, 0, keys)
+		require.NoError(t, err)
+
+		decoded, err := c.decode(`{"a":1}`)
+		require.NoError(t, err)
+		assert.Equal(t, `{"a":1}`, decoded)
+		assert.Zero(t, encryptionKeyID(`{"a":1}`))
+	})
+
+	t.Run("tampered value", func(t *testing.T) {
+		c, err := newCodec(CompressionNone, 0, keys)
+		require.NoError(t, err)
+
+		value := encoded(c.encode(`{"a":1}`))
+		value[len(value)-1] ^= 0xff
+
+		_, err = c.decode(string(value))
+		assert.Error(t, err)
+	})
+
+	t.Run("encryption disabled", func(t *testing.T) {
+		c, err := newCodec(CompressionNone, 0, keys)
+		require.NoError(t, err)
+
+		_, err = decodeValue(string(encoded(c.encode(`{"a":1}`))), nil)
+		assert.ErrorIs(t, err, ErrEncryptionDisabled)
+	})
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	keys := newTestKeyring(t, 1)
+	c, err := newCodec(CompressionNone, 0, keys)
+	require.NoError(t, err)
+
+	old := encoded(c.encode(`"old"`))
+
+	rotated := newTestKeyring(t, 1, 2)
+	keys.keys, keys.active = rotated.keys, rotated.active
+
+	current := encoded(c.encode(`"new"`))
+	assert.Equal(t, uint32(1), encryptionKeyID(string(old)))
+	assert.Equal(t, uint32(2), encryptionKeyID(string(current)))
+
+	decoded, err := c.decode(string(old))
+	require.NoError(t, err)
+	assert.Equal(t, `"old"`, decoded)
+}
+
+func TestKeyring_unwrap(t *testing.T) {
+	master, err := newAEAD(bytes.Repeat([]byte{0xaa}, masterKeyLen))
+	require.NoError(t, err)
+	k := &Keyring{master: master}
+
+	dataKey := bytes.Repeat([]byte{0x01}, dataKeySize)
+	nonce := make([]byte, master.NonceSize())
+	wrapped := master.Seal(nonce, nonce, dataKey, nil)
+
+	aead, err := k.unwrap(keyTuple{ID: 1, Wrapped: string(wrapped)})
+	require.NoError(t, err)
+	assert.NotNil(t, aead)
+
+	other, err := newAEAD(bytes.Repeat([]byte{0xbb}, masterKeyLen))
+	require.NoError(t, err)
+	_, err = (&Keyring{master: other}).unwrap(keyTuple{ID: 1, Wrapped: string(wrapped)})
+	assert.Error(t, err)
+}
+
+func TestKeyring_WrapKey(t *testing.T) {
+	master, err := newAEAD(bytes.Repeat([]byte{9}, masterKeyLen))
+	require.NoError(t, err)
+	k := &Keyring{master: master}
+
+	key := bytes.Repeat([]byte{7}, dataKeySize)
+	wrapped := k.WrapKey(key)
+	assert.NotContains(t, string(wrapped), string(key))
+
+	unwrapped, err := k.UnwrapKey(wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, key, unwrapped)
+
+	wrapped[len(wrapped)-1] ^= 0xff
+	_, err = k.UnwrapKey(wrapped)
+	assert.Error(t, err)
+	_, err = k.UnwrapKey(nil)
+	assert.ErrorIs(t, err, ErrInvalidDataFormat)
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+// EventsBroadcastKey is the box.broadcast key Tarantool notifies after
+// committing a change of the KV space.
+const EventsBroadcastKey = "kv.events"
+
+// Event types.
+const (
+	EventSet    = "set"
+	EventUpdate = "update"
+	EventDelete = "delete"
+)
+
+// Event is a change of a key recorded by Tarantool.
+type Event struct {
+	ID    uint64    `json:"id"`
+	Type  string    `json:"type"`
+	Key   string    `json:"key"`
+	Value any       `json:"value,omitempty"`
+	Time  time.Time `json:"time"`
+}
+
+type eventTuple struct {
+	_msgpack struct{} `msgpack:",asArray"`
+
+	ID    uint64
+	Key   string
+	Type  string
+	Value *string
+	Time  float64
+}
+
+// TarantoolEvents reads the change events of the KV space from Tarantool.
+type TarantoolEvents struct {
+	conn  *tarantool.Connection
+	space string
+	keys  *Keyring
+}
+
+// NewTarantoolEvents creates a new reader of the Tarantool events space. The
+// keys decrypt the values of events if the encryption is enabled, keys is
+// nil otherwise.
+func NewTarantoolEvents(conn *tarantool.Connection, space string, keys *Keyring) *TarantoolEvents {
+	return &TarantoolEvents{
+		conn:  conn,
+		space: space,
+		keys:  keys,
+	}
+}
+
+// Events returns up to limit events with IDs greater than afterID.
+func (s *TarantoolEvents) Events(afterID uint64, limit uint32) ([]Event, error) {
+	req := tarantool.NewSelectRequest(s.space).
+		Iterator(tarantool.IterGt).
+		Key(tarantool.UintKey{I: uint(afterID)}).
+		Limit(limit)
+
+	var tuples []eventTuple
+	if err := s.conn.Do(req).GetTyped(&tuples); err != nil {
+		return nil, storageError(err)
+	}
+
+	events := make([]Event, 0, len(tuples))
+	for _, t := range tuples {
+		event := Event{
+			ID:   t.ID,
+			Type: t.Type,
+			Key:  t.Key,
+			Time: unixFloatToTime(t.Time),
+		}
+		if t.Value != nil {
+			value, err := decodeValue(*t.Value, s.keys)
+			if err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal([]byte(value), &event.Value); err != nil {
+				return nil, err
+			}
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// LastEventID returns the ID of the most recent event or zero if there are
+// no events yet.
+func (s *TarantoolEvents) LastEventID() (uint64, error) {
+	req := tarantool.NewSelectRequest(s.space).
+		Iterator(tarantool.IterLe).
+		Key([]any{}).
+		Limit(1)
+
+	var tuples []eventTuple
+	if err := s.conn.Do(req).GetTyped(&tuples); err != nil {
+		return 0, storageError(err)
+	}
+
+	if len(tuples) == 0 {
+		return 0, nil
+	}
+	return tuples[0].ID, nil
+}
+
+func unixFloatToTime(ts float64) time.Time {
+	sec, frac := math.Modf(ts)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC()
+}
+
+func timeToUnixFloat(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"errors"
+	"time"
+
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+var (
+	// ErrNoExpiry is returned when the key has no expiration time.
+	ErrNoExpiry = errors.New("key has no expiration time")
+	// ErrExpiryDisabled is returned when the expiration of keys is used
+	// without the expiry space.
+	ErrExpiryDisabled = errors.New("expiry is disabled")
+)
+
+// expiryIndex is the name of the index on the expiration time of keys.
+const expiryIndex = "expires_at"
+
+// expiryTuple is a tuple of the expiry space: key and its expiration time.
+// The expiration time of a key is dropped by Tarantool when the key is
+// deleted.
+type expiryTuple struct {
+	_msgpack struct{} `msgpack:",asArray"`
+
+	Key       string
+	ExpiresAt float64
+}
+
+// Expire sets the expiration time of the key to ttl from now, a zero ttl
+// removes the expiration time. The key is checked and its expiration time is
+// set at once, ErrKeyNotFound is returned if it has no value. The key is
+// deleted once it expires and PurgeExpired is run, see PurgeExpired.
+func (s *Tarantool) Expire(key string, ttl time.Duration) error {
+	if s.expirySpace == "" && ttl != 0 {
+		return ErrExpiryDisabled
+	}
+
+	return s.inTx(func(doer tarantool.Doer) error {
+		t, err := s.getTuple(doer, key)
+		if err != nil {
+			return err
+		}
+		if t.deleted() {
+			return ErrKeyNotFound
+		}
+		if err := s.checkExpired(doer, key); err != nil {
+			return err
+		}
+		if ttl == 0 {
+			return s.persist(doer, key)
+		}
+
+		expiresAt := timeToUnixFloat(time.Now().Add(ttl))
+		req := tarantool.NewReplaceRequest(s.expirySpace).Tuple([]any{key, expiresAt})
+		_, err = doer.Do(req).Get()
+		return err
+	})
+}
+
+// Persist removes the expiration time of the key.
+func (s *Tarantool) Persist(key string) error {
+	return storageError(s.persist(s.conn, key))
+}
+
+func (s *Tarantool) persist(doer tarantool.Doer, key string) error {
+	if s.expirySpace == "" {
+		return nil
+	}
+
+	req := tarantool.NewDeleteRequest(s.expirySpace).Key(tarantool.StringKey{S: key})
+	_, err := doer.Do(req).Get()
+	return err
+}
+
+// TTL returns the time left until the key expires or ErrNoExpiry if it does
+// not expire.
+func (s *Tarantool) TTL(key string) (time.Duration, error) {
+	exists, err := s.Exists(key)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, ErrKeyNotFound
+	}
+
+	t, err := s.expiry(s.conn, key)
+	if err != nil {
+		return 0, err
+	}
+
+	return max(time.Until(unixFloatToTime(t.ExpiresAt)), 0), nil
+}
+
+// PurgeExpired deletes the keys expired by the given time the same way as
+// Delete does and returns the number of deleted keys. The expired keys are
+// hidden from reads before they are purged, see checkExpired.
+func (s *Tarantool) PurgeExpired(now time.Time) (int, error) {
+	if s.expirySpace == "" {
+		return 0, nil
+	}
+
+	cutoff := timeToUnixFloat(now)
+	purged := 0
+
+	for {
+		req := tarantool.NewSelectRequest(s.expirySpace).
+			Index(expiryIndex).
+			Iterator(tarantool.IterLe).
+			Key([]any{cutoff}).
+			Limit(purgeBatchSize)
+
+		var tuples []expiryTuple
+		if err := s.conn.Do(req).GetTyped(&tuples); err != nil {
+			return purged, storageError(err)
+		}
+
+		for _, t := range tuples {
+			// The expiration time may be changed since it was selected, it is
+			// checked again along with the deletion.
+			err := s.inTx(func(doer tarantool.Doer) error {
+				current, err := s.expiry(doer, t.Key)
+				if err != nil {
+					return err
+				}
+				if current.ExpiresAt > cutoff {
+					return ErrNoExpiry
+				}
+
+				if _, err := s.remove(doer, t.Key); err != nil {
+					return err
+				}
+				// Deleting a missing key leaves the expiration time in place.
+				return s.persist(doer, t.Key)
+			})
+			switch {
+			case err == nil:
+				purged++
+			case errors.Is(err, ErrNoExpiry):
+			default:
+				return purged, err
+			}
+		}
+
+		if len(tuples) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// checkExpired returns ErrKeyNotFound if the key has expired. The expired
+// keys are taken as not present before they are purged.
+func (s *Tarantool) checkExpired(doer tarantool.Doer, key string) error {
+	t, err := s.expiry(doer, key)
+	if errors.Is(err, ErrNoExpiry) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if t.ExpiresAt <= timeToUnixFloat(time.Now()) {
+		return ErrKeyNotFound
+	}
+	return nil
+}
+
+func (s *Tarantool) expiry(doer tarantool.Doer, key string) (expiryTuple, error) {
+	if s.expirySpace == "" {
+		return expiryTuple{}, ErrNoExpiry
+	}
+
+	req := tarantool.NewSelectRequest(s.expirySpace).Key(tarantool.StringKey{S: key})
+
+	var tuples []expiryTuple
+	if err := doer.Do(req).GetTyped(&tuples); err != nil {
+		return expiryTuple{}, storageError(err)
+	}
+	if len(tuples) == 0 {
+		return expiryTuple{}, ErrNoExpiry
+	}
+	return tuples[0], nil
+}
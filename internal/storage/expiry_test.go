@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tarantool/go-iproto"
+	"github.com/tarantool/go-tarantool/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// selectDoer answers every request with the tuples.
+type selectDoer struct {
+	tuples []any
+}
+
+func (d selectDoer) Do(req tarantool.Request) *tarantool.Future {
+	fut := tarantool.NewFuture(req)
+	body, err := msgpack.Marshal(map[iproto.Key]any{iproto.IPROTO_DATA: d.tuples})
+	if err == nil {
+		err = fut.SetResponse(tarantool.Header{}, bytes.NewReader(body))
+	}
+	if err != nil {
+		fut.SetError(err)
+	}
+	return fut
+}
+
+func TestTarantool_checkExpired(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		tuples  []any
+		wantErr error
+	}{
+		{name: "no expiration time", tuples: []any{}},
+		{name: "expires later", tuples: []any{[]any{"k", timeToUnixFloat(now.Add(time.Minute))}}},
+		{name: "expired", tuples: []any{[]any{"k", timeToUnixFloat(now.Add(-time.Second))}}, wantErr: ErrKeyNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Tarantool{expirySpace: "kv_expiry"}
+
+			err := s.checkExpired(selectDoer{tuples: tt.tuples}, "k")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+
+	disabled := &Tarantool{}
+	assert.NoError(t, disabled.checkExpired(selectDoer{tuples: []any{[]any{"k", 1.0}}}, "k"),
+		"keys do not expire without the expiry space")
+}
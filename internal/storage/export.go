@@ -0,0 +1,257 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+// ErrInvalidValue is returned when an imported value cannot be encoded.
+var ErrInvalidValue = errors.New("invalid value")
+
+// exportBatchSize is the maximum number of tuples read at once while
+// exporting.
+const exportBatchSize = 512
+
+// ExportRecord is a live key with its value as exported.
+type ExportRecord struct {
+	Key string
+	// Value is the JSON encoded value or the data of a blob.
+	Value []byte
+	// ContentType is the content type of a blob, empty for JSON values.
+	ContentType string
+	// ExpiresAt is the expiration time of the key, zero if it does not
+	// expire.
+	ExpiresAt time.Time
+}
+
+// Export calls fn for every live key starting with the prefix in ascending
+// order. The keys are read in batches, so the export is not a consistent
+// snapshot of the space: keys changed meanwhile may or may not be seen.
+func (s *Tarantool) Export(prefix string, fn func(ExportRecord) error) error {
+	iter, start := tarantool.IterGe, prefix
+	for {
+		req := tarantool.NewSelectRequest(s.space).
+			Index(s.index).
+			Iterator(iter).
+			Key(tarantool.StringKey{S: start}).
+			Limit(exportBatchSize)
+		resp, err := s.conn.Do(req).Get()
+		if err != nil {
+			return storageError(err)
+		}
+
+		tuples := make([]kvTuple, 0, len(resp))
+		for _, data := range resp {
+			t, err := s.decodeKVTuple(data)
+			if err != nil {
+				return err
+			}
+			// The keys with the prefix are contiguous in the index.
+			if !strings.HasPrefix(t.Key, prefix) {
+				break
+			}
+			tuples = append(tuples, t)
+		}
+		if len(tuples) == 0 {
+			return nil
+		}
+
+		expiries, err := s.batchExpiry(tuples[0].Key, tuples[len(tuples)-1].Key, len(tuples))
+		if err != nil {
+			return err
+		}
+
+		for _, t := range tuples {
+			if t.deleted() {
+				continue
+			}
+			if t.chunked() {
+				if t, err = s.exportTuple(t.Key); errors.Is(err, ErrKeyNotFound) {
+					continue
+				} else if err != nil {
+					return err
+				}
+			}
+
+			rec := ExportRecord{Key: t.Key, Value: []byte(t.Value), ContentType: t.ContentType}
+			if expiresAt, ok := expiries[t.Key]; ok {
+				rec.ExpiresAt = unixFloatToTime(expiresAt)
+			}
+			if err := fn(rec); err != nil {
+				return err
+			}
+		}
+
+		if len(tuples) < len(resp) || len(resp) < exportBatchSize {
+			return nil
+		}
+		iter, start = tarantool.IterGt, tuples[len(tuples)-1].Key
+	}
+}
+
+// exportTuple reads the tuple of the key along with its chunks in a
+// transaction, so the value is not mixed with a concurrent overwrite. The
+// value of the returned tuple is whole.
+func (s *Tarantool) exportTuple(key string) (kvTuple, error) {
+	var t kvTuple
+	err := s.inTx(func(doer tarantool.Doer) error {
+		var err error
+		if t, err = s.getTuple(doer, key); err != nil {
+			return err
+		}
+		if t.deleted() {
+			return ErrKeyNotFound
+		}
+		t.Value, err = s.readValue(doer, t)
+		return err
+	})
+	return t, err
+}
+
+// batchExpiry returns the expiration times of the keys between first and
+// last, both included, of which there are n. Every key of the expiry space
+// is a key of the KV space, so there are at most n of them.
+func (s *Tarantool) batchExpiry(first, last string, n int) (map[string]float64, error) {
+	if s.expirySpace == "" {
+		return nil, nil
+	}
+
+	req := tarantool.NewSelectRequest(s.expirySpace).
+		Iterator(tarantool.IterGe).
+		Key(tarantool.StringKey{S: first}).
+		Limit(uint32(n))
+
+	var tuples []expiryTuple
+	if err := s.conn.Do(req).GetTyped(&tuples); err != nil {
+		return nil, storageError(err)
+	}
+
+	expiries := make(map[string]float64, len(tuples))
+	for _, t := range tuples {
+		if t.Key > last {
+			break
+		}
+		expiries[t.Key] = t.ExpiresAt
+	}
+	return expiries, nil
+}
+
+// ImportMode is what Import does with the records of keys that exist.
+type ImportMode string
+
+const (
+	// ImportFail fails the records of existing keys.
+	ImportFail ImportMode = "fail"
+	// ImportSkip skips the records of existing keys.
+	ImportSkip ImportMode = "skip"
+	// ImportOverwrite overwrites the values of existing keys.
+	ImportOverwrite ImportMode = "overwrite"
+)
+
+// ImportRecord is a key with its value to import.
+type ImportRecord struct {
+	Key string
+	// Value is the decoded JSON value or a Blob.
+	Value any
+	// ExpiresAt is the expiration time of the key, zero if it does not
+	// expire. It is ignored if the expiry space is not set.
+	ExpiresAt time.Time
+}
+
+// ImportOutcome is what became of an imported record.
+type ImportOutcome int
+
+const (
+	// ImportCreated means the key was created.
+	ImportCreated ImportOutcome = iota
+	// ImportUpdated means the value of the existing key was overwritten.
+	ImportUpdated
+	// ImportSkipped means the key exists and was left as is.
+	ImportSkipped
+	// ImportFailed means the record was not imported, see ImportResult.Err.
+	ImportFailed
+)
+
+// ImportResult is the result of importing a record.
+type ImportResult struct {
+	Outcome ImportOutcome
+	// Err is the reason of the failure of the record, ErrKeyAlreadyExists for
+	// the existing keys in the ImportFail mode.
+	Err error
+}
+
+// Import writes the batch of records in a single transaction and returns the
+// result of every record. The records failing on their own, such as existing
+// keys in the ImportFail mode or values not matching the JSON indexes, do
+// not fail the others. An error is returned if the batch as a whole failed,
+// in which case nothing of it is written.
+func (s *Tarantool) Import(records []ImportRecord, mode ImportMode) ([]ImportResult, error) {
+	results := make([]ImportResult, len(records))
+	err := s.inTx(func(doer tarantool.Doer) error {
+		for i, rec := range records {
+			outcome, err := s.importRecord(doer, rec, mode)
+			switch {
+			case err == nil:
+				results[i] = ImportResult{Outcome: outcome}
+			case errors.Is(err, ErrKeyAlreadyExists), errors.Is(err, ErrFieldTypeMismatch),
+				errors.Is(err, ErrWrongType), errors.Is(err, ErrInvalidValue):
+				results[i] = ImportResult{Outcome: ImportFailed, Err: err}
+			default:
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *Tarantool) importRecord(doer tarantool.Doer, rec ImportRecord, mode ImportMode) (ImportOutcome, error) {
+	data, contentType, err := marshalValue(rec.Value)
+	if err != nil {
+		return ImportFailed, ErrInvalidValue
+	}
+	t := s.newTuple(rec.Key, data, contentType)
+
+	outcome := ImportCreated
+	current, err := s.getTuple(doer, rec.Key)
+	switch {
+	case errors.Is(err, ErrKeyNotFound):
+	case err != nil:
+		return ImportFailed, err
+	case current.deleted():
+		// The tombstone is overwritten as by Set.
+	case mode == ImportSkip:
+		return ImportSkipped, nil
+	case mode == ImportOverwrite:
+		outcome = ImportUpdated
+	default:
+		return ImportFailed, ErrKeyAlreadyExists
+	}
+
+	// The value is written first, as its failure is the only one the record
+	// may fail on alone: it leaves nothing written to roll back.
+	if err := s.store(doer, &t, current.Chunks, false); err != nil {
+		return ImportFailed, err
+	}
+	if err := s.recordRevision(doer, rec.Key, &t); err != nil {
+		return ImportFailed, err
+	}
+
+	if s.expirySpace == "" {
+		return outcome, nil
+	}
+	var req tarantool.Request = tarantool.NewDeleteRequest(s.expirySpace).Key(tarantool.StringKey{S: rec.Key})
+	if !rec.ExpiresAt.IsZero() {
+		req = tarantool.NewReplaceRequest(s.expirySpace).Tuple([]any{rec.Key, timeToUnixFloat(rec.ExpiresAt)})
+	}
+	if _, err := doer.Do(req).Get(); err != nil {
+		return ImportFailed, err
+	}
+	return outcome, nil
+}
@@ -0,0 +1,277 @@
+package storage
+
+import (
+	"errors"
+	"time"
+
+	"github.com/tarantool/go-tarantool/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+var (
+	// ErrHistoryDisabled is returned when the history is requested but not
+	// kept.
+	ErrHistoryDisabled = errors.New("history is disabled")
+	// ErrRevisionNotFound is returned when the revision is not found or holds
+	// no value.
+	ErrRevisionNotFound = errors.New("revision not found")
+)
+
+// Revision is a value of a key at some point of time. The values stored in
+// chunks are too large to be kept in the history, their revisions are
+// truncated and have no value.
+type Revision struct {
+	Revision  uint64    `json:"revision"`
+	Value     any       `json:"value,omitempty"`
+	Deleted   bool      `json:"deleted"`
+	Truncated bool      `json:"truncated,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// revisionTuple is a tuple of the history space: key, revision, stored
+// value or nil for a deletion, time, the content type for blobs and whether
+// the value is truncated. Trailing empty fields are not stored.
+type revisionTuple struct {
+	Key         string
+	Revision    uint64
+	Value       *string
+	Time        float64
+	ContentType string
+	Truncated   bool
+}
+
+// DecodeMsgpack decodes the tuple, the revisions stored before blobs were
+// supported have no content type field.
+func (t *revisionTuple) DecodeMsgpack(d *msgpack.Decoder) error {
+	n, err := d.DecodeArrayLen()
+	if err != nil {
+		return err
+	}
+	if n < 4 || n > 6 {
+		return ErrInvalidDataFormat
+	}
+
+	if t.Key, err = d.DecodeString(); err != nil {
+		return err
+	}
+	if t.Revision, err = d.DecodeUint64(); err != nil {
+		return err
+	}
+	value, err := d.DecodeInterface()
+	if err != nil {
+		return err
+	}
+	switch value := value.(type) {
+	case nil:
+	case string:
+		t.Value = &value
+	case []byte:
+		s := string(value)
+		t.Value = &s
+	default:
+		return ErrInvalidDataFormat
+	}
+	if t.Time, err = d.DecodeFloat64(); err != nil {
+		return err
+	}
+	if n >= 5 {
+		contentType, err := d.DecodeInterface()
+		if err != nil {
+			return err
+		}
+		if contentType != nil {
+			var ok bool
+			if t.ContentType, ok = contentType.(string); !ok {
+				return ErrInvalidDataFormat
+			}
+		}
+	}
+	if n >= 6 {
+		if t.Truncated, err = d.DecodeBool(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Tarantool) revision(t revisionTuple) (Revision, error) {
+	rev := Revision{
+		Revision:  t.Revision,
+		Deleted:   t.Value == nil && !t.Truncated,
+		Truncated: t.Truncated,
+		Time:      unixFloatToTime(t.Time),
+	}
+	if t.Value != nil {
+		value, err := s.codec.decode(*t.Value)
+		if err != nil {
+			return Revision{}, err
+		}
+		if rev.Value, err = unmarshalValue(value, t.ContentType); err != nil {
+			return Revision{}, err
+		}
+	}
+	return rev, nil
+}
+
+// History returns the kept revisions of the key, the latest first.
+func (s *Tarantool) History(key string) ([]Revision, error) {
+	tuples, err := s.revisions(s.conn, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tuples) == 0 {
+		return nil, ErrKeyNotFound
+	}
+
+	revisions := make([]Revision, 0, len(tuples))
+	for _, t := range tuples {
+		rev, err := s.revision(t)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, nil
+}
+
+// Revision returns the given revision of the key.
+func (s *Tarantool) Revision(key string, revision uint64) (Revision, error) {
+	t, err := s.findRevision(s.conn, key, func(t revisionTuple) bool {
+		return t.Revision == revision
+	})
+	if err != nil {
+		return Revision{}, err
+	}
+	return s.revision(t)
+}
+
+// RevisionAt returns the revision of the key that was current at the given
+// time.
+func (s *Tarantool) RevisionAt(key string, at time.Time) (Revision, error) {
+	ts := timeToUnixFloat(at)
+	t, err := s.findRevision(s.conn, key, func(t revisionTuple) bool {
+		return t.Time <= ts
+	})
+	if err != nil {
+		return Revision{}, err
+	}
+	if t.Value == nil && !t.Truncated {
+		return Revision{}, ErrKeyNotFound
+	}
+	return s.revision(t)
+}
+
+// Restore makes the value of the given revision the current value of the
+// key.
+func (s *Tarantool) Restore(key string, revision uint64) error {
+	if s.historyDepth <= 0 {
+		return ErrHistoryDisabled
+	}
+
+	return s.inTx(func(doer tarantool.Doer) error {
+		t, err := s.findRevision(doer, key, func(t revisionTuple) bool {
+			return t.Revision == revision
+		})
+		if err != nil {
+			return err
+		}
+		if t.Value == nil {
+			return ErrRevisionNotFound
+		}
+
+		value, err := s.codec.decode(*t.Value)
+		if err != nil {
+			return err
+		}
+
+		current, err := s.getTuple(doer, key)
+		if err != nil && !errors.Is(err, ErrKeyNotFound) {
+			return err
+		}
+
+		restored := s.newTuple(key, value, t.ContentType)
+		if err := s.store(doer, &restored, current.Chunks, false); err != nil {
+			return err
+		}
+
+		return s.recordRevision(doer, key, &restored)
+	})
+}
+
+// recordRevision stores the value of the tuple, or nil for a deletion, as the
+// next revision of the key and drops the revisions beyond the history depth.
+func (s *Tarantool) recordRevision(doer tarantool.Doer, key string, t *kvTuple) error {
+	if s.historyDepth <= 0 {
+		return nil
+	}
+
+	tuples, err := s.revisions(doer, key)
+	if err != nil {
+		return err
+	}
+
+	var next uint64 = 1
+	if len(tuples) > 0 {
+		next = tuples[0].Revision + 1
+	}
+
+	fields := []any{key, next, nil, timeToUnixFloat(time.Now()), nil, nil}
+	if t != nil {
+		if t.chunked() {
+			fields[5] = true
+		} else {
+			fields[2], _ = s.storedValue(t.Value, t.ContentType)
+		}
+		if t.blob() {
+			fields[4] = t.ContentType
+		}
+	}
+	for fields[len(fields)-1] == nil {
+		fields = fields[:len(fields)-1]
+	}
+	req := tarantool.NewInsertRequest(s.historySpace).Tuple(fields)
+	if _, err := doer.Do(req).Get(); err != nil {
+		return err
+	}
+
+	for i := s.historyDepth - 1; i < len(tuples); i++ {
+		req := tarantool.NewDeleteRequest(s.historySpace).Key([]any{key, tuples[i].Revision})
+		if _, err := doer.Do(req).Get(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// revisions returns the revisions of the key, the latest first.
+func (s *Tarantool) revisions(doer tarantool.Doer, key string) ([]revisionTuple, error) {
+	if s.historyDepth <= 0 {
+		return nil, ErrHistoryDisabled
+	}
+
+	req := tarantool.NewSelectRequest(s.historySpace).
+		Iterator(tarantool.IterReq).
+		Key([]any{key})
+
+	var tuples []revisionTuple
+	if err := doer.Do(req).GetTyped(&tuples); err != nil {
+		return nil, storageError(err)
+	}
+	return tuples, nil
+}
+
+func (s *Tarantool) findRevision(doer tarantool.Doer, key string, match func(revisionTuple) bool) (revisionTuple, error) {
+	tuples, err := s.revisions(doer, key)
+	if err != nil {
+		return revisionTuple{}, err
+	}
+
+	for _, t := range tuples {
+		if match(t) {
+			return t, nil
+		}
+	}
+	return revisionTuple{}, ErrRevisionNotFound
+}
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+// ErrNotInteger is returned when incrementing a value that is not an integer
+// or would overflow.
+var ErrNotInteger = errors.New("value is not an integer or out of range")
+
+// Exists reports whether the key has a value, expired keys have none.
+func (s *Tarantool) Exists(key string) (bool, error) {
+	t, err := s.getTuple(s.conn, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return false, nil
+	}
+	if err != nil || t.deleted() {
+		return false, err
+	}
+
+	err = s.checkExpired(s.conn, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Scan returns up to limit keys following the after key in ascending order,
+// from the first key if after is empty.
+func (s *Tarantool) Scan(after string, limit uint32) ([]string, error) {
+	return s.ScanPrefix("", after, limit)
+}
+
+// ScanPrefix returns up to limit keys starting with the prefix and following
+// the after key in ascending order, from the first such key if after is
+// empty.
+func (s *Tarantool) ScanPrefix(prefix, after string, limit uint32) ([]string, error) {
+	keys := make([]string, 0, limit)
+
+	iter, start := tarantool.IterGt, after
+	if after < prefix {
+		iter, start = tarantool.IterGe, prefix
+	}
+
+	for uint32(len(keys)) < limit {
+		req := tarantool.NewSelectRequest(s.space).
+			Index(s.index).
+			Iterator(iter).
+			Key(tarantool.StringKey{S: start}).
+			Limit(limit)
+		resp, err := s.conn.Do(req).Get()
+		if err != nil {
+			return nil, storageError(err)
+		}
+
+		for _, data := range resp {
+			t, err := s.decodeKVTuple(data)
+			if err != nil {
+				return nil, err
+			}
+			// The keys with the prefix are contiguous in the index.
+			if !strings.HasPrefix(t.Key, prefix) {
+				return keys, nil
+			}
+			start = t.Key
+
+			if !t.deleted() && uint32(len(keys)) < limit {
+				keys = append(keys, t.Key)
+			}
+		}
+
+		if uint32(len(resp)) < limit {
+			break
+		}
+		iter = tarantool.IterGt
+	}
+
+	return keys, nil
+}
+
+// Incr adds delta to the integer value of the key and returns the result.
+// A missing or expired key is set to delta. The value may be a JSON number or a string
+// holding an integer, the result is stored as a number.
+func (s *Tarantool) Incr(key string, delta int64) (int64, error) {
+	return s.incr(key, delta, false)
+}
+
+// IncrCounter adds delta to the value of the existing key like Incr does,
+// but treats it as an unsigned counter: decrementing it below zero gives
+// zero. It returns ErrKeyNotFound if the key does not exist or has expired.
+func (s *Tarantool) IncrCounter(key string, delta int64) (int64, error) {
+	return s.incr(key, delta, true)
+}
+
+func (s *Tarantool) incr(key string, delta int64, counter bool) (int64, error) {
+	var result int64
+
+	err := s.inTx(func(doer tarantool.Doer) error {
+		current, exists, err := s.liveTuple(doer, key)
+		if err != nil {
+			return err
+		}
+		if counter && !exists {
+			return ErrKeyNotFound
+		}
+
+		var n int64
+		if exists {
+			if current.blob() {
+				return ErrNotInteger
+			}
+
+			value, err := s.readValue(doer, current)
+			if err != nil {
+				return err
+			}
+			if n, err = parseInteger(value); err != nil {
+				return err
+			}
+		}
+
+		if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+			return ErrNotInteger
+		}
+		result = n + delta
+		if counter && result < 0 {
+			result = 0
+		}
+
+		t := s.newTuple(key, strconv.FormatInt(result, 10), "")
+		if err := s.store(doer, &t, current.Chunks, false); err != nil {
+			return err
+		}
+		if err := s.recordRevision(doer, key, &t); err != nil {
+			return err
+		}
+
+		// An expired key starts anew without its expiration time.
+		if !exists {
+			return s.persist(doer, key)
+		}
+		return nil
+	})
+
+	return result, err
+}
+
+// parseInteger parses the JSON encoded integer or string holding an integer.
+func parseInteger(value string) (int64, error) {
+	var decoded any
+	if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+		return 0, ErrNotInteger
+	}
+
+	raw := value
+	if str, ok := decoded.(string); ok {
+		raw = str
+	}
+
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, ErrNotInteger
+	}
+	return n, nil
+}
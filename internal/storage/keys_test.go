@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseInteger(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{name: "number", value: `42`, want: 42},
+		{name: "negative number", value: `-7`, want: -7},
+		{name: "string holding integer", value: `"15"`, want: 15},
+		{name: "fraction", value: `1.5`, wantErr: true},
+		{name: "exponent", value: `1e3`, wantErr: true},
+		{name: "string", value: `"abc"`, wantErr: true},
+		{name: "object", value: `{"a":1}`, wantErr: true},
+		{name: "out of range", value: `9223372036854775808`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseInteger(tt.value)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrNotInteger)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
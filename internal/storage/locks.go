@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+var (
+	// ErrLockHeld is returned when acquiring a lock held by someone else.
+	ErrLockHeld = errors.New("lock is held")
+	// ErrLockNotHeld is returned when the lock is not held or is held with
+	// another token.
+	ErrLockNotHeld = errors.New("lock is not held")
+)
+
+// Lease is a lock held until it expires. The fencing token increases with
+// every acquisition of the lock, so the resources guarded by the lock can
+// reject the writes of the holders whose leases have expired.
+type Lease struct {
+	Name         string    `json:"name"`
+	Token        string    `json:"token,omitempty"`
+	FencingToken uint64    `json:"fencing_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type lockTuple struct {
+	_msgpack struct{} `msgpack:",asArray"`
+
+	Name      string
+	Token     *string
+	Fencing   uint64
+	ExpiresAt float64
+}
+
+func (t lockTuple) lease() Lease {
+	lease := Lease{
+		Name:         t.Name,
+		FencingToken: t.Fencing,
+		ExpiresAt:    unixFloatToTime(t.ExpiresAt),
+	}
+	if t.Token != nil {
+		lease.Token = *t.Token
+	}
+	return lease
+}
+
+// TarantoolLocks manages the leases of locks stored in Tarantool. The leases
+// are changed by the functions defined on the Tarantool instance, so they
+// are atomic and expire by the clock of Tarantool.
+type TarantoolLocks struct {
+	conn  *tarantool.Connection
+	space string
+}
+
+// NewTarantoolLocks creates a new manager of the leases in the locks space.
+func NewTarantoolLocks(conn *tarantool.Connection, space string) *TarantoolLocks {
+	return &TarantoolLocks{
+		conn:  conn,
+		space: space,
+	}
+}
+
+// Acquire acquires the lock for ttl and returns the lease with the token to
+// renew and release it.
+func (l *TarantoolLocks) Acquire(name string, ttl time.Duration) (Lease, error) {
+	token := make([]byte, 16)
+	_, _ = rand.Read(token)
+
+	return l.call("kv_lock_acquire", ErrLockHeld, l.space, name, hex.EncodeToString(token), ttl.Seconds())
+}
+
+// Renew extends the lease of the lock held with the token for ttl from now.
+func (l *TarantoolLocks) Renew(name, token string, ttl time.Duration) (Lease, error) {
+	return l.call("kv_lock_renew", ErrLockNotHeld, l.space, name, token, ttl.Seconds())
+}
+
+// Release releases the lock held with the token.
+func (l *TarantoolLocks) Release(name, token string) error {
+	_, err := l.call("kv_lock_release", ErrLockNotHeld, l.space, name, token)
+	return err
+}
+
+// Get returns the current lease of the lock without its token.
+func (l *TarantoolLocks) Get(name string) (Lease, error) {
+	lease, err := l.call("kv_lock_get", ErrLockNotHeld, l.space, name)
+	lease.Token = ""
+	return lease, err
+}
+
+// call calls the function returning the lock tuple or nil, in which case
+// errNil is returned.
+func (l *TarantoolLocks) call(function string, errNil error, args ...any) (Lease, error) {
+	req := tarantool.NewCallRequest(function).Args(args)
+
+	var tuples []*lockTuple
+	if err := l.conn.Do(req).GetTyped(&tuples); err != nil {
+		return Lease{}, storageError(err)
+	}
+
+	if len(tuples) == 0 || tuples[0] == nil {
+		return Lease{}, errNil
+	}
+	return tuples[0].lease(), nil
+}
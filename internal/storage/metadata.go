@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"time"
+)
+
+// Metadata is the metadata of the value of a key.
+type Metadata struct {
+	// Version changes on every write of the value, see GetWithVersion. It is
+	// zero for the values stored in chunks before the version was kept in
+	// the tuple.
+	Version uint64
+	// ModifiedAt is the time the value was last set, zero for the values
+	// stored before it was kept in the tuple.
+	ModifiedAt time.Time
+	// ContentType is the content type of a blob, empty for JSON values.
+	ContentType string
+}
+
+// Metadata returns the metadata of the value of the key without reading the
+// chunks of the value.
+func (s *Tarantool) Metadata(key string) (Metadata, error) {
+	t, err := s.getTuple(s.conn, key)
+	if err != nil {
+		return Metadata{}, err
+	}
+	if t.deleted() {
+		return Metadata{}, ErrKeyDeleted
+	}
+	if err := s.checkExpired(s.conn, key); err != nil {
+		return Metadata{}, err
+	}
+	return t.metadata(), nil
+}
+
+// metadata returns the metadata of the tuple. The version of the values
+// stored before it was kept is derived from the value if it is inline.
+func (t kvTuple) metadata() Metadata {
+	meta := Metadata{
+		Version:     t.Version,
+		ContentType: t.ContentType,
+	}
+	if !t.chunked() {
+		meta.Version = t.version(t.Value)
+	}
+	if t.ModifiedAt != 0 {
+		meta.ModifiedAt = unixFloatToTime(t.ModifiedAt)
+	}
+	return meta
+}
+
+// decodeUint64 decodes the unsigned integer of a tuple field, which msgpack
+// decodes to the smallest fitting type.
+func decodeUint64(v any) (uint64, bool) {
+	switch v := v.(type) {
+	case uint8:
+		return uint64(v), true
+	case uint16:
+		return uint64(v), true
+	case uint32:
+		return uint64(v), true
+	case uint64:
+		return v, true
+	case uint:
+		return uint64(v), true
+	}
+
+	n, ok := decodeInt(v)
+	if !ok || n < 0 {
+		return 0, false
+	}
+	return uint64(n), true
+}
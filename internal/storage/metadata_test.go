@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVTuple_metadata(t *testing.T) {
+	s := &Tarantool{}
+	modifiedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	stored := s.newTuple("k", `"v"`, "")
+	stored.ModifiedAt = timeToUnixFloat(modifiedAt)
+	stored.Version = 42
+	tuple := s.tuple(stored)
+	assert.Equal(t, []any{"k", `"v"`, nil, nil, nil, nil, stored.ModifiedAt, stored.Version}, tuple)
+
+	decoded, err := s.decodeKVTuple(tuple)
+	require.NoError(t, err)
+	assert.Equal(t, Metadata{Version: stored.Version, ModifiedAt: modifiedAt}, decoded.metadata())
+
+	legacy, err := s.decodeKVTuple([]any{"k", "\x00\x01data", nil, nil, "image/png"})
+	require.NoError(t, err)
+	assert.Equal(t, Metadata{Version: valueVersion("\x01data", "image/png"), ContentType: "image/png"}, legacy.metadata(),
+		"version of inline values stored without one is derived from the value")
+
+	legacyChunked, err := s.decodeKVTuple([]any{"k", nil, nil, nil, nil, uint8(3)})
+	require.NoError(t, err)
+	assert.Equal(t, Metadata{}, legacyChunked.metadata())
+
+	_, err = s.decodeKVTuple([]any{"k", `"v"`, nil, nil, nil, nil, 1.5, int8(-1)})
+	assert.ErrorIs(t, err, ErrInvalidDataFormat)
+}
+
+func TestDecodeUint64(t *testing.T) {
+	tests := []struct {
+		value any
+		want  uint64
+		ok    bool
+	}{
+		{value: int8(5), want: 5, ok: true},
+		{value: uint64(1 << 63), want: 1 << 63, ok: true},
+		{value: int64(-1)},
+		{value: "5"},
+	}
+
+	for _, tt := range tests {
+		got, ok := decodeUint64(tt.value)
+		assert.Equal(t, tt.ok, ok, "%T(%v)", tt.value, tt.value)
+		assert.Equal(t, tt.want, got, "%T(%v)", tt.value, tt.value)
+	}
+}
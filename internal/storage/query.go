@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+var (
+	// ErrFieldNotIndexed is returned when querying a field without an index.
+	ErrFieldNotIndexed = errors.New("field is not indexed")
+	// ErrInvalidQueryValue is returned when the queried value cannot be
+	// converted to the type of the index.
+	ErrInvalidQueryValue = errors.New("invalid query value")
+	// ErrInvalidCursor is returned when the pagination cursor is malformed.
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// docField is the number of the tuple field holding the value as a native
+// document, as Tarantool counts fields.
+const docField = 4
+
+// JSON index field types.
+const (
+	JSONIndexString  = "string"
+	JSONIndexNumber  = "number"
+	JSONIndexBoolean = "boolean"
+)
+
+// JSONIndex is a secondary index on a field of JSON object values.
+type JSONIndex struct {
+	// Name is the name of the index in Tarantool.
+	Name string
+	// Path is the JSON path of the field inside the value, e.g. "country" or
+	// "address.city".
+	Path string
+	// Type is the type of the field: string, number or boolean.
+	Type string
+}
+
+// KeyValue is a key with its value.
+type KeyValue struct {
+	Key   string `json:"key"`
+	Value any    `json:"value"`
+}
+
+// QueryPage is a page of the query results.
+type QueryPage struct {
+	Items []KeyValue `json:"items"`
+	// Next is the cursor of the next page, empty for the last page.
+	Next string `json:"next,omitempty"`
+}
+
+// EnsureJSONIndexes creates the declared JSON indexes that do not exist yet.
+// Existing indexes are not altered.
+func (s *Tarantool) EnsureJSONIndexes() error {
+	for _, idx := range s.jsonIndexes {
+		switch idx.Type {
+		case JSONIndexString, JSONIndexNumber, JSONIndexBoolean:
+		default:
+			return fmt.Errorf("JSON index %q: unsupported type %q", idx.Name, idx.Type)
+		}
+
+		req := tarantool.NewEvalRequest(`
+			local space, name, field, path, type = ...
+			box.space[space]:create_index(name, {
+				type = "TREE",
+				unique = false,
+				if_not_exists = true,
+				parts = { { field = field, type = type, path = path, is_nullable = true } },
+			})
+		`).Args([]any{s.space, idx.Name, docField, idx.Path, idx.Type})
+		if _, err := s.conn.Do(req).Get(); err != nil {
+			return fmt.Errorf("create JSON index %q: %w", idx.Name, err)
+		}
+	}
+	return nil
+}
+
+// Query returns a page of up to limit keys which values have the indexed
+// field equal to the value. Soft deleted and expired keys are skipped, so a
+// page may contain fewer items than the limit.
+func (s *Tarantool) Query(path, value string, limit uint32, cursor string) (QueryPage, error) {
+	idx, ok := s.jsonIndex(path)
+	if !ok {
+		return QueryPage{}, ErrFieldNotIndexed
+	}
+
+	key, err := parseIndexValue(idx.Type, value)
+	if err != nil {
+		return QueryPage{}, err
+	}
+
+	req := tarantool.NewSelectRequest(s.space).
+		Index(idx.Name).
+		Iterator(tarantool.IterEq).
+		Key([]any{key}).
+		Limit(limit).
+		FetchPos(true)
+	if cursor != "" {
+		pos, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return QueryPage{}, ErrInvalidCursor
+		}
+		req = req.After(pos)
+	}
+
+	resp, err := s.conn.Do(req).GetResponse()
+	if err != nil {
+		return QueryPage{}, storageError(err)
+	}
+	selectResp, ok := resp.(*tarantool.SelectResponse)
+	if !ok {
+		return QueryPage{}, errors.New("unexpected select response")
+	}
+	rows, err := selectResp.Decode()
+	if err != nil {
+		return QueryPage{}, err
+	}
+
+	page := QueryPage{Items: make([]KeyValue, 0, len(rows))}
+	for _, row := range rows {
+		t, err := s.decodeKVTuple(row)
+		if err != nil {
+			return QueryPage{}, err
+		}
+		if t.deleted() {
+			continue
+		}
+		// The expired keys are skipped until they are purged.
+		err = s.checkExpired(s.conn, t.Key)
+		if errors.Is(err, ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return QueryPage{}, err
+		}
+
+		value, err := s.readValue(s.conn, t)
+		if err != nil {
+			return QueryPage{}, err
+		}
+
+		item := KeyValue{Key: t.Key}
+		if item.Value, err = unmarshalValue(value, t.ContentType); err != nil {
+			return QueryPage{}, err
+		}
+		page.Items = append(page.Items, item)
+	}
+
+	if uint32(len(rows)) == limit {
+		pos, err := selectResp.Pos()
+		if err != nil {
+			return QueryPage{}, err
+		}
+		if len(pos) > 0 {
+			page.Next = base64.RawURLEncoding.EncodeToString(pos)
+		}
+	}
+
+	return page, nil
+}
+
+func (s *Tarantool) jsonIndex(path string) (JSONIndex, bool) {
+	for _, idx := range s.jsonIndexes {
+		if idx.Path == path {
+			return idx, true
+		}
+	}
+	return JSONIndex{}, false
+}
+
+func parseIndexValue(typ, value string) (any, error) {
+	switch typ {
+	case JSONIndexNumber:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, ErrInvalidQueryValue
+		}
+		return v, nil
+	case JSONIndexBoolean:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, ErrInvalidQueryValue
+		}
+		return v, nil
+	default:
+		return value, nil
+	}
+}
+
+// indexedDocument returns the JSON encoded value as a native document holding
+// only the fields of the JSON indexes, so the value is not stored twice in
+// full. Values other than objects have no indexable fields and are
+// represented by an empty object.
+func indexedDocument(value string, indexes []JSONIndex) map[string]any {
+	doc := map[string]any{}
+	var full map[string]any
+	if err := json.Unmarshal([]byte(value), &full); err != nil {
+		return doc
+	}
+
+	for _, idx := range indexes {
+		names := strings.Split(idx.Path, ".")
+		field, ok := lookupField(full, names)
+		if !ok {
+			continue
+		}
+
+		parent := doc
+		for _, name := range names[:len(names)-1] {
+			child, ok := parent[name].(map[string]any)
+			if !ok {
+				child = map[string]any{}
+				parent[name] = child
+			}
+			parent = child
+		}
+		parent[names[len(names)-1]] = field
+	}
+	return doc
+}
+
+// lookupField returns the field of the document at the path of names.
+func lookupField(doc map[string]any, names []string) (any, bool) {
+	var field any = doc
+	for _, name := range names {
+		object, ok := field.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if field, ok = object[name]; !ok {
+			return nil, false
+		}
+	}
+	return field, true
+}
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndexedDocument(t *testing.T) {
+	indexes := []JSONIndex{
+		{Name: "country", Path: "country", Type: JSONIndexString},
+		{Name: "city", Path: "address.city", Type: JSONIndexString},
+		{Name: "age", Path: "age", Type: JSONIndexNumber},
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		want  map[string]any
+	}{
+		{
+			name:  "indexed fields only",
+			value: `{"country":"NL","address":{"city":"Delft","street":"Markt"},"bio":"long text"}`,
+			want:  map[string]any{"country": "NL", "address": map[string]any{"city": "Delft"}},
+		},
+		{name: "no indexed fields", value: `{"bio":"long text"}`, want: map[string]any{}},
+		{name: "parent is not an object", value: `{"address":"Delft"}`, want: map[string]any{}},
+		{name: "not an object", value: `"NL"`, want: map[string]any{}},
+		{name: "invalid JSON", value: `{`, want: map[string]any{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, indexedDocument(tt.value, indexes))
+		})
+	}
+}
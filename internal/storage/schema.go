@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+// ErrIncompatibleSchema is returned when the space or its indexes do not
+// match the storage.
+var ErrIncompatibleSchema = errors.New("incompatible schema")
+
+// kvFields are the field types of kvTuple the format of the KV space may
+// declare, in the order of the fields. Every field but the key is nullable,
+// as trailing empty fields are not stored.
+var kvFields = []struct {
+	name  string
+	types []string
+}{
+	{"key", []string{"string", "str"}},
+	{"value", []string{"any", "scalar", "string", "str"}},
+	{"deleted_at", []string{"any", "scalar", "number", "double"}},
+	{"doc", []string{"any"}},
+	{"content_type", []string{"any", "scalar", "string", "str"}},
+	{"chunks", []string{"any", "scalar", "number", "integer", "unsigned", "num"}},
+	{"modified_at", []string{"any", "scalar", "number", "double"}},
+	{"version", []string{"any", "scalar", "number", "integer", "unsigned", "num"}},
+	{"size", []string{"any", "scalar", "number", "integer", "unsigned", "num"}},
+}
+
+// VerifySchema loads the schema from Tarantool and checks the KV space
+// exists, has a format compatible with its tuples and the indexes the
+// storage reads by.
+func (s *Tarantool) VerifySchema() error {
+	schema, err := tarantool.GetSchema(s.conn)
+	if err != nil {
+		return fmt.Errorf("load schema: %w", err)
+	}
+	return s.verifySchema(schema)
+}
+
+func (s *Tarantool) verifySchema(schema tarantool.Schema) error {
+	space, ok := schema.Spaces[s.space]
+	if !ok {
+		return fmt.Errorf("%w: space %q does not exist", ErrIncompatibleSchema, s.space)
+	}
+	if err := verifyKVFormat(space); err != nil {
+		return fmt.Errorf("%w: space %q: %w", ErrIncompatibleSchema, s.space, err)
+	}
+
+	index, ok := space.Indexes[s.index]
+	if !ok {
+		return fmt.Errorf("%w: space %q has no index %q", ErrIncompatibleSchema, s.space, s.index)
+	}
+	if err := verifyKeyIndex(index); err != nil {
+		return fmt.Errorf("%w: index %q of space %q: %w", ErrIncompatibleSchema, s.index, s.space, err)
+	}
+
+	if s.softDelete {
+		if _, ok := space.Indexes[s.deletedIndex]; !ok {
+			return fmt.Errorf("%w: space %q has no index %q on the deletion time", ErrIncompatibleSchema, s.space, s.deletedIndex)
+		}
+	}
+	return nil
+}
+
+// verifyKVFormat checks the space accepts the tuples of every length up to
+// the number of fields of kvTuple. A space without a format accepts any.
+func verifyKVFormat(space tarantool.Space) error {
+	if space.FieldsCount != 0 {
+		return fmt.Errorf("field count is fixed to %d", space.FieldsCount)
+	}
+
+	for id := range uint32(len(space.FieldsById)) {
+		field := space.FieldsById[id]
+		if int(id) >= len(kvFields) {
+			if !field.IsNullable {
+				return fmt.Errorf("field %d %q must be nullable", id+1, field.Name)
+			}
+			continue
+		}
+
+		want := kvFields[id]
+		if !slices.Contains(want.types, field.Type) {
+			return fmt.Errorf("field %d %q has type %q, want one of %v for %s", id+1, field.Name, field.Type, want.types, want.name)
+		}
+		if id > 0 && !field.IsNullable {
+			return fmt.Errorf("field %d %q must be nullable", id+1, field.Name)
+		}
+	}
+	return nil
+}
+
+// verifyKeyIndex checks the index is unique on the key alone.
+func verifyKeyIndex(index tarantool.Index) error {
+	if !index.Unique {
+		return errors.New("must be unique")
+	}
+	if len(index.Fields) != 1 {
+		return fmt.Errorf("must have a single part on the key, has %d parts", len(index.Fields))
+	}
+	part := index.Fields[0]
+	if part.Id != 0 {
+		return fmt.Errorf("must be on field 1, the key, not on field %d", part.Id+1)
+	}
+	if part.Type != "string" && part.Type != "str" {
+		return fmt.Errorf("must be on a string part, not %q", part.Type)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+func TestVerifySchema(t *testing.T) {
+	primary := tarantool.Index{Name: "primary", Unique: true, Fields: []tarantool.IndexField{{Id: 0, Type: "string"}}}
+	deletedAt := tarantool.Index{Name: "deleted_at", Fields: []tarantool.IndexField{{Id: 2, Type: "number"}}}
+	format := []tarantool.Field{
+		{Name: "key", Type: "string"},
+		{Name: "value", Type: "any", IsNullable: true},
+		{Name: "deleted_at", Type: "number", IsNullable: true},
+		{Name: "doc", Type: "any", IsNullable: true},
+		{Name: "content_type", Type: "string", IsNullable: true},
+		{Name: "chunks", Type: "unsigned", IsNullable: true},
+		{Name: "modified_at", Type: "number", IsNullable: true},
+		{Name: "version", Type: "unsigned", IsNullable: true},
+		{Name: "size", Type: "unsigned", IsNullable: true},
+	}
+
+	space := func(fields []tarantool.Field, indexes ...tarantool.Index) tarantool.Space {
+		s := tarantool.Space{Name: "kv", FieldsById: map[uint32]tarantool.Field{}, Indexes: map[string]tarantool.Index{}}
+		for i, f := range fields {
+			f.Id = uint32(i)
+			s.FieldsById[f.Id] = f
+		}
+		for _, idx := range indexes {
+			s.Indexes[idx.Name] = idx
+		}
+		return s
+	}
+	with := func(i int, f tarantool.Field) []tarantool.Field {
+		fields := append([]tarantool.Field(nil), format...)
+		if i == len(fields) {
+			return append(fields, f)
+		}
+		fields[i] = f
+		return fields
+	}
+
+	tests := []struct {
+		name       string
+		space      tarantool.Space
+		softDelete bool
+		wantErr    string
+	}{
+		{
+			name:       "compatible",
+			space:      space(format, primary, deletedAt),
+			softDelete: true,
+		},
+		{
+			name:  "no format",
+			space: space(nil, primary),
+		},
+		{
+			name:  "partial format",
+			space: space(format[:1], primary),
+		},
+		{
+			name:    "no space",
+			wantErr: `incompatible schema: space "kv" does not exist`,
+		},
+		{
+			name:    "no index",
+			space:   space(format),
+			wantErr: `incompatible schema: space "kv" has no index "primary"`,
+		},
+		{
+			name:    "non-unique index",
+			space:   space(format, tarantool.Index{Name: "primary", Fields: primary.Fields}),
+			wantErr: `incompatible schema: index "primary" of space "kv": must be unique`,
+		},
+		{
+			name: "index on another field",
+			space: space(format, tarantool.Index{
+				Name: "primary", Unique: true, Fields: []tarantool.IndexField{{Id: 4, Type: "string"}},
+			}),
+			wantErr: `incompatible schema: index "primary" of space "kv": must be on field 1, the key, not on field 5`,
+		},
+		{
+			name: "unsigned index",
+			space: space(nil, tarantool.Index{
+				Name: "primary", Unique: true, Fields: []tarantool.IndexField{{Id: 0, Type: "unsigned"}},
+			}),
+			wantErr: `incompatible schema: index "primary" of space "kv": must be on a string part, not "unsigned"`,
+		},
+		{
+			name: "multipart index",
+			space: space(nil, tarantool.Index{
+				Name: "primary", Unique: true, Fields: []tarantool.IndexField{{Id: 0, Type: "string"}, {Id: 1, Type: "string"}},
+			}),
+			wantErr: `incompatible schema: index "primary" of space "kv": must have a single part on the key, has 2 parts`,
+		},
+		{
+			name:    "wrong field type",
+			space:   space(with(2, tarantool.Field{Name: "deleted_at", Type: "string", IsNullable: true}), primary),
+			wantErr: `incompatible schema: space "kv": field 3 "deleted_at" has type "string", want one of [any scalar number double] for deleted_at`,
+		},
+		{
+			name:    "non-nullable field",
+			space:   space(with(1, tarantool.Field{Name: "value", Type: "any"}), primary),
+			wantErr: `incompatible schema: space "kv": field 2 "value" must be nullable`,
+		},
+		{
+			name:    "extra non-nullable field",
+			space:   space(with(9, tarantool.Field{Name: "owner", Type: "string"}), primary),
+			wantErr: `incompatible schema: space "kv": field 10 "owner" must be nullable`,
+		},
+		{
+			name:       "no deleted index",
+			space:      space(format, primary),
+			softDelete: true,
+			wantErr:    `incompatible schema: space "kv" has no index "deleted_at" on the deletion time`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Tarantool{space: "kv", index: "primary", softDelete: tt.softDelete, deletedIndex: "deleted_at"}
+			schema := tarantool.Schema{Spaces: map[string]tarantool.Space{}}
+			if tt.space.Name != "" {
+				schema.Spaces[tt.space.Name] = tt.space
+			}
+
+			err := s.verifySchema(schema)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrIncompatibleSchema)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
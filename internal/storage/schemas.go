@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+type schemaTuple struct {
+	_msgpack struct{} `msgpack:",asArray"`
+
+	Prefix string
+	Schema string
+}
+
+// TarantoolSchemas stores the JSON Schemas of values in Tarantool, so they
+// outlive the process that registered them.
+type TarantoolSchemas struct {
+	conn  tarantool.Doer
+	space string
+}
+
+// NewTarantoolSchemas creates a new store of the JSON Schemas in the space.
+func NewTarantoolSchemas(conn tarantool.Doer, space string) *TarantoolSchemas {
+	return &TarantoolSchemas{
+		conn:  conn,
+		space: space,
+	}
+}
+
+// Save stores the schema of the key prefix, replacing the previous one.
+func (s *TarantoolSchemas) Save(prefix string, schema []byte) error {
+	req := tarantool.NewReplaceRequest(s.space).Tuple(schemaTuple{Prefix: prefix, Schema: string(schema)})
+	_, err := s.conn.Do(req).Get()
+	return err
+}
+
+// Delete removes the schema of the key prefix and reports whether there was
+// one.
+func (s *TarantoolSchemas) Delete(prefix string) (bool, error) {
+	req := tarantool.NewDeleteRequest(s.space).Key(tarantool.StringKey{S: prefix})
+
+	var tuples []schemaTuple
+	if err := s.conn.Do(req).GetTyped(&tuples); err != nil {
+		return false, err
+	}
+	return len(tuples) > 0, nil
+}
+
+// Load returns the stored schemas by their key prefixes.
+func (s *TarantoolSchemas) Load() (map[string][]byte, error) {
+	req := tarantool.NewSelectRequest(s.space).Iterator(tarantool.IterAll)
+
+	var tuples []schemaTuple
+	if err := s.conn.Do(req).GetTyped(&tuples); err != nil {
+		return nil, err
+	}
+
+	schemas := make(map[string][]byte, len(tuples))
+	for _, t := range tuples {
+		schemas[t.Prefix] = []byte(t.Schema)
+	}
+	return schemas, nil
+}
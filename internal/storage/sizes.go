@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+// Names of the totals in the sizes space.
+const (
+	sizeCompressed   = "compressed"
+	sizeUncompressed = "uncompressed"
+)
+
+type sizeTuple struct {
+	_msgpack struct{} `msgpack:",asArray"`
+
+	Name  string
+	Bytes uint64
+}
+
+// TarantoolSizes reads the sizes of the compressed values stored in the KV
+// and chunk spaces. The totals are kept by the triggers of the spaces on
+// every replace and delete, the values compressed before they were set are
+// not counted until they are set again.
+type TarantoolSizes struct {
+	conn  tarantool.Doer
+	space string
+}
+
+// NewTarantoolSizes creates a new reader of the totals in the sizes space.
+func NewTarantoolSizes(conn tarantool.Doer, space string) *TarantoolSizes {
+	return &TarantoolSizes{
+		conn:  conn,
+		space: space,
+	}
+}
+
+// Compression returns the size of the compressed values stored and their
+// size before compression.
+func (s *TarantoolSizes) Compression() (compressed, uncompressed uint64, err error) {
+	req := tarantool.NewSelectRequest(s.space).Iterator(tarantool.IterAll)
+
+	var tuples []sizeTuple
+	if err := s.conn.Do(req).GetTyped(&tuples); err != nil {
+		return 0, 0, err
+	}
+
+	for _, t := range tuples {
+		switch t.Name {
+		case sizeCompressed:
+			compressed = t.Bytes
+		case sizeUncompressed:
+			uncompressed = t.Bytes
+		}
+	}
+	return compressed, uncompressed, nil
+}
@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/tarantool/go-iproto"
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+var (
+	// ErrWrongType is returned when an operation on a list, hash or set hits
+	// a key holding a value of another type.
+	ErrWrongType = errors.New("key holds a value of another type")
+	// ErrHashFieldNotFound is returned when the field is not in the hash.
+	ErrHashFieldNotFound = errors.New("hash field not found")
+)
+
+// wrongTypeErrorCode is the code of the error raised by the structure
+// functions defined on the Tarantool instance on a key of another type, and
+// by the KV space on a write of a plain value to the key of a structure.
+const wrongTypeErrorCode iproto.Error = 10001
+
+// TarantoolStructures stores lists, hashes and sets in Tarantool. Each one is
+// a single tuple changed by the functions defined on the Tarantool instance,
+// so the operations are atomic. The keys share the namespace with the KV
+// space: an operation on a key holding a plain value fails with
+// ErrWrongType, and so does writing a plain value to the key of a structure.
+// A structure that becomes empty is removed.
+type TarantoolStructures struct {
+	conn    *tarantool.Connection
+	space   string
+	kvSpace string
+}
+
+// NewTarantoolStructures creates a new storage of the structures in the
+// structures space, whose keys must not be in the KV space.
+func NewTarantoolStructures(conn *tarantool.Connection, space, kvSpace string) *TarantoolStructures {
+	return &TarantoolStructures{
+		conn:    conn,
+		space:   space,
+		kvSpace: kvSpace,
+	}
+}
+
+// ListPush pushes the values to the head of the list if left is set or to
+// its tail otherwise, creating the list if need be. It returns the length of
+// the list.
+func (s *TarantoolStructures) ListPush(key string, left bool, values []any) (int, error) {
+	var res []int
+	if err := s.call("kv_list_push", &res, key, left, values); err != nil {
+		return 0, err
+	}
+	return first(res)
+}
+
+// ListPop removes up to count values from the head of the list if left is set
+// or from its tail otherwise and returns them in the order of removal.
+func (s *TarantoolStructures) ListPop(key string, left bool, count int) ([]any, error) {
+	var res [][]any
+	if err := s.call("kv_list_pop", &res, key, left, count); err != nil {
+		return nil, err
+	}
+	return first(res)
+}
+
+// ListRange returns the values of the list from start to stop inclusive.
+// Negative indexes count from the tail, so -1 is the last value.
+func (s *TarantoolStructures) ListRange(key string, start, stop int) ([]any, error) {
+	var res [][]any
+	if err := s.call("kv_list_range", &res, key, start, stop); err != nil {
+		return nil, err
+	}
+	return first(res)
+}
+
+// HashSet sets the fields of the hash, creating the hash if need be. It
+// returns the number of fields that were not in the hash.
+func (s *TarantoolStructures) HashSet(key string, fields map[string]any) (int, error) {
+	var res []int
+	if err := s.call("kv_hash_set", &res, key, fields); err != nil {
+		return 0, err
+	}
+	return first(res)
+}
+
+// HashGet returns the value of the field of the hash.
+func (s *TarantoolStructures) HashGet(key, field string) (any, error) {
+	var res []any
+	if err := s.call("kv_hash_get", &res, key, field); err != nil {
+		return nil, err
+	}
+
+	if len(res) != 2 {
+		return nil, ErrInvalidDataFormat
+	}
+	if found, _ := res[1].(bool); !found {
+		return nil, ErrHashFieldNotFound
+	}
+	return res[0], nil
+}
+
+// HashGetAll returns all fields of the hash.
+func (s *TarantoolStructures) HashGetAll(key string) (map[string]any, error) {
+	var res []map[string]any
+	if err := s.call("kv_hash_get_all", &res, key); err != nil {
+		return nil, err
+	}
+	return first(res)
+}
+
+// HashDelete deletes the fields from the hash and returns the number of
+// deleted fields.
+func (s *TarantoolStructures) HashDelete(key string, fields []string) (int, error) {
+	var res []int
+	if err := s.call("kv_hash_delete", &res, key, fields); err != nil {
+		return 0, err
+	}
+	return first(res)
+}
+
+// SetAdd adds the members to the set, creating the set if need be. It
+// returns the number of members that were not in the set.
+func (s *TarantoolStructures) SetAdd(key string, members []string) (int, error) {
+	var res []int
+	if err := s.call("kv_set_add", &res, key, members); err != nil {
+		return 0, err
+	}
+	return first(res)
+}
+
+// SetRemove removes the members from the set and returns the number of
+// removed members.
+func (s *TarantoolStructures) SetRemove(key string, members []string) (int, error) {
+	var res []int
+	if err := s.call("kv_set_remove", &res, key, members); err != nil {
+		return 0, err
+	}
+	return first(res)
+}
+
+// SetMembers returns the members of the set in ascending order.
+func (s *TarantoolStructures) SetMembers(key string) ([]string, error) {
+	var res [][]string
+	if err := s.call("kv_set_members", &res, key); err != nil {
+		return nil, err
+	}
+	return first(res)
+}
+
+// SetIsMember reports whether the member is in the set.
+func (s *TarantoolStructures) SetIsMember(key, member string) (bool, error) {
+	var res []bool
+	if err := s.call("kv_set_is_member", &res, key, member); err != nil {
+		return false, err
+	}
+	return first(res)
+}
+
+// call calls the structure function with the spaces and args and decodes
+// its results into res.
+func (s *TarantoolStructures) call(function string, res any, args ...any) error {
+	req := tarantool.NewCallRequest(function).Args(append([]any{s.space, s.kvSpace}, args...))
+
+	if err := s.conn.Do(req).GetTyped(res); err != nil {
+		if checkWrongTypeError(err) {
+			return ErrWrongType
+		}
+		return storageError(err)
+	}
+	return nil
+}
+
+// first returns the first result of a function call.
+func first[T any](res []T) (T, error) {
+	var zero T
+	if len(res) == 0 {
+		return zero, ErrInvalidDataFormat
+	}
+	return res[0], nil
+}
+
+func checkWrongTypeError(err error) bool {
+	var tntErr tarantool.Error
+	return errors.As(err, &tntErr) && tntErr.Code == wrongTypeErrorCode
+}
@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+func TestWriteError_WrongType(t *testing.T) {
+	err := fmt.Errorf("replace: %w", tarantool.Error{Code: wrongTypeErrorCode, Msg: "key holds a value of another type"})
+	assert.ErrorIs(t, writeError(err), ErrWrongType)
+
+	other := tarantool.Error{Code: 10002, Msg: "other"}
+	assert.False(t, errors.Is(writeError(other), ErrWrongType))
+}
+
+func TestFirst(t *testing.T) {
+	got, err := first([]int{3, 4})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, got)
+
+	_, err = first([]int{})
+	assert.ErrorIs(t, err, ErrInvalidDataFormat)
+}
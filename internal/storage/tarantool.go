@@ -1,10 +1,11 @@
 package storage
 
 import (
-	"encoding/json"
 	"errors"
-	"strings"
+	"fmt"
+	"time"
 
+	"github.com/tarantool/go-iproto"
 	"github.com/tarantool/go-tarantool/v2"
 )
 
@@ -15,100 +16,927 @@ var (
 	ErrKeyNotFound = errors.New("key not found")
 	// ErrKeyNotFound is returned when the key is already exists.
 	ErrKeyAlreadyExists = errors.New("key already exists")
+	// ErrKeyDeleted is returned when the key is soft deleted.
+	ErrKeyDeleted = errors.New("key deleted")
+	// ErrKeyNotDeleted is returned when undeleting a key that is not deleted.
+	ErrKeyNotDeleted = errors.New("key not deleted")
+	// ErrSoftDeleteDisabled is returned when undeleting a key while deleted
+	// keys are not kept.
+	ErrSoftDeleteDisabled = errors.New("soft delete is disabled")
+	// ErrFieldTypeMismatch is returned when an indexed field of the value has
+	// a type other than the index expects.
+	ErrFieldTypeMismatch = errors.New("indexed field type mismatch")
+	// ErrConflict is returned when the transaction conflicts with a
+	// concurrent one and is aborted, it may be retried.
+	ErrConflict = errors.New("transaction conflict")
+	// ErrUnavailable is returned when Tarantool cannot be reached.
+	ErrUnavailable = errors.New("storage unavailable")
+	// ErrReadOnly is returned when a write is sent to a read-only instance.
+	ErrReadOnly = errors.New("storage is read-only")
+	// ErrSpaceNotFound is returned when a space or an index of the storage
+	// does not exist.
+	ErrSpaceNotFound = errors.New("space or index not found")
+	// ErrOutOfMemory is returned when Tarantool has run out of memory for
+	// tuples.
+	ErrOutOfMemory = errors.New("storage out of memory")
+	// ErrTimeout is returned when Tarantool does not reply in time.
+	ErrTimeout = errors.New("storage timeout")
 )
 
+// modifyAttempts is the number of times Modify runs its transaction before
+// giving up on the conflicts with concurrent ones.
+const modifyAttempts = 5
+
+// purgeBatchSize is the maximum number of tombstones read at once while
+// purging.
+const purgeBatchSize = 512
+
 // Tarantool is a storage implementation that uses Tarantool as a backend.
 type Tarantool struct {
-	conn  *tarantool.Connection
-	space string
-	index string
+	conn         *tarantool.Connection
+	space        string
+	index        string
+	historySpace string
+	historyDepth int
+	chunkSpace   string
+	chunkSize    int
+	expirySpace  string
+	versionSeq   string
+	softDelete   bool
+	deletedIndex string
+	jsonIndexes  []JSONIndex
+	codec        codec
+}
+
+// TarantoolOptions is the Tarantool storage options.
+type TarantoolOptions struct {
+	// Space is the name of the space storing the key-value pairs.
+	Space string
+	// Index is the name of the unique index on keys.
+	Index string
+	// HistorySpace is the name of the space storing the previous revisions
+	// of values.
+	HistorySpace string
+	// HistoryDepth is the number of revisions kept for every key, zero
+	// disables the history.
+	HistoryDepth int
+	// ChunkSpace is the name of the space storing the chunks of large
+	// values.
+	ChunkSpace string
+	// ChunkSize is the maximum size in bytes of a value stored inline, larger
+	// values are split into chunks of this size. Zero disables chunking.
+	ChunkSize int
+	// ExpirySpace is the name of the space storing the expiration times of
+	// keys, the keys do not expire if it is empty.
+	ExpirySpace string
+	// VersionSequence is the name of the sequence the versions of values
+	// are taken from.
+	VersionSequence string
+	// SoftDelete makes Delete keep a tombstone of the key that can be
+	// undeleted until it is purged.
+	SoftDelete bool
+	// DeletedIndex is the name of the index on the deletion time of
+	// tombstones.
+	DeletedIndex string
+	// JSONIndexes are the secondary indexes on fields of JSON object values.
+	JSONIndexes []JSONIndex
+	// Compression is the algorithm compressing the values, none if empty.
+	Compression string
+	// CompressionThreshold is the minimum size of a JSON encoded value in
+	// bytes to be compressed.
+	CompressionThreshold int
+	// Keyring encrypts the values, they are stored in plaintext if it is
+	// nil. The encryption cannot be used along with JSON indexes, which keep
+	// the values in plaintext for Tarantool to index them.
+	Keyring *Keyring
 }
 
 // NewTarantool creates a new Tarantool storage.
-func NewTarantool(conn *tarantool.Connection, space, index string) *Tarantool {
+func NewTarantool(conn *tarantool.Connection, opts TarantoolOptions) (*Tarantool, error) {
+	codec, err := newCodec(opts.Compression, opts.CompressionThreshold, opts.Keyring)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Keyring != nil && len(opts.JSONIndexes) > 0 {
+		return nil, errors.New("JSON indexes cannot be used with encryption")
+	}
+
 	return &Tarantool{
-		conn:  conn,
-		space: space,
-		index: index,
+		conn:         conn,
+		space:        opts.Space,
+		index:        opts.Index,
+		historySpace: opts.HistorySpace,
+		historyDepth: opts.HistoryDepth,
+		chunkSpace:   opts.ChunkSpace,
+		chunkSize:    opts.ChunkSize,
+		expirySpace:  opts.ExpirySpace,
+		versionSeq:   opts.VersionSequence,
+		softDelete:   opts.SoftDelete,
+		deletedIndex: opts.DeletedIndex,
+		jsonIndexes:  opts.JSONIndexes,
+		codec:        codec,
+	}, nil
+}
+
+// kvTuple is a tuple of the KV space: key, JSON encoded value or blob data,
+// the deletion time for tombstones, the value as a native document for JSON
+// indexes if they are declared, the content type of blobs, the number of
+// chunks of values stored in the chunk space, which have no value in the
+// tuple itself, the time the value was set, its version, see GetWithVersion,
+// and the size of compressed values before compression, which is only
+// written. Trailing empty fields are not stored. KeyID is the ID of the
+// data key the value is encrypted with, it is not stored separately.
+type kvTuple struct {
+	Key         string
+	Value       string
+	DeletedAt   float64
+	Doc         any
+	ContentType string
+	Chunks      int
+	ModifiedAt  float64
+	Version     uint64
+	KeyID       uint32
+}
+
+func (t kvTuple) deleted() bool {
+	return t.DeletedAt != 0
+}
+
+func (t kvTuple) blob() bool {
+	return t.ContentType != ""
+}
+
+func (t kvTuple) chunked() bool {
+	return t.Chunks > 0
+}
+
+// tuple returns the fields of the tuple to store, the value is compressed
+// if the compression is enabled. A compressed value is stored along with its
+// size before compression, to count the bytes saved.
+func (s *Tarantool) tuple(t kvTuple) []any {
+	fields := []any{t.Key, nil, nil, t.Doc, nil, nil, nil, nil, nil}
+	if t.chunked() {
+		fields[5] = t.Chunks
+	} else {
+		var compressed bool
+		fields[1], compressed = s.storedValue(t.Value, t.ContentType)
+		if compressed {
+			fields[8] = len(t.Value)
+		}
+	}
+	if t.deleted() {
+		fields[2] = t.DeletedAt
+	}
+	if t.blob() {
+		fields[4] = t.ContentType
+	}
+	if t.ModifiedAt != 0 {
+		fields[6] = t.ModifiedAt
 	}
+	if t.Version != 0 {
+		fields[7] = t.Version
+	}
+
+	for fields[len(fields)-1] == nil {
+		fields = fields[:len(fields)-1]
+	}
+	return fields
 }
 
-// Set stores the value for the given key.
+// storedValue returns the value to store in a tuple and reports whether it
+// is compressed. Blob data is always stored as binary, marked so it cannot
+// be taken for compressed data.
+func (s *Tarantool) storedValue(value, contentType string) (any, bool) {
+	if contentType != "" {
+		return s.codec.encodeBinary(value)
+	}
+	return s.codec.encode(value)
+}
+
+// newTuple returns the tuple of the live key with the JSON encoded value or,
+// if the content type is set, the blob data.
+func (s *Tarantool) newTuple(key, value, contentType string) kvTuple {
+	t := kvTuple{Key: key, Value: value, ContentType: contentType}
+	if len(s.jsonIndexes) > 0 {
+		if t.blob() {
+			t.Doc = map[string]any{}
+		} else {
+			// The value may be compressed or stored in chunks, its document
+			// keeps only the indexed fields not to store it in full again.
+			t.Doc = indexedDocument(value, s.jsonIndexes)
+		}
+	}
+	return t
+}
+
+func (s *Tarantool) decodeKVTuple(data any) (kvTuple, error) {
+	row, ok := data.([]any)
+	if !ok {
+		return kvTuple{}, errors.New("cannot retrieve response row")
+	}
+
+	if len(row) < 2 || len(row) > 9 {
+		return kvTuple{}, ErrInvalidDataFormat
+	}
+
+	var t kvTuple
+	if t.Key, ok = row[0].(string); !ok {
+		return kvTuple{}, ErrInvalidDataFormat
+	}
+	if len(row) >= 6 && row[5] != nil {
+		if t.Chunks, ok = decodeInt(row[5]); !ok || t.Chunks <= 0 {
+			return kvTuple{}, ErrInvalidDataFormat
+		}
+	}
+	switch value := row[1].(type) {
+	case string:
+		t.Value = value
+	case []byte:
+		t.Value = string(value)
+	case nil:
+		if !t.chunked() {
+			return kvTuple{}, ErrInvalidDataFormat
+		}
+	default:
+		return kvTuple{}, ErrInvalidDataFormat
+	}
+	t.KeyID = encryptionKeyID(t.Value)
+	var err error
+	if t.Value, err = s.codec.decode(t.Value); err != nil {
+		return kvTuple{}, err
+	}
+	if len(row) >= 3 && row[2] != nil {
+		if t.DeletedAt, ok = row[2].(float64); !ok {
+			return kvTuple{}, ErrInvalidDataFormat
+		}
+	}
+	if len(row) >= 5 && row[4] != nil {
+		if t.ContentType, ok = row[4].(string); !ok {
+			return kvTuple{}, ErrInvalidDataFormat
+		}
+	}
+	if len(row) >= 7 && row[6] != nil {
+		if t.ModifiedAt, ok = row[6].(float64); !ok {
+			return kvTuple{}, ErrInvalidDataFormat
+		}
+	}
+	if len(row) >= 8 && row[7] != nil {
+		if t.Version, ok = decodeUint64(row[7]); !ok {
+			return kvTuple{}, ErrInvalidDataFormat
+		}
+	}
+
+	return t, nil
+}
+
+// Set stores the value for the given key. An expired key is taken as not
+// present and its expiration time is removed.
 func (s *Tarantool) Set(key string, value any) error {
-	jsonValue, err := json.Marshal(value)
+	data, contentType, err := marshalValue(value)
 	if err != nil {
 		return err
 	}
+	t := s.newTuple(key, data, contentType)
 
-	req := tarantool.NewInsertRequest(s.space).Tuple([]any{key, string(jsonValue)})
-	if _, err := s.conn.Do(req).Get(); err != nil {
-		if checkDuplicateKeyError(err) {
+	return s.write(func(doer tarantool.Doer) error {
+		if !s.softDelete && s.expirySpace == "" {
+			if err := s.store(doer, &t, 0, true); err != nil {
+				return err
+			}
+
+			return s.recordRevision(doer, key, &t)
+		}
+
+		current, present, err := s.liveTuple(doer, key)
+		if err != nil {
+			return err
+		}
+		if present {
 			return ErrKeyAlreadyExists
 		}
-		return err
-	}
 
-	return nil
+		// Overwrite the tombstone or the expired value if there is one.
+		if err := s.store(doer, &t, current.Chunks, false); err != nil {
+			return err
+		}
+		if err := s.recordRevision(doer, key, &t); err != nil {
+			return err
+		}
+
+		return s.persist(doer, key)
+	})
 }
 
-// Update updates the value for the given key.
+// Update updates the value for the given key. An expired key is not found,
+// a present one keeps its expiration time.
 func (s *Tarantool) Update(key string, value any) error {
-	if _, err := s.Get(key); err != nil {
+	data, contentType, err := marshalValue(value)
+	if err != nil {
 		return err
 	}
+	t := s.newTuple(key, data, contentType)
+
+	return s.write(func(doer tarantool.Doer) error {
+		current, present, err := s.liveTuple(doer, key)
+		if err != nil {
+			return err
+		}
+		if !present {
+			return absentError(current)
+		}
 
-	jsonValue, err := json.Marshal(value)
+		if err := s.store(doer, &t, current.Chunks, false); err != nil {
+			return err
+		}
+
+		return s.recordRevision(doer, key, &t)
+	})
+}
+
+// Upsert sets the value for the given key whether the key is present or not
+// and reports whether the key was created. The key is read and written in a
+// transaction, so of the concurrent upserts of a new key only one creates
+// it. An expired key is created anew without its expiration time.
+func (s *Tarantool) Upsert(key string, value any) (bool, error) {
+	data, contentType, err := marshalValue(value)
 	if err != nil {
-		return err
+		return false, err
 	}
+	t := s.newTuple(key, data, contentType)
 
-	req := tarantool.NewReplaceRequest(s.space).Tuple([]any{key, string(jsonValue)})
-	if _, err := s.conn.Do(req).Get(); err != nil {
-		return err
+	var created bool
+	err = s.inTx(func(doer tarantool.Doer) error {
+		current, present, err := s.liveTuple(doer, key)
+		if err != nil {
+			return err
+		}
+		// A tombstone or an expired value is overwritten, the key is created
+		// anew.
+		created = !present
+
+		if err := s.store(doer, &t, current.Chunks, false); err != nil {
+			return err
+		}
+		if err := s.recordRevision(doer, key, &t); err != nil {
+			return err
+		}
+
+		if created {
+			return s.persist(doer, key)
+		}
+		return nil
+	})
+	return created, err
+}
+
+// SetMode is the condition SetWithOptions sets the value on.
+type SetMode int
+
+const (
+	// SetAlways sets the value whether the key is present or not.
+	SetAlways SetMode = iota
+	// SetIfAbsent sets the value only if the key is not present.
+	SetIfAbsent
+	// SetIfPresent sets the value only if the key is present.
+	SetIfPresent
+)
+
+// SetOptions are the options of SetWithOptions.
+type SetOptions struct {
+	// Mode is the condition the value is set on.
+	Mode SetMode
+	// TTL is the time from now the key expires in, the expiration time of
+	// the key is removed if it is zero unless KeepTTL is set.
+	TTL time.Duration
+	// KeepTTL keeps the expiration time of a present key.
+	KeepTTL bool
+	// Version, if set, is the version the value of the present key is
+	// expected to have, see GetWithVersion.
+	Version uint64
+}
+
+// SetWithOptions sets the value for the given key on the condition of the
+// mode along with its expiration time and reports whether the key was
+// created. ErrKeyAlreadyExists is returned if the key is present and the
+// mode is SetIfAbsent, ErrKeyNotFound if it is not and the mode is
+// SetIfPresent. With the version set the value is compared and swapped:
+// ErrKeyNotFound is returned if the key is not present and
+// ErrVersionMismatch if its version is not the expected one. The value and
+// its expiration time are written in a transaction, an expired key is taken
+// as not present.
+func (s *Tarantool) SetWithOptions(key string, value any, opts SetOptions) (bool, error) {
+	if opts.TTL != 0 && s.expirySpace == "" {
+		return false, ErrExpiryDisabled
 	}
 
-	return nil
+	data, contentType, err := marshalValue(value)
+	if err != nil {
+		return false, err
+	}
+	t := s.newTuple(key, data, contentType)
+
+	var created bool
+	err = s.inTx(func(doer tarantool.Doer) error {
+		current, present, err := s.liveTuple(doer, key)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case present && opts.Mode == SetIfAbsent:
+			return ErrKeyAlreadyExists
+		case !present && (opts.Mode == SetIfPresent || opts.Version != 0):
+			return ErrKeyNotFound
+		}
+		if opts.Version != 0 {
+			version, err := s.currentVersion(doer, current)
+			if err != nil {
+				return err
+			}
+			if version != opts.Version {
+				return ErrVersionMismatch
+			}
+		}
+		created = !present
+
+		if err := s.store(doer, &t, current.Chunks, false); err != nil {
+			return err
+		}
+		if err := s.recordRevision(doer, key, &t); err != nil {
+			return err
+		}
+
+		switch {
+		case opts.TTL != 0:
+			expiresAt := timeToUnixFloat(time.Now().Add(opts.TTL))
+			req := tarantool.NewReplaceRequest(s.expirySpace).Tuple([]any{key, expiresAt})
+			_, err := doer.Do(req).Get()
+			return err
+		case opts.KeepTTL && present:
+			return nil
+		default:
+			return s.persist(doer, key)
+		}
+	})
+	return created, err
+}
+
+// Modify replaces the value for the given key with the one fn returns for
+// the current value. The value is read and replaced in a transaction, so
+// the modifications made concurrently are not lost. A transaction aborted by
+// a concurrent one is retried up to modifyAttempts times, rereading the
+// value and calling fn again, ErrConflict is returned if it still fails. The
+// value is left as it is if fn returns an error, which Modify returns.
+func (s *Tarantool) Modify(key string, fn func(value any) (any, error)) error {
+	return retryConflicts(modifyAttempts, func() error {
+		return s.modify(key, fn)
+	})
+}
+
+// retryConflicts calls fn up to attempts times while it fails with
+// ErrConflict.
+func retryConflicts(attempts int, fn func() error) error {
+	var err error
+	for range attempts {
+		if err = fn(); !errors.Is(err, ErrConflict) {
+			return err
+		}
+	}
+	return err
 }
 
-// Get retrieves the value for the given key.
+func (s *Tarantool) modify(key string, fn func(value any) (any, error)) error {
+	return s.inTx(func(doer tarantool.Doer) error {
+		current, err := s.getTuple(doer, key)
+		if err != nil {
+			return err
+		}
+		if current.deleted() {
+			return ErrKeyDeleted
+		}
+
+		data, err := s.readValue(doer, current)
+		if err != nil {
+			return err
+		}
+		value, err := unmarshalValue(data, current.ContentType)
+		if err != nil {
+			return err
+		}
+
+		if value, err = fn(value); err != nil {
+			return err
+		}
+		data, contentType, err := marshalValue(value)
+		if err != nil {
+			return err
+		}
+		t := s.newTuple(key, data, contentType)
+
+		if err := s.store(doer, &t, current.Chunks, false); err != nil {
+			return err
+		}
+
+		return s.recordRevision(doer, key, &t)
+	})
+}
+
+// Get retrieves the value for the given key. A value stored in chunks is
+// read in full, see OpenValue to stream it.
 func (s *Tarantool) Get(key string) (any, error) {
+	value, _, err := s.GetWithMetadata(key)
+	return value, err
+}
+
+// GetWithMetadata retrieves the value for the given key along with its
+// metadata, see Get. An expired key is not found.
+func (s *Tarantool) GetWithMetadata(key string) (any, Metadata, error) {
+	t, value, err := s.getValue(key)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	decoded, err := unmarshalValue(value, t.ContentType)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	meta := t.metadata()
+	meta.Version = t.version(value)
+	return decoded, meta, nil
+}
+
+// getValue returns the tuple of the live key along with its whole stored
+// value. The chunks of a value are read in a transaction to be consistent.
+func (s *Tarantool) getValue(key string) (kvTuple, string, error) {
+	t, err := s.getTuple(s.conn, key)
+	if err != nil {
+		return kvTuple{}, "", err
+	}
+	if t.deleted() {
+		return kvTuple{}, "", ErrKeyDeleted
+	}
+	if err := s.checkExpired(s.conn, key); err != nil {
+		return kvTuple{}, "", err
+	}
+	if !t.chunked() {
+		return t, t.Value, nil
+	}
+
+	var value string
+	err = s.inTx(func(doer tarantool.Doer) error {
+		if t, err = s.getTuple(doer, key); err != nil {
+			return err
+		}
+		if t.deleted() {
+			return ErrKeyDeleted
+		}
+		value, err = s.readValue(doer, t)
+		return err
+	})
+	if err != nil {
+		return kvTuple{}, "", err
+	}
+	return t, value, nil
+}
+
+// getTuple returns the tuple of the key, tombstones included.
+func (s *Tarantool) getTuple(doer tarantool.Doer, key string) (kvTuple, error) {
 	req := tarantool.NewSelectRequest(s.space).Index(s.index).Key(tarantool.StringKey{S: key})
-	resp, err := s.conn.Do(req).Get()
+	resp, err := doer.Do(req).Get()
 	if err != nil {
-		return nil, err
+		return kvTuple{}, storageError(err)
 	}
 
 	if len(resp) == 0 {
-		return nil, ErrKeyNotFound
+		return kvTuple{}, ErrKeyNotFound
 	}
 
-	row, ok := resp[0].([]any)
-	if !ok {
-		return nil, errors.New("cannot retrieve response row")
+	return s.decodeKVTuple(resp[0])
+}
+
+// liveTuple returns the tuple of the key, if there is one, and reports
+// whether the key is present: neither deleted nor expired. The tuple of a key
+// that is not present is returned for its chunks to be dropped.
+func (s *Tarantool) liveTuple(doer tarantool.Doer, key string) (kvTuple, bool, error) {
+	current, err := s.getTuple(doer, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return kvTuple{}, false, nil
+	}
+	if err != nil {
+		return kvTuple{}, false, err
+	}
+	if current.deleted() {
+		return current, false, nil
 	}
 
-	if len(row) != 2 {
-		return nil, ErrInvalidDataFormat
+	err = s.checkExpired(doer, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return current, false, nil
 	}
+	if err != nil {
+		return kvTuple{}, false, err
+	}
+	return current, true, nil
+}
 
-	valueBytes := []byte(row[1].(string))
-	var value any
-	if err := json.Unmarshal(valueBytes, &value); err != nil {
-		return nil, err
+// absentError returns the error for the key of the tuple that is not
+// present, see liveTuple.
+func absentError(t kvTuple) error {
+	if t.deleted() {
+		return ErrKeyDeleted
 	}
+	return ErrKeyNotFound
+}
+
+// store replaces the tuple of the key, or inserts it if insert is set,
+// splitting a value larger than the chunk size into chunks, and drops the
+// chunks left of the previous value. The value is marked as modified now and
+// given a new version unless the modification time and the version of the
+// tuple are set.
+func (s *Tarantool) store(doer tarantool.Doer, t *kvTuple, prevChunks int, insert bool) error {
+	if t.Version == 0 {
+		version, err := s.nextVersion(doer)
+		if err != nil {
+			return err
+		}
+		t.Version = version
+	}
+	if t.ModifiedAt == 0 {
+		t.ModifiedAt = timeToUnixFloat(time.Now())
+	}
+
+	var chunks []string
+	if s.chunkSize > 0 && len(t.Value) > s.chunkSize {
+		chunks = splitChunks(t.Value, s.chunkSize)
+	}
+	t.Chunks = len(chunks)
 
-	return value, nil
+	var req tarantool.Request = tarantool.NewReplaceRequest(s.space).Tuple(s.tuple(*t))
+	if insert {
+		req = tarantool.NewInsertRequest(s.space).Tuple(s.tuple(*t))
+	}
+	if _, err := doer.Do(req).Get(); err != nil {
+		return writeError(err)
+	}
+
+	if err := s.writeChunks(doer, t.Key, chunks); err != nil {
+		return err
+	}
+
+	return s.dropChunks(doer, t.Key, len(chunks), prevChunks)
 }
 
-// Delete deletes the value for the given key.
+// Delete deletes the value for the given key. In the soft delete mode a
+// tombstone is kept in place of the value.
 func (s *Tarantool) Delete(key string) error {
+	_, err := s.Remove(key)
+	return err
+}
+
+// Remove deletes the value for the given key as Delete does and reports
+// whether the key had a value. The key is checked and deleted at once, so
+// of the concurrent removals of a key only one reports it.
+func (s *Tarantool) Remove(key string) (bool, error) {
+	var removed bool
+	err := s.write(func(doer tarantool.Doer) error {
+		var err error
+		removed, err = s.remove(doer, key)
+		return err
+	})
+	return removed, err
+}
+
+func (s *Tarantool) remove(doer tarantool.Doer, key string) (bool, error) {
+	if s.softDelete {
+		t, err := s.getTuple(doer, key)
+		if errors.Is(err, ErrKeyNotFound) || (err == nil && t.deleted()) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		// The chunks of the value are kept along with the tombstone.
+		t.DeletedAt = timeToUnixFloat(time.Now())
+		req := tarantool.NewReplaceRequest(s.space).Tuple(s.tuple(t))
+		if _, err := doer.Do(req).Get(); err != nil {
+			return false, err
+		}
+
+		return true, s.recordRevision(doer, key, nil)
+	}
+
 	req := tarantool.NewDeleteRequest(s.space).Index(s.index).Key(tarantool.StringKey{S: key})
-	if _, err := s.conn.Do(req).Get(); err != nil {
+	resp, err := doer.Do(req).Get()
+	if err != nil {
+		return false, err
+	}
+
+	if len(resp) == 0 {
+		return false, nil
+	}
+
+	t, err := s.decodeKVTuple(resp[0])
+	if err != nil {
+		return false, err
+	}
+	if err := s.dropChunks(doer, key, 0, t.Chunks); err != nil {
+		return false, err
+	}
+
+	return true, s.recordRevision(doer, key, nil)
+}
+
+// Undelete restores the soft deleted key.
+func (s *Tarantool) Undelete(key string) error {
+	if !s.softDelete {
+		return ErrSoftDeleteDisabled
+	}
+
+	return s.inTx(func(doer tarantool.Doer) error {
+		t, err := s.getTuple(doer, key)
+		if err != nil {
+			return err
+		}
+
+		if !t.deleted() {
+			return ErrKeyNotDeleted
+		}
+
+		value, err := s.readValue(doer, t)
+		if err != nil {
+			return err
+		}
+
+		restored := s.newTuple(key, value, t.ContentType)
+		if err := s.store(doer, &restored, t.Chunks, false); err != nil {
+			return err
+		}
+
+		return s.recordRevision(doer, key, &restored)
+	})
+}
+
+// PurgeDeleted removes the tombstones of keys deleted before the given time
+// and returns the number of removed keys.
+func (s *Tarantool) PurgeDeleted(before time.Time) (int, error) {
+	cutoff := timeToUnixFloat(before)
+	purged := 0
+
+	for {
+		req := tarantool.NewSelectRequest(s.space).
+			Index(s.deletedIndex).
+			Iterator(tarantool.IterLt).
+			Key([]any{cutoff}).
+			Limit(purgeBatchSize)
+		resp, err := s.conn.Do(req).Get()
+		if err != nil {
+			return purged, err
+		}
+
+		for _, data := range resp {
+			t, err := s.decodeKVTuple(data)
+			if err != nil {
+				return purged, err
+			}
+
+			// The key may be undeleted or set again since it was selected.
+			err = s.inTx(func(doer tarantool.Doer) error {
+				current, err := s.getTuple(doer, t.Key)
+				if err != nil {
+					return err
+				}
+				if !current.deleted() || current.DeletedAt >= cutoff {
+					return ErrKeyNotDeleted
+				}
+
+				req := tarantool.NewDeleteRequest(s.space).Index(s.index).Key(tarantool.StringKey{S: t.Key})
+				if _, err := doer.Do(req).Get(); err != nil {
+					return err
+				}
+
+				return s.dropChunks(doer, t.Key, 0, current.Chunks)
+			})
+			switch {
+			case err == nil:
+				purged++
+			case errors.Is(err, ErrKeyNotFound), errors.Is(err, ErrKeyNotDeleted):
+			default:
+				return purged, err
+			}
+		}
+
+		if len(resp) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// write runs fn against the connection or, if fn reads and writes or writes
+// more than one space, inside a transaction.
+func (s *Tarantool) write(fn func(doer tarantool.Doer) error) error {
+	if s.historyDepth <= 0 && !s.softDelete && s.chunkSize <= 0 && s.expirySpace == "" {
+		return storageError(fn(s.conn))
+	}
+	return s.inTx(fn)
+}
+
+// inTx runs fn inside a transaction of a new stream.
+func (s *Tarantool) inTx(fn func(doer tarantool.Doer) error) error {
+	stream, err := s.conn.NewStream()
+	if err != nil {
+		return storageError(err)
+	}
+	return runTx(stream, fn)
+}
+
+// runTx runs fn inside a transaction of the stream, rolled back if fn fails.
+// The errors are translated, including the conflicts the commit fails with.
+func runTx(stream tarantool.Doer, fn func(doer tarantool.Doer) error) error {
+	if _, err := stream.Do(tarantool.NewBeginRequest()).Get(); err != nil {
+		return storageError(err)
+	}
+
+	if err := fn(stream); err != nil {
+		_, _ = stream.Do(tarantool.NewRollbackRequest()).Get()
+		return storageError(err)
+	}
+
+	_, err := stream.Do(tarantool.NewCommitRequest()).Get()
+	return storageError(err)
+}
+
+// writeError translates the error of a write to the KV space. A duplicate
+// key is reported only for the KV space, the duplicates in the other spaces
+// are not the ones of the keys.
+func writeError(err error) error {
+	switch {
+	case checkDuplicateError(err):
+		return fmt.Errorf("%w: %w", ErrKeyAlreadyExists, err)
+	case checkFieldTypeError(err):
+		return ErrFieldTypeMismatch
+	case checkWrongTypeError(err):
+		return ErrWrongType
+	default:
+		return storageError(err)
+	}
+}
+
+// storageError translates the errors of Tarantool and of the connection to
+// it into the errors of the storage, wrapping the original one. Other
+// errors, translated ones included, are returned as they are. Duplicate keys
+// are translated by writeError only.
+func storageError(err error) error {
+	if err == nil || translated(err) {
+		return err
+	}
+
+	var tntErr tarantool.Error
+	if errors.As(err, &tntErr) {
+		switch tntErr.Code {
+		case iproto.ER_TRANSACTION_CONFLICT:
+			return fmt.Errorf("%w: %w", ErrConflict, err)
+		case iproto.ER_READONLY, iproto.ER_LOADING:
+			return fmt.Errorf("%w: %w", ErrReadOnly, err)
+		case iproto.ER_NO_SUCH_SPACE, iproto.ER_NO_SUCH_INDEX_ID, iproto.ER_NO_SUCH_INDEX_NAME:
+			return fmt.Errorf("%w: %w", ErrSpaceNotFound, err)
+		case iproto.ER_MEMORY_ISSUE:
+			return fmt.Errorf("%w: %w", ErrOutOfMemory, err)
+		case iproto.ER_TIMEOUT:
+			return fmt.Errorf("%w: %w", ErrTimeout, err)
+		}
 		return err
 	}
-	return nil
+
+	var clientErr tarantool.ClientError
+	if errors.As(err, &clientErr) {
+		switch clientErr.Code {
+		case tarantool.ErrTimeouted:
+			return fmt.Errorf("%w: %w", ErrTimeout, err)
+		case tarantool.ErrConnectionNotReady, tarantool.ErrConnectionClosed,
+			tarantool.ErrConnectionShutdown, tarantool.ErrRateLimited, tarantool.ErrIoError:
+			return fmt.Errorf("%w: %w", ErrUnavailable, err)
+		}
+	}
+	return err
+}
+
+func translated(err error) bool {
+	for _, target := range []error{ErrKeyAlreadyExists, ErrConflict, ErrUnavailable, ErrReadOnly, ErrSpaceNotFound, ErrOutOfMemory, ErrTimeout} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkDuplicateError(err error) bool {
+	var tntErr tarantool.Error
+	return errors.As(err, &tntErr) && tntErr.Code == iproto.ER_TUPLE_FOUND
 }
 
-func checkDuplicateKeyError(err error) bool {
-	return strings.Contains(err.Error(), "Duplicate key exists")
+func checkFieldTypeError(err error) bool {
+	var tntErr tarantool.Error
+	return errors.As(err, &tntErr) && tntErr.Code == iproto.ER_FIELD_TYPE
 }
@@ -0,0 +1,273 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tarantool/go-iproto"
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+func TestStorageError(t *testing.T) {
+	other := errors.New("other")
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"nil", nil, nil},
+		{"duplicate key", tarantool.Error{Code: iproto.ER_TUPLE_FOUND}, tarantool.Error{Code: iproto.ER_TUPLE_FOUND}},
+		{"transaction conflict", tarantool.Error{Code: iproto.ER_TRANSACTION_CONFLICT}, ErrConflict},
+		{"read-only", tarantool.Error{Code: iproto.ER_READONLY}, ErrReadOnly},
+		{"no space", fmt.Errorf("select: %w", tarantool.Error{Code: iproto.ER_NO_SUCH_SPACE}), ErrSpaceNotFound},
+		{"no index", tarantool.Error{Code: iproto.ER_NO_SUCH_INDEX_NAME}, ErrSpaceNotFound},
+		{"out of memory", tarantool.Error{Code: iproto.ER_MEMORY_ISSUE}, ErrOutOfMemory},
+		{"server timeout", tarantool.Error{Code: iproto.ER_TIMEOUT}, ErrTimeout},
+		{"client timeout", tarantool.ClientError{Code: tarantool.ErrTimeouted}, ErrTimeout},
+		{"connection closed", tarantool.ClientError{Code: tarantool.ErrConnectionClosed}, ErrUnavailable},
+		{"not connected", tarantool.ClientError{Code: tarantool.ErrConnectionNotReady}, ErrUnavailable},
+		{"translated", fmt.Errorf("%w: %w", ErrTimeout, tarantool.ClientError{Code: tarantool.ErrTimeouted}), ErrTimeout},
+		{"other", other, other},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := storageError(tt.err)
+			if tt.want == nil {
+				assert.NoError(t, got)
+				return
+			}
+			assert.ErrorIs(t, got, tt.want)
+		})
+	}
+
+	cause := tarantool.ClientError{Code: tarantool.ErrTimeouted, Msg: "timeout"}
+	translated := storageError(cause)
+	assert.ErrorIs(t, translated, cause)
+	assert.Equal(t, translated, storageError(translated))
+}
+
+func TestWriteError(t *testing.T) {
+	duplicate := tarantool.Error{Code: iproto.ER_TUPLE_FOUND, Msg: "Duplicate key exists"}
+	err := writeError(duplicate)
+	assert.ErrorIs(t, err, ErrKeyAlreadyExists)
+	assert.ErrorIs(t, err, duplicate, "cause must be kept")
+	assert.Equal(t, err, storageError(err))
+
+	assert.ErrorIs(t, writeError(tarantool.Error{Code: iproto.ER_FIELD_TYPE}), ErrFieldTypeMismatch)
+	assert.ErrorIs(t, writeError(tarantool.Error{Code: iproto.ER_TRANSACTION_CONFLICT}), ErrConflict)
+}
+
+// txDoer answers the requests of a transaction, failing the ones of the
+// types in errs. Evals are answered with the next value of a sequence.
+type txDoer struct {
+	errs     map[iproto.Type]error
+	requests []iproto.Type
+	sequence uint64
+}
+
+func (d *txDoer) Do(req tarantool.Request) *tarantool.Future {
+	d.requests = append(d.requests, req.Type())
+	fut := tarantool.NewFuture(req)
+	if err, ok := d.errs[req.Type()]; ok {
+		fut.SetError(err)
+		return fut
+	}
+	// An empty body, the responses to the transaction requests have no data.
+	body := []byte{0x80}
+	if req.Type() == iproto.IPROTO_EVAL {
+		d.sequence++
+		// The body {IPROTO_DATA: [sequence]}.
+		body = binary.BigEndian.AppendUint64([]byte{0x81, byte(iproto.IPROTO_DATA), 0x91, 0xcf}, d.sequence)
+	}
+	if err := fut.SetResponse(tarantool.Header{}, bytes.NewReader(body)); err != nil {
+		fut.SetError(err)
+	}
+	return fut
+}
+
+func TestRunTx(t *testing.T) {
+	conflict := tarantool.Error{Code: iproto.ER_TRANSACTION_CONFLICT, Msg: "Transaction has been aborted by conflict"}
+	closed := tarantool.ClientError{Code: tarantool.ErrConnectionClosed, Msg: "connection closed"}
+
+	tests := []struct {
+		name     string
+		errs     map[iproto.Type]error
+		fnErr    error
+		want     error
+		requests []iproto.Type
+	}{
+		{
+			name:     "committed",
+			requests: []iproto.Type{iproto.IPROTO_BEGIN, iproto.IPROTO_COMMIT},
+		},
+		{
+			name:     "conflict on commit",
+			errs:     map[iproto.Type]error{iproto.IPROTO_COMMIT: conflict},
+			want:     ErrConflict,
+			requests: []iproto.Type{iproto.IPROTO_BEGIN, iproto.IPROTO_COMMIT},
+		},
+		{
+			name:     "connection closed on begin",
+			errs:     map[iproto.Type]error{iproto.IPROTO_BEGIN: closed},
+			want:     ErrUnavailable,
+			requests: []iproto.Type{iproto.IPROTO_BEGIN},
+		},
+		{
+			name:     "conflict in transaction",
+			fnErr:    conflict,
+			want:     ErrConflict,
+			requests: []iproto.Type{iproto.IPROTO_BEGIN, iproto.IPROTO_ROLLBACK},
+		},
+		{
+			name:     "storage error in transaction",
+			fnErr:    ErrKeyNotFound,
+			want:     ErrKeyNotFound,
+			requests: []iproto.Type{iproto.IPROTO_BEGIN, iproto.IPROTO_ROLLBACK},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doer := &txDoer{errs: tt.errs}
+
+			err := runTx(doer, func(tarantool.Doer) error { return tt.fnErr })
+			if tt.want == nil {
+				require.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.want)
+			}
+			assert.Equal(t, tt.requests, doer.requests)
+		})
+	}
+}
+
+func TestTarantool_store_versions(t *testing.T) {
+	s := &Tarantool{space: "kv", versionSeq: "kv_versions"}
+	doer := &txDoer{}
+
+	first := s.newTuple("k", `"v"`, "")
+	require.NoError(t, s.store(doer, &first, 0, false))
+	second := s.newTuple("k", `"v"`, "")
+	require.NoError(t, s.store(doer, &second, 0, false))
+	assert.Equal(t, uint64(1), first.Version)
+	assert.Equal(t, uint64(2), second.Version, "setting the value back must give a new version")
+	assert.Equal(t, []iproto.Type{
+		iproto.IPROTO_EVAL, iproto.IPROTO_REPLACE,
+		iproto.IPROTO_EVAL, iproto.IPROTO_REPLACE,
+	}, doer.requests)
+
+	kept := s.newTuple("k", `"v"`, "")
+	kept.Version = 7
+	require.NoError(t, s.store(doer, &kept, 0, false))
+	assert.Equal(t, uint64(7), kept.Version)
+	assert.Equal(t, iproto.IPROTO_REPLACE, doer.requests[len(doer.requests)-1])
+	assert.Len(t, doer.requests, 5, "a set version must be kept")
+}
+
+func TestRetryConflicts(t *testing.T) {
+	conflict := fmt.Errorf("%w: aborted", ErrConflict)
+
+	t.Run("succeeds after conflicts", func(t *testing.T) {
+		calls := 0
+		err := retryConflicts(3, func() error {
+			calls++
+			if calls < 3 {
+				return conflict
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		calls := 0
+		err := retryConflicts(3, func() error {
+			calls++
+			return conflict
+		})
+		assert.ErrorIs(t, err, ErrConflict)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("other errors are not retried", func(t *testing.T) {
+		calls := 0
+		err := retryConflicts(3, func() error {
+			calls++
+			return ErrKeyNotFound
+		})
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestTarantool_Undelete_disabled(t *testing.T) {
+	s := &Tarantool{}
+	assert.ErrorIs(t, s.Undelete("k"), ErrSoftDeleteDisabled)
+}
+
+// selectsDoer answers the requests with the tuples of the responses in turn.
+type selectsDoer struct {
+	responses [][]any
+}
+
+func (d *selectsDoer) Do(req tarantool.Request) *tarantool.Future {
+	tuples := d.responses[0]
+	d.responses = d.responses[1:]
+	return selectDoer{tuples: tuples}.Do(req)
+}
+
+func TestTarantool_liveTuple(t *testing.T) {
+	now := time.Now()
+	live := []any{"k", `"v"`}
+	tests := []struct {
+		name        string
+		expirySpace string
+		responses   [][]any
+		wantPresent bool
+		wantErr     error
+	}{
+		{name: "absent", expirySpace: "kv_expiry", responses: [][]any{{}}, wantErr: ErrKeyNotFound},
+		{
+			name:        "deleted",
+			expirySpace: "kv_expiry",
+			responses:   [][]any{{[]any{"k", `"v"`, 1.0}}},
+			wantErr:     ErrKeyDeleted,
+		},
+		{
+			name:        "expired",
+			expirySpace: "kv_expiry",
+			responses:   [][]any{{live}, {[]any{"k", timeToUnixFloat(now.Add(-time.Second))}}},
+			wantErr:     ErrKeyNotFound,
+		},
+		{
+			name:        "expires later",
+			expirySpace: "kv_expiry",
+			responses:   [][]any{{live}, {[]any{"k", timeToUnixFloat(now.Add(time.Minute))}}},
+			wantPresent: true,
+		},
+		{name: "no expiry", expirySpace: "kv_expiry", responses: [][]any{{live}, {}}, wantPresent: true},
+		{name: "expiry disabled", responses: [][]any{{live}}, wantPresent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Tarantool{space: "kv", expirySpace: tt.expirySpace}
+
+			current, present, err := s.liveTuple(&selectsDoer{responses: tt.responses}, "k")
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantPresent, present)
+			if !present && tt.wantErr != nil {
+				assert.ErrorIs(t, absentError(current), tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+// ErrVersionMismatch is returned when the value of the key has changed since
+// the expected version was read.
+var ErrVersionMismatch = errors.New("value version mismatch")
+
+// GetWithVersion returns the value of the key along with its version. Every
+// write of a value gives it a new version taken from a sequence, so setting
+// a value back does not give back its version. The values stored before the
+// versions were kept have the version derived from their content. A value
+// stored in chunks is read in full.
+func (s *Tarantool) GetWithVersion(key string) (any, uint64, error) {
+	t, value, err := s.getValue(key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	decoded, err := unmarshalValue(value, t.ContentType)
+	if err != nil {
+		return nil, 0, err
+	}
+	return decoded, t.version(value), nil
+}
+
+// CompareAndSwap updates the value of the key if its current version is the
+// expected one, see GetWithVersion. An expired key is not found, a present
+// one keeps its expiration time.
+func (s *Tarantool) CompareAndSwap(key string, version uint64, value any) error {
+	data, contentType, err := marshalValue(value)
+	if err != nil {
+		return err
+	}
+	t := s.newTuple(key, data, contentType)
+
+	return s.inTx(func(doer tarantool.Doer) error {
+		current, present, err := s.liveTuple(doer, key)
+		if err != nil {
+			return err
+		}
+		if !present {
+			return absentError(current)
+		}
+
+		currentVersion, err := s.currentVersion(doer, current)
+		if err != nil {
+			return err
+		}
+		if currentVersion != version {
+			return ErrVersionMismatch
+		}
+
+		if err := s.store(doer, &t, current.Chunks, false); err != nil {
+			return err
+		}
+
+		return s.recordRevision(doer, key, &t)
+	})
+}
+
+// currentVersion returns the version of the stored tuple, reading its value
+// if it was stored before the versions were kept.
+func (s *Tarantool) currentVersion(doer tarantool.Doer, t kvTuple) (uint64, error) {
+	if t.Version != 0 {
+		return t.Version, nil
+	}
+	value, err := s.readValue(doer, t)
+	if err != nil {
+		return 0, err
+	}
+	return t.version(value), nil
+}
+
+// nextVersion returns the next version from the sequence of versions.
+func (s *Tarantool) nextVersion(doer tarantool.Doer) (uint64, error) {
+	req := tarantool.NewEvalRequest("return box.sequence[...]:next()").Args([]any{s.versionSeq})
+
+	var version []uint64
+	if err := doer.Do(req).GetTyped(&version); err != nil {
+		return 0, storageError(err)
+	}
+	if len(version) == 0 || version[0] == 0 {
+		return 0, fmt.Errorf("%w: no version from sequence %s", ErrInvalidDataFormat, s.versionSeq)
+	}
+	return version[0], nil
+}
+
+// version returns the version of the tuple with the given plaintext value,
+// the stored one or, for the values stored before the versions were kept,
+// the one derived from the value.
+func (t kvTuple) version(value string) uint64 {
+	if t.Version != 0 {
+		return t.Version
+	}
+	return valueVersion(value, t.ContentType)
+}
+
+// valueVersion returns the version of a value stored without one, the hash
+// of its content type and plaintext data.
+func valueVersion(value, contentType string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(contentType))
+	h.Write([]byte{0})
+	h.Write([]byte(value))
+	return h.Sum64()
+}
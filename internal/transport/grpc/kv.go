@@ -0,0 +1,297 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	kvv1 "github.com/tmybsv/tarantool-kv/api/proto/kv/v1"
+	"github.com/tmybsv/tarantool-kv/internal/schema"
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// Storage is the contract for the KV storage served over gRPC. Values are
+// either decoded JSON or storage.Blob for binary values.
+type Storage interface {
+	// Set sets the value for the key or an error if the key is already present.
+	Set(key string, value any) error
+	// Update updates the value for the key or an error if the key is not found.
+	Update(key string, value any) error
+	// Delete removes the key from the storage or an error if the key is not found.
+	Delete(key string) error
+	// Get returns the value for the key or an error if the key is not found.
+	Get(key string) (any, error)
+	// Scan returns up to limit keys following the after key.
+	Scan(after string, limit uint32) ([]string, error)
+}
+
+// EventSubscriber is the contract for the source of key change events.
+type EventSubscriber interface {
+	// Subscribe returns a channel of events with IDs greater than afterID or
+	// only new events if afterID is zero.
+	Subscribe(ctx context.Context, afterID uint64) (<-chan storage.Event, error)
+}
+
+// ValueValidator is the contract for validating values before they are
+// stored.
+type ValueValidator interface {
+	// Validate returns an error if the value is not valid for the key.
+	Validate(key string, value any) error
+}
+
+// service implements the KV gRPC service.
+type service struct {
+	kvv1.UnimplementedKVServer
+
+	log       *slog.Logger
+	storage   Storage
+	events    EventSubscriber
+	validator ValueValidator
+}
+
+func newService(log *slog.Logger, storage Storage, events EventSubscriber, validator ValueValidator) *service {
+	return &service{
+		log:       log,
+		storage:   storage,
+		events:    events,
+		validator: validator,
+	}
+}
+
+// Get returns the value of the key.
+func (s *service) Get(_ context.Context, req *kvv1.GetRequest) (*kvv1.GetResponse, error) {
+	value, err := s.storage.Get(req.GetKey())
+	if err != nil {
+		s.log.Error("failed to get key", slog.String("error", err.Error()))
+		return nil, storageStatus(err)
+	}
+
+	msg, err := valueMessage(value)
+	if err != nil {
+		s.log.Error("failed to read value", slog.String("error", err.Error()))
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &kvv1.GetResponse{Key: req.GetKey(), Value: msg}, nil
+}
+
+// Set sets the value of a new key.
+func (s *service) Set(_ context.Context, req *kvv1.SetRequest) (*kvv1.SetResponse, error) {
+	if req.GetKey() == "" {
+		return nil, status.Error(codes.InvalidArgument, "key cannot be empty")
+	}
+
+	value, err := s.value(req.GetKey(), req.GetValue())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.storage.Set(req.GetKey(), value); err != nil {
+		s.log.Error("failed to set key", slog.String("error", err.Error()))
+		return nil, storageStatus(err)
+	}
+
+	return &kvv1.SetResponse{Key: req.GetKey()}, nil
+}
+
+// Update updates the value of an existing key.
+func (s *service) Update(_ context.Context, req *kvv1.UpdateRequest) (*kvv1.UpdateResponse, error) {
+	value, err := s.value(req.GetKey(), req.GetValue())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.storage.Update(req.GetKey(), value); err != nil {
+		s.log.Error("failed to update key", slog.String("error", err.Error()))
+		return nil, storageStatus(err)
+	}
+
+	return &kvv1.UpdateResponse{Key: req.GetKey()}, nil
+}
+
+// Delete removes the key.
+func (s *service) Delete(_ context.Context, req *kvv1.DeleteRequest) (*kvv1.DeleteResponse, error) {
+	if err := s.storage.Delete(req.GetKey()); err != nil {
+		s.log.Error("failed to delete key", slog.String("error", err.Error()))
+		return nil, storageStatus(err)
+	}
+
+	return &kvv1.DeleteResponse{Key: req.GetKey()}, nil
+}
+
+// List returns a page of keys. The page token is the last key of the
+// previous page.
+func (s *service) List(_ context.Context, req *kvv1.ListRequest) (*kvv1.ListResponse, error) {
+	pageSize := req.GetPageSize()
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		return nil, status.Error(codes.InvalidArgument, "page size must be at most 1000")
+	}
+
+	keys, err := s.storage.Scan(req.GetPageToken(), pageSize)
+	if err != nil {
+		s.log.Error("failed to list keys", slog.String("error", err.Error()))
+		return nil, storageStatus(err)
+	}
+
+	resp := &kvv1.ListResponse{Keys: keys}
+	if len(keys) == int(pageSize) {
+		resp.NextPageToken = keys[len(keys)-1]
+	}
+	return resp, nil
+}
+
+// Watch streams the changes of the key or of the keys starting with the
+// prefix. The stream fails with Unavailable if it falls too far behind or
+// the server is stopping, the client is expected to watch again after the
+// last event received.
+func (s *service) Watch(req *kvv1.WatchRequest, stream kvv1.KV_WatchServer) error {
+	match := func(key string) bool { return strings.HasPrefix(key, req.GetPrefix()) }
+	if target, ok := req.GetTarget().(*kvv1.WatchRequest_Key); ok {
+		match = func(key string) bool { return key == target.Key }
+	}
+
+	ctx := stream.Context()
+	events, err := s.events.Subscribe(ctx, req.GetAfterEventId())
+	if err != nil {
+		s.log.Error("failed to subscribe to events", slog.String("error", err.Error()))
+		return status.Error(codes.Unavailable, "events are unavailable")
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case event, ok := <-events:
+			if !ok {
+				if ctx.Err() != nil {
+					return status.FromContextError(ctx.Err()).Err()
+				}
+				return status.Error(codes.Unavailable, "event stream interrupted")
+			}
+			if !match(event.Key) {
+				continue
+			}
+
+			msg, err := eventMessage(event)
+			if err != nil {
+				s.log.Error("failed to convert event", slog.String("error", err.Error()))
+				return status.Error(codes.Internal, "internal error")
+			}
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// value returns the value to store from the message, validating it.
+func (s *service) value(key string, msg *kvv1.Value) (any, error) {
+	var value any
+	switch {
+	case msg.GetBlob() != nil:
+		blob := msg.GetBlob()
+		value = storage.Blob{ContentType: blob.GetContentType(), Data: blob.GetData()}
+	case msg.GetJson() != nil:
+		value = msg.GetJson().AsInterface()
+	default:
+		return nil, status.Error(codes.InvalidArgument, "value cannot be empty")
+	}
+	if s.validator == nil {
+		return value, nil
+	}
+
+	err := s.validator.Validate(key, value)
+	if err == nil {
+		return value, nil
+	}
+
+	var verr *schema.ValidationError
+	if errors.As(err, &verr) {
+		return nil, status.Error(codes.InvalidArgument, verr.Error())
+	}
+
+	s.log.Error("failed to validate value", slog.String("error", err.Error()))
+	return nil, status.Error(codes.Internal, "internal error")
+}
+
+func storageStatus(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrKeyNotFound), errors.Is(err, storage.ErrKeyDeleted):
+		return status.Error(codes.NotFound, "key not found")
+	case errors.Is(err, storage.ErrKeyAlreadyExists):
+		return status.Error(codes.AlreadyExists, "key already exists")
+	case errors.Is(err, storage.ErrWrongType):
+		return status.Error(codes.FailedPrecondition, "key holds a value of another type")
+	case errors.Is(err, storage.ErrFieldTypeMismatch):
+		return status.Error(codes.InvalidArgument, "value does not match indexed field type")
+	case errors.Is(err, storage.ErrConflict):
+		return status.Error(codes.Aborted, "transaction conflict")
+	case errors.Is(err, storage.ErrUnavailable), errors.Is(err, storage.ErrReadOnly),
+		errors.Is(err, storage.ErrSpaceNotFound):
+		return status.Error(codes.Unavailable, "storage unavailable")
+	case errors.Is(err, storage.ErrOutOfMemory):
+		return status.Error(codes.ResourceExhausted, "storage out of memory")
+	case errors.Is(err, storage.ErrTimeout):
+		return status.Error(codes.DeadlineExceeded, "storage timeout")
+	default:
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+// valueMessage returns the message of the stored value.
+func valueMessage(value any) (*kvv1.Value, error) {
+	switch value := value.(type) {
+	case storage.Blob:
+		return blobMessage(value.ContentType, value.Data), nil
+	default:
+		msg, err := structpb.NewValue(value)
+		if err != nil {
+			return nil, err
+		}
+		return &kvv1.Value{Kind: &kvv1.Value_Json{Json: msg}}, nil
+	}
+}
+
+func blobMessage(contentType string, data []byte) *kvv1.Value {
+	return &kvv1.Value{Kind: &kvv1.Value_Blob{Blob: &kvv1.Blob{
+		ContentType: contentType,
+		Data:        data,
+	}}}
+}
+
+var eventTypes = map[string]kvv1.WatchEvent_Type{
+	storage.EventSet:    kvv1.WatchEvent_TYPE_SET,
+	storage.EventUpdate: kvv1.WatchEvent_TYPE_UPDATE,
+	storage.EventDelete: kvv1.WatchEvent_TYPE_DELETE,
+}
+
+func eventMessage(event storage.Event) (*kvv1.WatchEvent, error) {
+	msg := &kvv1.WatchEvent{
+		Id:   event.ID,
+		Type: eventTypes[event.Type],
+		Key:  event.Key,
+		Time: timestamppb.New(event.Time),
+	}
+	if event.Type != storage.EventDelete {
+		value, err := structpb.NewValue(event.Value)
+		if err != nil {
+			return nil, err
+		}
+		msg.Value = value
+	}
+	return msg, nil
+}
@@ -0,0 +1,302 @@
+package grpc
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	kvv1 "github.com/tmybsv/tarantool-kv/api/proto/kv/v1"
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+type MockStorage struct {
+	mock.Mock
+}
+
+func (m *MockStorage) Set(key string, value any) error {
+	args := m.Called(key, value)
+	return args.Error(0)
+}
+
+func (m *MockStorage) Update(key string, value any) error {
+	args := m.Called(key, value)
+	return args.Error(0)
+}
+
+func (m *MockStorage) Delete(key string) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
+func (m *MockStorage) Get(key string) (any, error) {
+	args := m.Called(key)
+	return args.Get(0), args.Error(1)
+}
+
+func (m *MockStorage) Scan(after string, limit uint32) ([]string, error) {
+	args := m.Called(after, limit)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+type fakeEvents struct {
+	events []storage.Event
+}
+
+func (f *fakeEvents) Subscribe(ctx context.Context, afterID uint64) (<-chan storage.Event, error) {
+	ch := make(chan storage.Event, len(f.events))
+	for _, event := range f.events {
+		if event.ID > afterID {
+			ch <- event
+		}
+	}
+	close(ch)
+	return ch, nil
+}
+
+func newTestService(st Storage, events EventSubscriber) *service {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	return newService(logger, st, events, nil)
+}
+
+func jsonValue(t *testing.T, v any) *kvv1.Value {
+	t.Helper()
+
+	msg, err := structpb.NewValue(v)
+	require.NoError(t, err)
+	return &kvv1.Value{Kind: &kvv1.Value_Json{Json: msg}}
+}
+
+func TestService_Get(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockSetup func(*MockStorage)
+		want      *kvv1.Value
+		wantCode  codes.Code
+	}{
+		{
+			name: "json value",
+			mockSetup: func(m *MockStorage) {
+				m.On("Get", "k").Return(map[string]any{"a": float64(1)}, nil)
+			},
+			want: jsonValue(t, map[string]any{"a": float64(1)}),
+		},
+		{
+			name: "blob value",
+			mockSetup: func(m *MockStorage) {
+				m.On("Get", "k").Return(storage.Blob{ContentType: "image/png", Data: []byte{1}}, nil)
+			},
+			want: &kvv1.Value{Kind: &kvv1.Value_Blob{Blob: &kvv1.Blob{ContentType: "image/png", Data: []byte{1}}}},
+		},
+		{
+			name: "missing key",
+			mockSetup: func(m *MockStorage) {
+				m.On("Get", "k").Return(nil, storage.ErrKeyNotFound)
+			},
+			wantCode: codes.NotFound,
+		},
+		{
+			name: "storage error",
+			mockSetup: func(m *MockStorage) {
+				m.On("Get", "k").Return(nil, assert.AnError)
+			},
+			wantCode: codes.Internal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &MockStorage{}
+			tt.mockSetup(st)
+			s := newTestService(st, nil)
+
+			resp, err := s.Get(context.Background(), &kvv1.GetRequest{Key: "k"})
+			if tt.wantCode != codes.OK {
+				assert.Equal(t, tt.wantCode, status.Code(err))
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "k", resp.GetKey())
+				assert.Equal(t, tt.want.String(), resp.GetValue().String())
+			}
+			st.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_Set(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       *kvv1.SetRequest
+		mockSetup func(*MockStorage)
+		wantCode  codes.Code
+	}{
+		{
+			name: "json value",
+			req:  &kvv1.SetRequest{Key: "k", Value: jsonValue(t, "v")},
+			mockSetup: func(m *MockStorage) {
+				m.On("Set", "k", "v").Return(nil)
+			},
+		},
+		{
+			name: "blob value",
+			req: &kvv1.SetRequest{Key: "k", Value: &kvv1.Value{Kind: &kvv1.Value_Blob{
+				Blob: &kvv1.Blob{ContentType: "image/png", Data: []byte{1}},
+			}}},
+			mockSetup: func(m *MockStorage) {
+				m.On("Set", "k", storage.Blob{ContentType: "image/png", Data: []byte{1}}).Return(nil)
+			},
+		},
+		{
+			name:      "empty key",
+			req:       &kvv1.SetRequest{Value: jsonValue(t, "v")},
+			mockSetup: func(m *MockStorage) {},
+			wantCode:  codes.InvalidArgument,
+		},
+		{
+			name:      "empty value",
+			req:       &kvv1.SetRequest{Key: "k"},
+			mockSetup: func(m *MockStorage) {},
+			wantCode:  codes.InvalidArgument,
+		},
+		{
+			name: "existing key",
+			req:  &kvv1.SetRequest{Key: "k", Value: jsonValue(t, "v")},
+			mockSetup: func(m *MockStorage) {
+				m.On("Set", "k", "v").Return(storage.ErrKeyAlreadyExists)
+			},
+			wantCode: codes.AlreadyExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &MockStorage{}
+			tt.mockSetup(st)
+			s := newTestService(st, nil)
+
+			_, err := s.Set(context.Background(), tt.req)
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			st.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_List(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       *kvv1.ListRequest
+		mockSetup func(*MockStorage)
+		want      *kvv1.ListResponse
+		wantCode  codes.Code
+	}{
+		{
+			name: "full page",
+			req:  &kvv1.ListRequest{PageSize: 2, PageToken: "a"},
+			mockSetup: func(m *MockStorage) {
+				m.On("Scan", "a", uint32(2)).Return([]string{"b", "c"}, nil)
+			},
+			want: &kvv1.ListResponse{Keys: []string{"b", "c"}, NextPageToken: "c"},
+		},
+		{
+			name: "last page",
+			req:  &kvv1.ListRequest{},
+			mockSetup: func(m *MockStorage) {
+				m.On("Scan", "", uint32(defaultPageSize)).Return([]string{"a"}, nil)
+			},
+			want: &kvv1.ListResponse{Keys: []string{"a"}},
+		},
+		{
+			name:      "page size too large",
+			req:       &kvv1.ListRequest{PageSize: maxPageSize + 1},
+			mockSetup: func(m *MockStorage) {},
+			wantCode:  codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &MockStorage{}
+			tt.mockSetup(st)
+			s := newTestService(st, nil)
+
+			resp, err := s.List(context.Background(), tt.req)
+			if tt.wantCode != codes.OK {
+				assert.Equal(t, tt.wantCode, status.Code(err))
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want.GetKeys(), resp.GetKeys())
+				assert.Equal(t, tt.want.GetNextPageToken(), resp.GetNextPageToken())
+			}
+			st.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServer_Serve(t *testing.T) {
+	st := &MockStorage{}
+	st.On("Update", "user:1", "v").Return(nil)
+	events := &fakeEvents{events: []storage.Event{
+		{ID: 1, Type: storage.EventSet, Key: "user:1", Value: "v", Time: time.Unix(1, 0)},
+		{ID: 2, Type: storage.EventSet, Key: "order:1", Value: "v", Time: time.Unix(2, 0)},
+		{ID: 3, Type: storage.EventDelete, Key: "user:1", Time: time.Unix(3, 0)},
+	}}
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	s := NewServer(logger, "127.0.0.1:0", st, events, nil)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(listener) }()
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := kvv1.NewKVClient(conn)
+
+	_, err = client.Update(context.Background(), &kvv1.UpdateRequest{Key: "user:1", Value: jsonValue(t, "v")})
+	require.NoError(t, err)
+
+	stream, err := client.Watch(context.Background(), &kvv1.WatchRequest{
+		Target:       &kvv1.WatchRequest_Prefix{Prefix: "user:"},
+		AfterEventId: 0,
+	})
+	require.NoError(t, err)
+
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), event.GetId())
+	assert.Equal(t, kvv1.WatchEvent_TYPE_SET, event.GetType())
+	assert.Equal(t, "v", event.GetValue().GetStringValue())
+
+	event, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), event.GetId())
+	assert.Equal(t, kvv1.WatchEvent_TYPE_DELETE, event.GetType())
+	assert.Nil(t, event.GetValue())
+
+	// The fake subscription ends after the events, as a dropped one does.
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, s.Shutdown(ctx))
+	assert.ErrorIs(t, <-served, ErrServerClosed)
+	st.AssertExpectations(t)
+}
@@ -0,0 +1,127 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	kvv1 "github.com/tmybsv/tarantool-kv/api/proto/kv/v1"
+)
+
+// ErrServerClosed is returned by ListenAndServe after Shutdown.
+var ErrServerClosed = errors.New("grpc: server closed")
+
+// Server serves the KV storage to gRPC clients.
+type Server struct {
+	addr   string
+	server *grpc.Server
+}
+
+// NewServer creates a new gRPC server listening on addr. Values are stored
+// without validation if validator is nil.
+func NewServer(log *slog.Logger, addr string, storage Storage, events EventSubscriber, validator ValueValidator) *Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryLogging(log)),
+		grpc.ChainStreamInterceptor(streamLogging(log)),
+	)
+	kvv1.RegisterKVServer(server, newService(log, storage, events, validator))
+
+	return &Server{
+		addr:   addr,
+		server: server,
+	}
+}
+
+// ListenAndServe listens on the address of the server and serves the
+// connections until Shutdown is called.
+func (s *Server) ListenAndServe() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve serves the connections accepted by the listener until Shutdown is
+// called.
+func (s *Server) Serve(listener net.Listener) error {
+	// Serve returns nil once the server is stopped.
+	err := s.server.Serve(listener)
+	if err == nil || errors.Is(err, grpc.ErrServerStopped) {
+		return ErrServerClosed
+	}
+	return err
+}
+
+// Shutdown stops accepting connections and closes the open ones once their
+// current calls are served or ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return ctx.Err()
+	}
+}
+
+// unaryLogging returns an interceptor logging the calls the way the HTTP
+// logging middleware logs the requests.
+func unaryLogging(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := logStarted(ctx, log, info.FullMethod)
+		resp, err := handler(ctx, req)
+		logCompleted(log, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// streamLogging returns an interceptor logging the streams the way the HTTP
+// logging middleware logs the requests.
+func streamLogging(log *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := logStarted(ss.Context(), log, info.FullMethod)
+		err := handler(srv, ss)
+		logCompleted(log, info.FullMethod, start, err)
+		return err
+	}
+}
+
+func logStarted(ctx context.Context, log *slog.Logger, method string) time.Time {
+	var remoteAddr, userAgent string
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		userAgent = strings.Join(md.Get("user-agent"), " ")
+	}
+
+	log.Info("request started",
+		slog.String("method", method),
+		slog.String("remote_addr", remoteAddr),
+		slog.String("user_agent", userAgent),
+	)
+	return time.Now()
+}
+
+func logCompleted(log *slog.Logger, method string, start time.Time, err error) {
+	log.Info("request completed",
+		slog.String("status", status.Code(err).String()),
+		slog.String("method", method),
+		slog.String("duration", time.Since(start).String()),
+	)
+}
@@ -2,8 +2,14 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
+
+	"github.com/tmybsv/tarantool-kv/internal/storage"
 )
 
 type successResponse struct {
@@ -12,19 +18,65 @@ type successResponse struct {
 	Details any    `json:"details,omitempty"`
 }
 
+// errResponse is the error response. ErrorCode is the machine-readable code
+// of the error, for clients to tell apart errors of the same status code.
 type errResponse struct {
-	Status  string `json:"status"`
-	Code    int    `json:"code"`
-	Details string `json:"details"`
+	Status    string `json:"status"`
+	Code      int    `json:"code"`
+	ErrorCode string `json:"error_code,omitempty"`
+	Details   any    `json:"details"`
 }
 
+// Machine-readable codes of the errors.
+const (
+	errCodeInternal           = "internal_error"
+	errCodeInvalidRequest     = "invalid_request"
+	errCodeKeyNotFound        = "key_not_found"
+	errCodeKeyNotDeleted      = "key_not_deleted"
+	errCodeKeyAlreadyExists   = "key_already_exists"
+	errCodeWrongType          = "wrong_type"
+	errCodeFieldTypeMismatch  = "field_type_mismatch"
+	errCodeRevisionNotFound   = "revision_not_found"
+	errCodeHistoryDisabled    = "history_disabled"
+	errCodeSoftDeleteDisabled = "soft_delete_disabled"
+	errCodePreconditionFailed = "precondition_failed"
+	errCodePatchTestFailed    = "patch_test_failed"
+	errCodePatchConflict      = "patch_conflict"
+	errCodeLockHeld           = "lock_held"
+	errCodeLockNotHeld        = "lock_not_held"
+	errCodeConflict           = "transaction_conflict"
+	errCodeUnavailable        = "storage_unavailable"
+	errCodeReadOnly           = "storage_read_only"
+	errCodeSpaceNotFound      = "storage_space_not_found"
+	errCodeOutOfMemory        = "storage_out_of_memory"
+	errCodeTimeout            = "storage_timeout"
+	errCodeInvalidDataFormat  = "storage_invalid_data"
+)
+
 func writeJSONErr(log *slog.Logger, w http.ResponseWriter, statusCode int, details string) {
+	writeJSONErrDetails(log, w, statusCode, details)
+}
+
+// writeJSONErrDetails writes the error response with structured details.
+func writeJSONErrDetails(log *slog.Logger, w http.ResponseWriter, statusCode int, details any) {
+	writeJSONErrCode(log, w, statusCode, "", details)
+}
+
+// writeJSONErrCode writes the error response with the machine-readable code
+// of the error, or the problem of the error on the routes of Problems.
+func writeJSONErrCode(log *slog.Logger, w http.ResponseWriter, statusCode int, errorCode string, details any) {
+	if pw, ok := problemWriterOf(w); ok {
+		pw.writeProblem(log, statusCode, errorCode, details)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(statusCode)
 	resp := errResponse{
-		Status:  "error",
-		Code:    statusCode,
-		Details: details,
+		Status:    "error",
+		Code:      statusCode,
+		ErrorCode: errorCode,
+		Details:   details,
 	}
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		log.Error("failed to encode error response", slog.String("error", err.Error()))
@@ -43,3 +95,83 @@ func writeJSONSuccess(log *slog.Logger, w http.ResponseWriter, statusCode int, d
 		log.Error("failed to encode success response", slog.String("error", err.Error()))
 	}
 }
+
+// writeBlob writes the blob data verbatim with its content type.
+func writeBlob(log *slog.Logger, w http.ResponseWriter, blob storage.Blob) {
+	w.Header().Set("Content-Type", blob.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(blob.Data)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(blob.Data); err != nil {
+		log.Error("failed to write blob", slog.String("error", err.Error()))
+	}
+}
+
+// writeBlobStream writes the blob data read from r with its content type.
+func writeBlobStream(log *slog.Logger, w http.ResponseWriter, contentType string, r io.Reader) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, r); err != nil {
+		log.Error("failed to stream blob", slog.String("error", err.Error()))
+	}
+}
+
+// writeJSONValueStream writes the success response with the key and its JSON
+// encoded value read from r, the same as writeJSONSuccess does for decoded
+// values but without holding the whole value in memory.
+func writeJSONValueStream(log *slog.Logger, w http.ResponseWriter, key string, r io.Reader) {
+	jsonKey, err := json.Marshal(key)
+	if err != nil {
+		writeJSONErr(log, w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	prefix := fmt.Sprintf(`{"status":"success","code":%d,"details":{"key":%s,"value":`, http.StatusOK, jsonKey)
+	if _, err := io.WriteString(w, prefix); err != nil {
+		log.Error("failed to stream value", slog.String("error", err.Error()))
+		return
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		log.Error("failed to stream value", slog.String("error", err.Error()))
+		return
+	}
+	if _, err := io.WriteString(w, "}}\n"); err != nil {
+		log.Error("failed to stream value", slog.String("error", err.Error()))
+	}
+}
+
+// writeStructureErr writes the error response of a failed operation on a
+// list, hash or set.
+func writeStructureErr(log *slog.Logger, w http.ResponseWriter, err error) {
+	if errors.Is(err, storage.ErrWrongType) {
+		writeJSONErrCode(log, w, http.StatusConflict, errCodeWrongType, "key holds a value of another type")
+		return
+	}
+	writeStorageFailure(log, w, err)
+}
+
+// writeStorageFailure writes the error response of a request failed by the
+// storage rather than by the request itself. Unknown errors are internal.
+func writeStorageFailure(log *slog.Logger, w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrConflict):
+		writeJSONErrCode(log, w, http.StatusConflict, errCodeConflict, "transaction conflict, retry the request")
+	case errors.Is(err, storage.ErrUnavailable):
+		writeJSONErrCode(log, w, http.StatusServiceUnavailable, errCodeUnavailable, "storage unavailable")
+	case errors.Is(err, storage.ErrReadOnly):
+		writeJSONErrCode(log, w, http.StatusServiceUnavailable, errCodeReadOnly, "storage is read-only")
+	case errors.Is(err, storage.ErrSpaceNotFound):
+		writeJSONErrCode(log, w, http.StatusServiceUnavailable, errCodeSpaceNotFound, "storage space not found")
+	case errors.Is(err, storage.ErrOutOfMemory):
+		writeJSONErrCode(log, w, http.StatusInsufficientStorage, errCodeOutOfMemory, "storage out of memory")
+	case errors.Is(err, storage.ErrTimeout):
+		writeJSONErrCode(log, w, http.StatusGatewayTimeout, errCodeTimeout, "storage timeout")
+	case errors.Is(err, storage.ErrInvalidDataFormat):
+		writeJSONErrCode(log, w, http.StatusBadGateway, errCodeInvalidDataFormat, "storage error")
+	default:
+		writeJSONErrCode(log, w, http.StatusInternalServerError, errCodeInternal, "internal error")
+	}
+}
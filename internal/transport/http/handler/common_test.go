@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteJSONValueStream(t *testing.T) {
+	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	value := map[string]any{"name": "John", "tags": []any{"a", "b"}}
+
+	expected := httptest.NewRecorder()
+	writeJSONSuccess(log, expected, http.StatusOK, map[string]any{"key": `user:"1"`, "value": value})
+
+	w := httptest.NewRecorder()
+	writeJSONValueStream(log, w, `user:"1"`, strings.NewReader(`{"name":"John","tags":["a","b"]}`))
+
+	assert.Equal(t, expected.Code, w.Code)
+	assert.Equal(t, expected.Header().Get("Content-Type"), w.Header().Get("Content-Type"))
+	assert.JSONEq(t, expected.Body.String(), w.Body.String())
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+// entityTag returns the strong entity tag of the version of a value.
+func entityTag(version uint64) string {
+	return fmt.Sprintf(`"%016x"`, version)
+}
+
+// setValidators sets the ETag and Last-Modified headers of the value, the
+// ones the metadata has no data for are not set.
+func setValidators(w http.ResponseWriter, meta storage.Metadata) {
+	if meta.Version != 0 {
+		w.Header().Set("ETag", entityTag(meta.Version))
+	}
+	if !meta.ModifiedAt.IsZero() {
+		w.Header().Set("Last-Modified", meta.ModifiedAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+// notModified reports whether the value is not modified according to the
+// If-None-Match or, if it is not set, the If-Modified-Since header of the
+// request, see RFC 9110 section 13.2.2.
+func notModified(r *http.Request, meta storage.Metadata) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, meta.Version)
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || meta.ModifiedAt.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	// Last-Modified has a precision of a second.
+	return !meta.ModifiedAt.Truncate(time.Second).After(since)
+}
+
+// etagMatches reports whether the list of entity tags of If-None-Match
+// matches the version by the weak comparison.
+func etagMatches(list string, version uint64) bool {
+	if strings.TrimSpace(list) == "*" {
+		return true
+	}
+	if version == 0 {
+		return false
+	}
+
+	etag := entityTag(version)
+	for tag := range strings.SplitSeq(list, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// etagVersions returns the versions of the strong entity tags of the list of
+// If-Match. The weak ones and the ones not issued by entityTag never match,
+// they are skipped.
+func etagVersions(list string) []uint64 {
+	var versions []uint64
+	for tag := range strings.SplitSeq(list, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) != 18 || tag[0] != '"' || tag[17] != '"' {
+			continue
+		}
+		version, err := strconv.ParseUint(tag[1:17], 16, 64)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	return versions
+}
+
+// writeNotModified answers the conditional request with 304 and the
+// validators of the value.
+func writeNotModified(w http.ResponseWriter, meta storage.Metadata) {
+	setValidators(w, meta)
+	w.WriteHeader(http.StatusNotModified)
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// KeyRotator is the contract for rotating the data keys encrypting values.
+type KeyRotator interface {
+	// Rotate creates a new data key encrypting new values and returns its ID.
+	Rotate() (uint32, error)
+}
+
+// Encryption is the HTTP handler for managing the encryption of values.
+type Encryption struct {
+	log     *slog.Logger
+	rotator KeyRotator
+}
+
+// NewEncryption creates a new HTTP handler for managing the encryption.
+func NewEncryption(log *slog.Logger, rotator KeyRotator) *Encryption {
+	return &Encryption{
+		log:     log,
+		rotator: rotator,
+	}
+}
+
+// Rotate creates a new data key. The values encrypted with the previous keys
+// are re-encrypted in the background.
+func (h *Encryption) Rotate(w http.ResponseWriter, r *http.Request) {
+	id, err := h.rotator.Rotate()
+	if err != nil {
+		h.log.Error("failed to rotate data key", slog.String("error", err.Error()))
+		writeJSONErr(h.log, w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	h.log.Info("data key rotated", slog.Uint64("key_id", uint64(id)))
+	writeJSONSuccess(h.log, w, http.StatusOK, map[string]any{"key_id": id})
+}
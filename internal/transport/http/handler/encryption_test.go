@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockKeyRotator struct {
+	mock.Mock
+}
+
+func (m *MockKeyRotator) Rotate() (uint32, error) {
+	args := m.Called()
+	return args.Get(0).(uint32), args.Error(1)
+}
+
+func TestEncryption_Rotate(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	tests := []struct {
+		name           string
+		mockSetup      func(*MockKeyRotator)
+		expectedStatus int
+	}{
+		{
+			name: "successful rotation",
+			mockSetup: func(m *MockKeyRotator) {
+				m.On("Rotate").Return(uint32(2), nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "storage error",
+			mockSetup: func(m *MockKeyRotator) {
+				m.On("Rotate").Return(uint32(0), errors.New("connection refused"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rotator := &MockKeyRotator{}
+			tt.mockSetup(rotator)
+
+			handler := NewEncryption(logger, rotator)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/encryption/rotate", nil)
+			w := httptest.NewRecorder()
+
+			handler.Rotate(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			rotator.AssertExpectations(t)
+		})
+	}
+}
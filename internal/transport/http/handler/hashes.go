@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+// HashStorage is the contract for the storage of hashes.
+type HashStorage interface {
+	// HashSet sets the fields of the hash and returns the number of new
+	// fields.
+	HashSet(key string, fields map[string]any) (int, error)
+	// HashGet returns the value of the field of the hash.
+	HashGet(key, field string) (any, error)
+	// HashGetAll returns all fields of the hash.
+	HashGetAll(key string) (map[string]any, error)
+	// HashDelete deletes the fields from the hash and returns the number of
+	// deleted fields.
+	HashDelete(key string, fields []string) (int, error)
+}
+
+// Hashes is the HTTP handler for hashes.
+type Hashes struct {
+	log     *slog.Logger
+	storage HashStorage
+}
+
+// NewHashes creates a new HTTP handler for hashes.
+func NewHashes(log *slog.Logger, storage HashStorage) *Hashes {
+	return &Hashes{
+		log:     log,
+		storage: storage,
+	}
+}
+
+type hashSetRequest struct {
+	Fields map[string]any `json:"fields"`
+}
+
+// Set sets the fields of the hash, creating it if need be.
+func (h *Hashes) Set(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	var req hashSetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONErr(h.log, w, http.StatusUnprocessableEntity, fmt.Sprintf("decode request: %s", err.Error()))
+		return
+	}
+
+	if len(req.Fields) == 0 {
+		writeJSONErr(h.log, w, http.StatusBadRequest, "fields cannot be empty")
+		return
+	}
+
+	added, err := h.storage.HashSet(key, req.Fields)
+	if err != nil {
+		h.log.Error("failed to set hash fields", slog.String("error", err.Error()))
+		writeStructureErr(h.log, w, err)
+		return
+	}
+
+	writeJSONSuccess(h.log, w, http.StatusOK, map[string]any{"key": key, "added": added})
+}
+
+// GetAll returns all fields of the hash.
+func (h *Hashes) GetAll(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	fields, err := h.storage.HashGetAll(key)
+	if err != nil {
+		h.log.Error("failed to get hash", slog.String("error", err.Error()))
+		writeStructureErr(h.log, w, err)
+		return
+	}
+
+	writeJSONSuccess(h.log, w, http.StatusOK, map[string]any{"key": key, "fields": fields})
+}
+
+// Get returns the value of the field of the hash.
+func (h *Hashes) Get(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	field := r.PathValue("field")
+
+	value, err := h.storage.HashGet(key, field)
+	if errors.Is(err, storage.ErrHashFieldNotFound) {
+		writeJSONErr(h.log, w, http.StatusNotFound, "field not found")
+		return
+	}
+	if err != nil {
+		h.log.Error("failed to get hash field", slog.String("error", err.Error()))
+		writeStructureErr(h.log, w, err)
+		return
+	}
+
+	writeJSONSuccess(h.log, w, http.StatusOK, map[string]any{"key": key, "field": field, "value": value})
+}
+
+// Delete deletes the field from the hash. Deleting the last field deletes
+// the hash.
+func (h *Hashes) Delete(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	field := r.PathValue("field")
+
+	deleted, err := h.storage.HashDelete(key, []string{field})
+	if err != nil {
+		h.log.Error("failed to delete hash field", slog.String("error", err.Error()))
+		writeStructureErr(h.log, w, err)
+		return
+	}
+
+	writeJSONSuccess(h.log, w, http.StatusOK, map[string]any{"key": key, "deleted": deleted})
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+type MockHashStorage struct {
+	mock.Mock
+}
+
+func (m *MockHashStorage) HashSet(key string, fields map[string]any) (int, error) {
+	args := m.Called(key, fields)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockHashStorage) HashGet(key, field string) (any, error) {
+	args := m.Called(key, field)
+	return args.Get(0), args.Error(1)
+}
+
+func (m *MockHashStorage) HashGetAll(key string) (map[string]any, error) {
+	args := m.Called(key)
+	return args.Get(0).(map[string]any), args.Error(1)
+}
+
+func (m *MockHashStorage) HashDelete(key string, fields []string) (int, error) {
+	args := m.Called(key, fields)
+	return args.Int(0), args.Error(1)
+}
+
+func TestHashes(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	tests := []struct {
+		name           string
+		call           func(h *Hashes) http.HandlerFunc
+		body           string
+		mockSetup      func(*MockHashStorage)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "set",
+			call: func(h *Hashes) http.HandlerFunc { return h.Set },
+			body: `{"fields": {"name": "alice", "age": 30}}`,
+			mockSetup: func(m *MockHashStorage) {
+				m.On("HashSet", "user", map[string]any{"name": "alice", "age": float64(30)}).Return(2, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","code":200,"details":{"key":"user","added":2}}`,
+		},
+		{
+			name:           "set without fields",
+			call:           func(h *Hashes) http.HandlerFunc { return h.Set },
+			body:           `{"fields": {}}`,
+			mockSetup:      func(m *MockHashStorage) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "get field",
+			call: func(h *Hashes) http.HandlerFunc { return h.Get },
+			mockSetup: func(m *MockHashStorage) {
+				m.On("HashGet", "user", "name").Return("alice", nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","code":200,"details":{"key":"user","field":"name","value":"alice"}}`,
+		},
+		{
+			name: "get missing field",
+			call: func(h *Hashes) http.HandlerFunc { return h.Get },
+			mockSetup: func(m *MockHashStorage) {
+				m.On("HashGet", "user", "name").Return(nil, storage.ErrHashFieldNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "get field of key of another type",
+			call: func(h *Hashes) http.HandlerFunc { return h.Get },
+			mockSetup: func(m *MockHashStorage) {
+				m.On("HashGet", "user", "name").Return(nil, storage.ErrWrongType)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name: "get all",
+			call: func(h *Hashes) http.HandlerFunc { return h.GetAll },
+			mockSetup: func(m *MockHashStorage) {
+				m.On("HashGetAll", "user").Return(map[string]any{"name": "alice"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","code":200,"details":{"key":"user","fields":{"name":"alice"}}}`,
+		},
+		{
+			name: "delete field",
+			call: func(h *Hashes) http.HandlerFunc { return h.Delete },
+			mockSetup: func(m *MockHashStorage) {
+				m.On("HashDelete", "user", []string{"name"}).Return(1, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","code":200,"details":{"key":"user","deleted":1}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hashes := &MockHashStorage{}
+			tt.mockSetup(hashes)
+
+			handler := NewHashes(logger, hashes)
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/hashes/user", bytes.NewBufferString(tt.body))
+			req.SetPathValue("key", "user")
+			req.SetPathValue("field", "name")
+			w := httptest.NewRecorder()
+
+			tt.call(handler)(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+			hashes.AssertExpectations(t)
+		})
+	}
+}
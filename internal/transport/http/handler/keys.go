@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// KeyScanner is the contract for the storage listing keys in order.
+type KeyScanner interface {
+	// ScanPrefix returns up to limit keys starting with the prefix and
+	// following the after key.
+	ScanPrefix(prefix, after string, limit uint32) ([]string, error)
+}
+
+// Keys is the HTTP handler for listing keys.
+type Keys struct {
+	log     *slog.Logger
+	storage KeyScanner
+}
+
+// NewKeys creates a new HTTP handler for listing keys.
+func NewKeys(log *slog.Logger, storage KeyScanner) *Keys {
+	return &Keys{
+		log:     log,
+		storage: storage,
+	}
+}
+
+// List returns a page of keys starting with the prefix from the query in
+// ascending order. The cursor of the next page is the last key of the page.
+func (h *Keys) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := uint64(defaultQueryLimit)
+	if raw := query.Get("limit"); raw != "" {
+		var err error
+		limit, err = strconv.ParseUint(raw, 10, 32)
+		if err != nil || limit == 0 || limit > maxQueryLimit {
+			writeJSONErr(h.log, w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+	}
+
+	keys, err := h.storage.ScanPrefix(query.Get("prefix"), query.Get("cursor"), uint32(limit))
+	if err != nil {
+		h.log.Error("failed to list keys", slog.String("error", err.Error()))
+		writeStorageFailure(h.log, w, err)
+		return
+	}
+
+	page := map[string]any{"keys": keys}
+	if uint64(len(keys)) == limit {
+		page["next"] = keys[len(keys)-1]
+	}
+	writeJSONSuccess(h.log, w, http.StatusOK, page)
+}
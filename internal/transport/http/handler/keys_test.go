@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+type MockKeyScanner struct {
+	mock.Mock
+}
+
+func (m *MockKeyScanner) ScanPrefix(prefix, after string, limit uint32) ([]string, error) {
+	args := m.Called(prefix, after, limit)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func TestKeys_List(t *testing.T) {
+	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	tests := []struct {
+		name           string
+		query          string
+		mockSetup      func(*MockKeyScanner)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "full page",
+			query: "?prefix=user:&limit=2&cursor=user:1",
+			mockSetup: func(ms *MockKeyScanner) {
+				ms.On("ScanPrefix", "user:", "user:1", uint32(2)).Return([]string{"user:2", "user:3"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","code":200,"details":{"keys":["user:2","user:3"],"next":"user:3"}}`,
+		},
+		{
+			name:  "last page",
+			query: "",
+			mockSetup: func(ms *MockKeyScanner) {
+				ms.On("ScanPrefix", "", "", uint32(defaultQueryLimit)).Return([]string{"a"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","code":200,"details":{"keys":["a"]}}`,
+		},
+		{
+			name:           "invalid limit",
+			query:          "?limit=0",
+			mockSetup:      func(ms *MockKeyScanner) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":400,"details":"limit must be between 1 and 1000"}`,
+		},
+		{
+			name:  "storage error",
+			query: "",
+			mockSetup: func(ms *MockKeyScanner) {
+				ms.On("ScanPrefix", "", "", uint32(defaultQueryLimit)).Return([]string(nil), errors.New("boom"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"status":"error","code":500,"error_code":"internal_error","details":"internal error"}`,
+		},
+		{
+			name:  "storage unavailable",
+			query: "",
+			mockSetup: func(ms *MockKeyScanner) {
+				ms.On("ScanPrefix", "", "", uint32(defaultQueryLimit)).Return([]string(nil), fmt.Errorf("%w: connection closed", storage.ErrUnavailable))
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `{"status":"error","code":503,"error_code":"storage_unavailable","details":"storage unavailable"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := &MockKeyScanner{}
+			tt.mockSetup(mockStorage)
+
+			handler := NewKeys(log, mockStorage)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/kv/_keys"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			handler.List(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
@@ -4,37 +4,123 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"mime"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/tmybsv/tarantool-kv/internal/jsonpatch"
+	"github.com/tmybsv/tarantool-kv/internal/schema"
 	"github.com/tmybsv/tarantool-kv/internal/storage"
 )
 
-// KVStorage is the contract for the KV storage.
+// maxBlobSize is the maximum size of a blob value. Blobs larger than a
+// Tarantool tuple are stored only if the storage splits them into chunks.
+const maxBlobSize = 64 << 20
+
+// maxPatchSize is the maximum size of a patch, which may carry whole values.
+const maxPatchSize = maxBlobSize
+
+// KVStorage is the contract for the KV storage. Values are either decoded
+// JSON or storage.Blob for binary values.
 type KVStorage interface {
 	// Set sets the value for the key or an error if the key is already present.
 	Set(key string, value any) error
 	// Update updates the value for the key or an error if the key is not found.
 	Update(key string, value any) error
+	// Upsert sets the value for the key whether the key is present or not and
+	// reports whether the key was created.
+	Upsert(key string, value any) (bool, error)
 	// Delete removes the key from the storage or an error if the key is not found.
 	Delete(key string) error
 	// Get returns the value for the key or an error if the key is not found.
 	Get(key string) (any, error)
 }
 
+// KVHistory is the contract for the storage keeping previous revisions of
+// values.
+type KVHistory interface {
+	// History returns the kept revisions of the key, the latest first.
+	History(key string) ([]storage.Revision, error)
+	// Revision returns the given revision of the key.
+	Revision(key string, revision uint64) (storage.Revision, error)
+	// RevisionAt returns the revision of the key that was current at the time.
+	RevisionAt(key string, at time.Time) (storage.Revision, error)
+	// Restore makes the value of the revision the current value of the key.
+	Restore(key string, revision uint64) error
+}
+
+// KVUndeleter is the contract for the storage keeping tombstones of deleted
+// keys.
+type KVUndeleter interface {
+	// Undelete restores the deleted key or returns an error if the key is not
+	// deleted.
+	Undelete(key string) error
+}
+
+// KVMetadata is the contract for the storage keeping the metadata of values
+// for conditional requests.
+type KVMetadata interface {
+	// GetWithMetadata returns the value for the key along with its metadata,
+	// see KVStorage.Get.
+	GetWithMetadata(key string) (any, storage.Metadata, error)
+	// Metadata returns the metadata of the value for the key without the
+	// value.
+	Metadata(key string) (storage.Metadata, error)
+}
+
+// KVStreamer is the contract for the storage streaming values, the large
+// ones stored in chunks are not held in memory.
+type KVStreamer interface {
+	// OpenValue opens the stored value for the key, the JSON encoding of a
+	// JSON value or the data of a blob, along with its metadata. The value
+	// must be closed.
+	OpenValue(key string) (io.ReadCloser, storage.Metadata, error)
+}
+
+// KVModifier is the contract for the storage modifying values atomically.
+type KVModifier interface {
+	// Modify replaces the value for the key with the one fn returns for the
+	// current value, so that the value is not changed in between.
+	Modify(key string, fn func(value any) (any, error)) error
+}
+
+// KVComparer is the contract for the storage updating values only if they
+// have not changed since they were read.
+type KVComparer interface {
+	// CompareAndSwap updates the value for the key if its current version,
+	// see storage.Metadata, is the given one or returns
+	// storage.ErrVersionMismatch.
+	CompareAndSwap(key string, version uint64, value any) error
+}
+
+// ValueValidator is the contract for validating values before they are
+// stored.
+type ValueValidator interface {
+	// Validate returns an error if the value is not valid for the key.
+	Validate(key string, value any) error
+}
+
 // KV is the HTTP handler for the KV storage.
 type KV struct {
-	log      *slog.Logger
-	storage  KVStorage
-	basePath string
+	log       *slog.Logger
+	storage   KVStorage
+	validator ValueValidator
+	basePath  string
 }
 
-// NewKV creates a new HTTP handler for the KV storage.
-func NewKV(log *slog.Logger, storage KVStorage, basePath string) *KV {
+// NewKV creates a new HTTP handler for the KV storage. Values are stored
+// without validation if validator is nil.
+func NewKV(log *slog.Logger, storage KVStorage, basePath string, validator ValueValidator) *KV {
 	return &KV{
-		basePath: basePath,
-		storage:  storage,
-		log:      log,
+		basePath:  basePath,
+		storage:   storage,
+		validator: validator,
+		log:       log,
 	}
 }
 
@@ -55,6 +141,10 @@ func (h *KV) Set(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.validate(w, req.Key, req.Value) {
+		return
+	}
+
 	if err := h.storage.Set(req.Key, req.Value); err != nil {
 		h.log.Error("failed to set key", slog.String("error", err.Error()))
 		h.handleStorageError(w, err)
@@ -64,40 +154,428 @@ func (h *KV) Set(w http.ResponseWriter, r *http.Request) {
 	writeJSONSuccess(h.log, w, http.StatusCreated, map[string]any{"key": req.Key})
 }
 
-// Get returns the value for the key.
+// Get returns the value for the key. The value of a previous revision is
+// returned if either revision or at query parameter is set. Deleted keys are
+// reported as gone rather than not found if include_deleted is set. If the
+// storage keeps the metadata of values, the response has the ETag and
+// Last-Modified headers and conditional requests are answered with 304 if
+// the value is not modified. The value is streamed if the storage supports
+// it.
 func (h *KV) Get(w http.ResponseWriter, r *http.Request) {
 	key := r.PathValue("key")
 
-	value, err := h.storage.Get(key)
+	query := r.URL.Query()
+	if query.Has("revision") || query.Has("at") {
+		h.getRevision(w, r, key)
+		return
+	}
+
+	if ss, ok := h.storage.(KVStreamer); ok {
+		h.streamValue(w, r, ss, key)
+		return
+	}
+
+	var (
+		value any
+		meta  storage.Metadata
+		err   error
+	)
+	if ms, ok := h.storage.(KVMetadata); ok {
+		value, meta, err = ms.GetWithMetadata(key)
+	} else {
+		value, err = h.storage.Get(key)
+	}
 	if err != nil {
 		h.log.Error("failed to get key", slog.String("error", err.Error()))
-		h.handleStorageError(w, err)
+		h.handleGetError(w, r, err)
+		return
+	}
+
+	if notModified(r, meta) {
+		writeNotModified(w, meta)
+		return
+	}
+	setValidators(w, meta)
+
+	if blob, ok := value.(storage.Blob); ok {
+		writeBlob(h.log, w, blob)
 		return
 	}
 
 	writeJSONSuccess(h.log, w, http.StatusOK, map[string]any{"key": key, "value": value})
 }
 
-// Update updates the value for the key.
+// streamValue writes the value of the key as Get does, streaming it from the
+// storage.
+func (h *KV) streamValue(w http.ResponseWriter, r *http.Request, ss KVStreamer, key string) {
+	value, meta, err := ss.OpenValue(key)
+	if err != nil {
+		h.log.Error("failed to get key", slog.String("error", err.Error()))
+		h.handleGetError(w, r, err)
+		return
+	}
+	defer value.Close()
+
+	if notModified(r, meta) {
+		writeNotModified(w, meta)
+		return
+	}
+	setValidators(w, meta)
+
+	if meta.ContentType != "" {
+		writeBlobStream(h.log, w, meta.ContentType, value)
+		return
+	}
+	writeJSONValueStream(h.log, w, key, value)
+}
+
+// Head reports whether the key exists along with the metadata of its value
+// in the headers, the same as Get does but without the value. If the storage
+// does not keep the metadata of values, the value is read to check the key
+// exists.
+func (h *KV) Head(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	ms, ok := h.storage.(KVMetadata)
+	if !ok {
+		value, err := h.storage.Get(key)
+		if err != nil {
+			h.handleGetError(w, r, err)
+			return
+		}
+		contentType := ""
+		if blob, ok := value.(storage.Blob); ok {
+			contentType = blob.ContentType
+		}
+		writeHead(w, storage.Metadata{ContentType: contentType})
+		return
+	}
+
+	meta, err := ms.Metadata(key)
+	if err != nil {
+		h.handleGetError(w, r, err)
+		return
+	}
+
+	if notModified(r, meta) {
+		writeNotModified(w, meta)
+		return
+	}
+	writeHead(w, meta)
+}
+
+// writeHead answers the HEAD request with the content type and the
+// validators of the value.
+func writeHead(w http.ResponseWriter, meta storage.Metadata) {
+	setValidators(w, meta)
+	if meta.ContentType != "" {
+		w.Header().Set("Content-Type", meta.ContentType)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+	} else {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// putMode is the way PUT stores the value, selected by the mode query
+// parameter or the If-None-Match and If-Match headers.
+type putMode int
+
+const (
+	// putUpdate updates the value of the present key.
+	putUpdate putMode = iota
+	// putUpsert sets the value whether the key is present or not.
+	putUpsert
+	// putCreate sets the value of the key that is not present.
+	putCreate
+	// putMatch updates the value of the key if it has one of the versions
+	// of the entity tags of If-Match.
+	putMatch
+)
+
+// parsePutMode returns the mode of the request along with the versions of
+// the entity tags of If-Match. If-None-Match: * selects the create-only
+// mode, If-Match: * the update-only one and If-Match with entity tags the
+// conditional update whatever the mode query parameter is.
+func parsePutMode(r *http.Request, defaultMode putMode) (putMode, []uint64, error) {
+	inm, im := r.Header.Get("If-None-Match"), r.Header.Get("If-Match")
+	switch {
+	case inm != "" && im != "":
+		return 0, nil, errors.New("If-None-Match and If-Match cannot be set together")
+	case inm != "":
+		if strings.TrimSpace(inm) != "*" {
+			return 0, nil, errors.New("If-None-Match supports only *")
+		}
+		return putCreate, nil, nil
+	case strings.TrimSpace(im) == "*":
+		return putUpdate, nil, nil
+	case im != "":
+		return putMatch, etagVersions(im), nil
+	}
+
+	switch mode := r.URL.Query().Get("mode"); mode {
+	case "":
+		return defaultMode, nil, nil
+	case "update":
+		return putUpdate, nil, nil
+	case "upsert":
+		return putUpsert, nil, nil
+	default:
+		return 0, nil, fmt.Errorf("unknown mode %q, want update or upsert", mode)
+	}
+}
+
+// Update updates the value for the key. With mode=upsert query parameter the
+// key is created if it is not present, If-None-Match: * and If-Match: *
+// headers restrict the request to creating and updating the key. If-Match
+// with entity tags updates the value only if its ETag is one of them. A request
+// body of a content type other than JSON is stored verbatim as a blob,
+// creating the key if needed unless the mode says otherwise.
 func (h *KV) Update(w http.ResponseWriter, r *http.Request) {
 	key := r.PathValue("key")
 
-	var req struct {
-		Value any `json:"value"`
+	isJSON := isJSONContentType(r.Header.Get("Content-Type"))
+	defaultMode := putUpdate
+	if !isJSON {
+		defaultMode = putUpsert
+	}
+	mode, versions, err := parsePutMode(r, defaultMode)
+	if err != nil {
+		writeJSONErr(h.log, w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSONErr(h.log, w, http.StatusUnprocessableEntity, fmt.Sprintf("decode request: %s", err.Error()))
+	var value any
+	if isJSON {
+		var req struct {
+			Value any `json:"value"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONErr(h.log, w, http.StatusUnprocessableEntity, fmt.Sprintf("decode request: %s", err.Error()))
+			return
+		}
+
+		if !h.validate(w, key, req.Value) {
+			return
+		}
+		value = req.Value
+	} else {
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBlobSize))
+		if tooLarge(err) {
+			writeJSONErr(h.log, w, http.StatusRequestEntityTooLarge, "value is too large")
+			return
+		}
+		if err != nil {
+			writeJSONErr(h.log, w, http.StatusBadRequest, fmt.Sprintf("read request: %s", err.Error()))
+			return
+		}
+		value = storage.Blob{ContentType: r.Header.Get("Content-Type"), Data: data}
+		if !h.validate(w, key, value) {
+			return
+		}
+	}
+
+	status := http.StatusOK
+	switch mode {
+	case putUpdate:
+		err = h.storage.Update(key, value)
+	case putUpsert:
+		var created bool
+		if created, err = h.storage.Upsert(key, value); created {
+			status = http.StatusCreated
+		}
+	case putCreate:
+		status = http.StatusCreated
+		err = h.storage.Set(key, value)
+	case putMatch:
+		if !h.updateMatching(w, key, versions, value) {
+			return
+		}
+	}
+	if err != nil {
+		h.log.Error("failed to put key", slog.String("error", err.Error()))
+		h.handlePutError(w, mode == putCreate || r.Header.Get("If-Match") != "", err)
 		return
 	}
 
-	if err := h.storage.Update(key, req.Value); err != nil {
-		h.log.Error("failed to update key", slog.String("error", err.Error()))
-		h.handleStorageError(w, err)
+	writeJSONSuccess(h.log, w, status, map[string]any{"key": key})
+}
+
+// updateMatching updates the value of the key if its version is one of the
+// versions and writes the error response if it is not, reporting whether
+// the value is updated.
+func (h *KV) updateMatching(w http.ResponseWriter, key string, versions []uint64, value any) bool {
+	ms, okMeta := h.storage.(KVMetadata)
+	comparer, okCAS := h.storage.(KVComparer)
+	if !okMeta || !okCAS {
+		writeJSONErr(h.log, w, http.StatusNotImplemented, "If-Match with entity tags is not supported")
+		return false
+	}
+
+	meta, err := ms.Metadata(key)
+	if err == nil {
+		if meta.Version == 0 || !slices.Contains(versions, meta.Version) {
+			err = storage.ErrVersionMismatch
+		} else {
+			err = comparer.CompareAndSwap(key, meta.Version, value)
+		}
+	}
+	if err != nil {
+		h.log.Error("failed to put key", slog.String("error", err.Error()))
+		h.handlePutError(w, true, err)
+		return false
+	}
+	return true
+}
+
+// handlePutError writes the error response of storing the key. The requests
+// restricted by the conditional headers fail their preconditions rather than
+// report the key is present, not found or changed.
+func (h *KV) handlePutError(w http.ResponseWriter, conditional bool, err error) {
+	notFound := errors.Is(err, storage.ErrKeyNotFound) || errors.Is(err, storage.ErrKeyDeleted)
+	if conditional && (notFound || errors.Is(err, storage.ErrKeyAlreadyExists) || errors.Is(err, storage.ErrVersionMismatch)) {
+		writeJSONErrCode(h.log, w, http.StatusPreconditionFailed, errCodePreconditionFailed, "precondition failed")
 		return
 	}
+	h.handleStorageError(w, err)
+}
 
-	writeJSONSuccess(h.log, w, http.StatusOK, map[string]any{"key": key})
+// Media types of the patches Patch accepts.
+const (
+	mediaTypeMergePatch = "application/merge-patch+json"
+	mediaTypeJSONPatch  = "application/json-patch+json"
+)
+
+// tooLarge reports whether reading the request body failed as the body is
+// larger than the limit of http.MaxBytesReader.
+func tooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// errPatchBlob is returned when patching a blob value.
+var errPatchBlob = errors.New("cannot patch a blob value")
+
+// Patch applies the JSON Merge Patch (RFC 7396) or the JSON Patch (RFC 6902)
+// of the request body to the JSON value of the key, depending on the content
+// type. The patch is applied to the value atomically, either all of its
+// operations are applied or none is, and the patched value is validated the
+// same as the set ones. The patch is reapplied to the value changed by a
+// concurrent write, the request fails with 409 to be retried only if the
+// value keeps changing.
+func (h *KV) Patch(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	modifier, ok := h.storage.(KVModifier)
+	if !ok {
+		writeJSONErr(h.log, w, http.StatusNotImplemented, "patching values is not supported")
+		return
+	}
+
+	var apply func(value any) (any, error)
+	body := http.MaxBytesReader(w, r.Body, maxPatchSize)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case mediaTypeMergePatch:
+		var patch any
+		if err := json.NewDecoder(body).Decode(&patch); err != nil {
+			if tooLarge(err) {
+				writeJSONErr(h.log, w, http.StatusRequestEntityTooLarge, "patch is too large")
+				return
+			}
+			writeJSONErr(h.log, w, http.StatusUnprocessableEntity, fmt.Sprintf("decode request: %s", err.Error()))
+			return
+		}
+		apply = func(value any) (any, error) {
+			return jsonpatch.MergePatch(value, patch), nil
+		}
+	case mediaTypeJSONPatch:
+		data, err := io.ReadAll(body)
+		if tooLarge(err) {
+			writeJSONErr(h.log, w, http.StatusRequestEntityTooLarge, "patch is too large")
+			return
+		}
+		if err != nil {
+			writeJSONErr(h.log, w, http.StatusBadRequest, fmt.Sprintf("read request: %s", err.Error()))
+			return
+		}
+		patch, err := jsonpatch.Decode(data)
+		if err != nil {
+			writeJSONErr(h.log, w, http.StatusBadRequest, err.Error())
+			return
+		}
+		apply = patch.Apply
+	default:
+		w.Header().Set("Accept-Patch", mediaTypeMergePatch+", "+mediaTypeJSONPatch)
+		writeJSONErr(h.log, w, http.StatusUnsupportedMediaType,
+			fmt.Sprintf("content type must be %s or %s", mediaTypeMergePatch, mediaTypeJSONPatch))
+		return
+	}
+
+	var patched any
+	err := modifier.Modify(key, func(value any) (any, error) {
+		if _, ok := value.(storage.Blob); ok {
+			return nil, errPatchBlob
+		}
+
+		value, err := apply(value)
+		if err != nil {
+			return nil, err
+		}
+		if h.validator != nil {
+			if err := h.validator.Validate(key, value); err != nil {
+				return nil, err
+			}
+		}
+
+		patched = value
+		return value, nil
+	})
+	if err != nil {
+		h.log.Error("failed to patch key", slog.String("error", err.Error()))
+		h.handlePatchError(w, err)
+		return
+	}
+
+	writeJSONSuccess(h.log, w, http.StatusOK, map[string]any{"key": key, "value": patched})
+}
+
+// handlePatchError writes the error response of patching the value.
+func (h *KV) handlePatchError(w http.ResponseWriter, err error) {
+	var verr *schema.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		h.writeValidationError(w, verr)
+	case errors.Is(err, jsonpatch.ErrTestFailed):
+		writeJSONErrCode(h.log, w, http.StatusConflict, errCodePatchTestFailed, err.Error())
+	case errors.Is(err, jsonpatch.ErrPathNotFound):
+		writeJSONErrCode(h.log, w, http.StatusConflict, errCodePatchConflict, err.Error())
+	case errors.Is(err, jsonpatch.ErrInvalidPatch):
+		writeJSONErr(h.log, w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, errPatchBlob):
+		writeJSONErrCode(h.log, w, http.StatusConflict, errCodeWrongType, errPatchBlob.Error())
+	default:
+		h.handleStorageError(w, err)
+	}
+}
+
+// isJSONContentType reports whether the request body of the content type is
+// decoded as JSON. Requests without a content type and form ones, which curl
+// sends by default, are decoded as JSON as they always were.
+func isJSONContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "application/json" ||
+		mediaType == "application/x-www-form-urlencoded" ||
+		strings.HasSuffix(mediaType, "+json")
 }
 
 // Delete removes the key from the storage.
@@ -113,15 +591,180 @@ func (h *KV) Delete(w http.ResponseWriter, r *http.Request) {
 	writeJSONSuccess(h.log, w, http.StatusOK, map[string]any{"key": key})
 }
 
+// Undelete restores the soft deleted key.
+func (h *KV) Undelete(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	undeleter, ok := h.storage.(KVUndeleter)
+	if !ok {
+		h.handleStorageError(w, storage.ErrSoftDeleteDisabled)
+		return
+	}
+
+	if err := undeleter.Undelete(key); err != nil {
+		h.log.Error("failed to undelete key", slog.String("error", err.Error()))
+		h.handleStorageError(w, err)
+		return
+	}
+
+	writeJSONSuccess(h.log, w, http.StatusOK, map[string]any{"key": key})
+}
+
+func (h *KV) getRevision(w http.ResponseWriter, r *http.Request, key string) {
+	history, ok := h.storage.(KVHistory)
+	if !ok {
+		h.handleStorageError(w, storage.ErrHistoryDisabled)
+		return
+	}
+
+	var (
+		rev storage.Revision
+		err error
+	)
+	query := r.URL.Query()
+	if query.Has("revision") {
+		revision, parseErr := strconv.ParseUint(query.Get("revision"), 10, 64)
+		if parseErr != nil {
+			writeJSONErr(h.log, w, http.StatusBadRequest, "revision must be a positive integer")
+			return
+		}
+		rev, err = history.Revision(key, revision)
+	} else {
+		at, parseErr := time.Parse(time.RFC3339Nano, query.Get("at"))
+		if parseErr != nil {
+			writeJSONErr(h.log, w, http.StatusBadRequest, "at must be an RFC 3339 timestamp")
+			return
+		}
+		rev, err = history.RevisionAt(key, at)
+	}
+	if err != nil {
+		h.log.Error("failed to get key revision", slog.String("error", err.Error()))
+		h.handleStorageError(w, err)
+		return
+	}
+
+	writeJSONSuccess(h.log, w, http.StatusOK, map[string]any{
+		"key":      key,
+		"value":    rev.Value,
+		"revision": rev.Revision,
+		"deleted":  rev.Deleted,
+		"time":     rev.Time,
+	})
+}
+
+// History returns the kept revisions of the key.
+func (h *KV) History(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	history, ok := h.storage.(KVHistory)
+	if !ok {
+		h.handleStorageError(w, storage.ErrHistoryDisabled)
+		return
+	}
+
+	revisions, err := history.History(key)
+	if err != nil {
+		h.log.Error("failed to get key history", slog.String("error", err.Error()))
+		h.handleStorageError(w, err)
+		return
+	}
+
+	writeJSONSuccess(h.log, w, http.StatusOK, map[string]any{"key": key, "revisions": revisions})
+}
+
+// Restore makes the value of a previous revision the current value of the key.
+func (h *KV) Restore(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	history, ok := h.storage.(KVHistory)
+	if !ok {
+		h.handleStorageError(w, storage.ErrHistoryDisabled)
+		return
+	}
+
+	var req struct {
+		Revision uint64 `json:"revision"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONErr(h.log, w, http.StatusUnprocessableEntity, fmt.Sprintf("decode request: %s", err.Error()))
+		return
+	}
+
+	if req.Revision == 0 {
+		writeJSONErr(h.log, w, http.StatusBadRequest, "revision cannot be empty")
+		return
+	}
+
+	if err := history.Restore(key, req.Revision); err != nil {
+		h.log.Error("failed to restore key", slog.String("error", err.Error()))
+		h.handleStorageError(w, err)
+		return
+	}
+
+	writeJSONSuccess(h.log, w, http.StatusOK, map[string]any{"key": key, "revision": req.Revision})
+}
+
+// validate validates the value of the key and writes the error response if
+// it is not valid.
+func (h *KV) validate(w http.ResponseWriter, key string, value any) bool {
+	if h.validator == nil {
+		return true
+	}
+
+	err := h.validator.Validate(key, value)
+	if err == nil {
+		return true
+	}
+
+	var verr *schema.ValidationError
+	if errors.As(err, &verr) {
+		h.writeValidationError(w, verr)
+		return false
+	}
+
+	h.log.Error("failed to validate value", slog.String("error", err.Error()))
+	writeJSONErr(h.log, w, http.StatusInternalServerError, "internal error")
+	return false
+}
+
+// writeValidationError writes the violations of the schema by the value.
+func (h *KV) writeValidationError(w http.ResponseWriter, verr *schema.ValidationError) {
+	writeJSONErrDetails(h.log, w, http.StatusUnprocessableEntity, map[string]any{
+		"message":    verr.Error(),
+		"violations": verr.Violations,
+	})
+}
+
+// handleGetError writes the error response of reading the key, deleted keys
+// are reported as gone if include_deleted query parameter is set.
+func (h *KV) handleGetError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, storage.ErrKeyDeleted) && r.URL.Query().Has("include_deleted") {
+		writeJSONErr(h.log, w, http.StatusGone, "key deleted")
+		return
+	}
+	h.handleStorageError(w, err)
+}
+
 func (h *KV) handleStorageError(w http.ResponseWriter, err error) {
 	switch {
-	case errors.Is(err, storage.ErrKeyNotFound):
-		writeJSONErr(h.log, w, http.StatusNotFound, "key not found")
-	case errors.Is(err, storage.ErrInvalidDataFormat):
-		writeJSONErr(h.log, w, http.StatusBadGateway, "storage error")
+	case errors.Is(err, storage.ErrKeyNotFound), errors.Is(err, storage.ErrKeyDeleted):
+		writeJSONErrCode(h.log, w, http.StatusNotFound, errCodeKeyNotFound, "key not found")
+	case errors.Is(err, storage.ErrKeyNotDeleted):
+		writeJSONErrCode(h.log, w, http.StatusConflict, errCodeKeyNotDeleted, "key not deleted")
+	case errors.Is(err, storage.ErrFieldTypeMismatch):
+		writeJSONErrCode(h.log, w, http.StatusUnprocessableEntity, errCodeFieldTypeMismatch, "value does not match indexed field type")
 	case errors.Is(err, storage.ErrKeyAlreadyExists):
-		writeJSONErr(h.log, w, http.StatusConflict, "key already exists")
+		writeJSONErrCode(h.log, w, http.StatusConflict, errCodeKeyAlreadyExists, "key already exists")
+	case errors.Is(err, storage.ErrWrongType):
+		writeJSONErrCode(h.log, w, http.StatusConflict, errCodeWrongType, "key holds a value of another type")
+	case errors.Is(err, storage.ErrRevisionNotFound):
+		writeJSONErrCode(h.log, w, http.StatusNotFound, errCodeRevisionNotFound, "revision not found")
+	case errors.Is(err, storage.ErrHistoryDisabled):
+		writeJSONErrCode(h.log, w, http.StatusNotImplemented, errCodeHistoryDisabled, "history is disabled")
+	case errors.Is(err, storage.ErrSoftDeleteDisabled):
+		writeJSONErrCode(h.log, w, http.StatusNotImplemented, errCodeSoftDeleteDisabled, "soft delete is disabled")
 	default:
-		writeJSONErr(h.log, w, http.StatusInternalServerError, "internal error")
+		writeStorageFailure(h.log, w, err)
 	}
 }
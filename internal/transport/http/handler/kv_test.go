@@ -3,15 +3,22 @@ package handler
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"log/slog"
 	"os"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/tmybsv/tarantool-kv/internal/schema"
 	"github.com/tmybsv/tarantool-kv/internal/storage"
 )
 
@@ -29,6 +36,11 @@ func (m *MockKVStorage) Update(key string, value any) error {
 	return args.Error(0)
 }
 
+func (m *MockKVStorage) Upsert(key string, value any) (bool, error) {
+	args := m.Called(key, value)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockKVStorage) Delete(key string) error {
 	args := m.Called(key)
 	return args.Error(0)
@@ -84,7 +96,7 @@ func TestKV_Set(t *testing.T) {
 			mockStorage := &MockKVStorage{}
 			tt.mockSetup(mockStorage)
 
-			handler := NewKV(log, mockStorage, "/api/v1/kv")
+			handler := NewKV(log, mockStorage, "/api/v1/kv", nil)
 
 			var body bytes.Buffer
 			if tt.name == "invalid JSON" {
@@ -138,7 +150,7 @@ func TestKV_Get(t *testing.T) {
 			mockStorage := &MockKVStorage{}
 			tt.mockSetup(mockStorage)
 
-			handler := NewKV(logger, mockStorage, "/api/v1/kv")
+			handler := NewKV(logger, mockStorage, "/api/v1/kv", nil)
 
 			req := httptest.NewRequest(http.MethodGet, "/api/v1/kv/"+tt.key, nil)
 			req.SetPathValue("key", tt.key)
@@ -151,3 +163,1015 @@ func TestKV_Get(t *testing.T) {
 		})
 	}
 }
+
+func TestKV_GetBlob(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	mockStorage := &MockKVStorage{}
+	mockStorage.On("Get", "avatar").Return(storage.Blob{ContentType: "image/png", Data: []byte{0x89, 'P', 'N', 'G'}}, nil)
+	handler := NewKV(logger, mockStorage, "/api/v1/kv", nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/kv/avatar", nil)
+	req.SetPathValue("key", "avatar")
+	w := httptest.NewRecorder()
+
+	handler.Get(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, []byte{0x89, 'P', 'N', 'G'}, w.Body.Bytes())
+	mockStorage.AssertExpectations(t)
+}
+
+func TestKV_UpdateBlob(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	blob := storage.Blob{ContentType: "text/plain; charset=utf-8", Data: []byte("hello")}
+
+	registry := schema.NewRegistry()
+	assert.NoError(t, registry.Register("greet", []byte(`{"type": "string"}`)))
+
+	tests := []struct {
+		name           string
+		body           []byte
+		bodyErr        error
+		validator      ValueValidator
+		mockSetup      func(*MockKVStorage)
+		expectedStatus int
+	}{
+		{
+			name: "replace existing key",
+			body: blob.Data,
+			mockSetup: func(ms *MockKVStorage) {
+				ms.On("Upsert", "greeting", blob).Return(false, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "create new key",
+			body: blob.Data,
+			mockSetup: func(ms *MockKVStorage) {
+				ms.On("Upsert", "greeting", blob).Return(true, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "too large",
+			body:           bytes.Repeat([]byte("a"), maxBlobSize+1),
+			mockSetup:      func(ms *MockKVStorage) {},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "body read fails",
+			bodyErr:        errors.New("connection reset"),
+			mockSetup:      func(ms *MockKVStorage) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "key with schema",
+			body:           blob.Data,
+			validator:      registry,
+			mockSetup:      func(ms *MockKVStorage) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := &MockKVStorage{}
+			tt.mockSetup(mockStorage)
+
+			handler := NewKV(logger, mockStorage, "/api/v1/kv", tt.validator)
+
+			var body io.Reader = bytes.NewReader(tt.body)
+			if tt.bodyErr != nil {
+				body = iotest.ErrReader(tt.bodyErr)
+			}
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/kv/greeting", body)
+			req.Header.Set("Content-Type", blob.ContentType)
+			req.SetPathValue("key", "greeting")
+			w := httptest.NewRecorder()
+
+			handler.Update(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestKV_UpdateMode(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	tests := []struct {
+		name           string
+		query          string
+		header         map[string]string
+		mockSetup      func(*MockKVStorage)
+		expectedStatus int
+	}{
+		{
+			name: "update by default",
+			mockSetup: func(ms *MockKVStorage) {
+				ms.On("Update", "test-key", "value").Return(storage.ErrKeyNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:  "upsert creates key",
+			query: "?mode=upsert",
+			mockSetup: func(ms *MockKVStorage) {
+				ms.On("Upsert", "test-key", "value").Return(true, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:  "upsert updates key",
+			query: "?mode=upsert",
+			mockSetup: func(ms *MockKVStorage) {
+				ms.On("Upsert", "test-key", "value").Return(false, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unknown mode",
+			query:          "?mode=merge",
+			mockSetup:      func(ms *MockKVStorage) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "create only",
+			query:  "?mode=upsert",
+			header: map[string]string{"If-None-Match": "*"},
+			mockSetup: func(ms *MockKVStorage) {
+				ms.On("Set", "test-key", "value").Return(nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:   "create only present key",
+			header: map[string]string{"If-None-Match": "*"},
+			mockSetup: func(ms *MockKVStorage) {
+				ms.On("Set", "test-key", "value").Return(storage.ErrKeyAlreadyExists)
+			},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:   "update only missing key",
+			query:  "?mode=upsert",
+			header: map[string]string{"If-Match": "*"},
+			mockSetup: func(ms *MockKVStorage) {
+				ms.On("Update", "test-key", "value").Return(storage.ErrKeyDeleted)
+			},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:           "entity tags not supported by storage",
+			header:         map[string]string{"If-Match": `"0000000000000001"`},
+			mockSetup:      func(ms *MockKVStorage) {},
+			expectedStatus: http.StatusNotImplemented,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := &MockKVStorage{}
+			tt.mockSetup(mockStorage)
+
+			handler := NewKV(logger, mockStorage, "/api/v1/kv", nil)
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/kv/test-key"+tt.query, bytes.NewBufferString(`{"value":"value"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.SetPathValue("key", "test-key")
+			for name, value := range tt.header {
+				req.Header.Set(name, value)
+			}
+			w := httptest.NewRecorder()
+
+			handler.Update(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+type MockKVHistoryStorage struct {
+	MockKVStorage
+}
+
+func (m *MockKVHistoryStorage) History(key string) ([]storage.Revision, error) {
+	args := m.Called(key)
+	return args.Get(0).([]storage.Revision), args.Error(1)
+}
+
+func (m *MockKVHistoryStorage) Revision(key string, revision uint64) (storage.Revision, error) {
+	args := m.Called(key, revision)
+	return args.Get(0).(storage.Revision), args.Error(1)
+}
+
+func (m *MockKVHistoryStorage) RevisionAt(key string, at time.Time) (storage.Revision, error) {
+	args := m.Called(key, at)
+	return args.Get(0).(storage.Revision), args.Error(1)
+}
+
+func (m *MockKVHistoryStorage) Restore(key string, revision uint64) error {
+	args := m.Called(key, revision)
+	return args.Error(0)
+}
+
+func TestKV_GetRevision(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		query          string
+		mockSetup      func(*MockKVHistoryStorage)
+		expectedStatus int
+	}{
+		{
+			name:  "by revision",
+			query: "?revision=2",
+			mockSetup: func(ms *MockKVHistoryStorage) {
+				ms.On("Revision", "test-key", uint64(2)).Return(storage.Revision{Revision: 2, Value: "old"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "by time",
+			query: "?at=2025-01-02T03:04:05Z",
+			mockSetup: func(ms *MockKVHistoryStorage) {
+				ms.On("RevisionAt", "test-key", at).Return(storage.Revision{Revision: 1, Value: "old"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid revision",
+			query:          "?revision=abc",
+			mockSetup:      func(ms *MockKVHistoryStorage) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "revision not found",
+			query: "?revision=9",
+			mockSetup: func(ms *MockKVHistoryStorage) {
+				ms.On("Revision", "test-key", uint64(9)).Return(storage.Revision{}, storage.ErrRevisionNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := &MockKVHistoryStorage{}
+			tt.mockSetup(mockStorage)
+
+			handler := NewKV(logger, mockStorage, "/api/v1/kv", nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/kv/test-key"+tt.query, nil)
+			req.SetPathValue("key", "test-key")
+			w := httptest.NewRecorder()
+
+			handler.Get(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestKV_Restore(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	t.Run("successful restore", func(t *testing.T) {
+		mockStorage := &MockKVHistoryStorage{}
+		mockStorage.On("Restore", "test-key", uint64(3)).Return(nil)
+
+		handler := NewKV(logger, mockStorage, "/api/v1/kv", nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/kv/test-key/restore", bytes.NewBufferString(`{"revision":3}`))
+		req.SetPathValue("key", "test-key")
+		w := httptest.NewRecorder()
+
+		handler.Restore(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("history not supported", func(t *testing.T) {
+		handler := NewKV(logger, &MockKVStorage{}, "/api/v1/kv", nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/kv/test-key/restore", bytes.NewBufferString(`{"revision":3}`))
+		req.SetPathValue("key", "test-key")
+		w := httptest.NewRecorder()
+
+		handler.Restore(w, req)
+
+		assert.Equal(t, http.StatusNotImplemented, w.Code)
+	})
+}
+
+type MockKVUndeleteStorage struct {
+	MockKVStorage
+}
+
+func (m *MockKVUndeleteStorage) Undelete(key string) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
+func TestKV_GetDeleted(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+	}{
+		{
+			name:           "deleted key is not found",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "deleted key is gone",
+			query:          "?include_deleted",
+			expectedStatus: http.StatusGone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := &MockKVStorage{}
+			mockStorage.On("Get", "test-key").Return(nil, storage.ErrKeyDeleted)
+
+			handler := NewKV(logger, mockStorage, "/api/v1/kv", nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/kv/test-key"+tt.query, nil)
+			req.SetPathValue("key", "test-key")
+			w := httptest.NewRecorder()
+
+			handler.Get(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestKV_Undelete(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	tests := []struct {
+		name           string
+		mockErr        error
+		expectedStatus int
+	}{
+		{
+			name:           "successful undelete",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "key not deleted",
+			mockErr:        storage.ErrKeyNotDeleted,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "key not found",
+			mockErr:        storage.ErrKeyNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "soft delete disabled",
+			mockErr:        storage.ErrSoftDeleteDisabled,
+			expectedStatus: http.StatusNotImplemented,
+		},
+		{
+			name:           "transaction conflict",
+			mockErr:        storage.ErrConflict,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "read-only storage",
+			mockErr:        storage.ErrReadOnly,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "storage out of memory",
+			mockErr:        storage.ErrOutOfMemory,
+			expectedStatus: http.StatusInsufficientStorage,
+		},
+		{
+			name:           "storage timeout",
+			mockErr:        storage.ErrTimeout,
+			expectedStatus: http.StatusGatewayTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := &MockKVUndeleteStorage{}
+			mockStorage.On("Undelete", "test-key").Return(tt.mockErr)
+
+			handler := NewKV(logger, mockStorage, "/api/v1/kv", nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/kv/test-key/undelete", nil)
+			req.SetPathValue("key", "test-key")
+			w := httptest.NewRecorder()
+
+			handler.Undelete(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestKV_SetSchemaValidation(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	registry := schema.NewRegistry()
+	err := registry.Register("user:", []byte(`{"type": "object", "required": ["name"]}`))
+	assert.NoError(t, err)
+
+	mockStorage := &MockKVStorage{}
+	handler := NewKV(logger, mockStorage, "/api/v1/kv", registry)
+
+	body := bytes.NewBufferString(`{"key": "user:1", "value": {"age": 20}}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/kv", body)
+	w := httptest.NewRecorder()
+
+	handler.Set(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var resp struct {
+		Details struct {
+			Violations []schema.Violation `json:"violations"`
+		} `json:"details"`
+	}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Len(t, resp.Details.Violations, 1)
+	mockStorage.AssertExpectations(t)
+}
+
+type MockKVMetadataStorage struct {
+	MockKVStorage
+}
+
+func (m *MockKVMetadataStorage) GetWithMetadata(key string) (any, storage.Metadata, error) {
+	args := m.Called(key)
+	return args.Get(0), args.Get(1).(storage.Metadata), args.Error(2)
+}
+
+func (m *MockKVMetadataStorage) Metadata(key string) (storage.Metadata, error) {
+	args := m.Called(key)
+	return args.Get(0).(storage.Metadata), args.Error(1)
+}
+
+func TestKV_GetConditional(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	meta := storage.Metadata{
+		Version:    0xaf63bd4c8601b7be,
+		ModifiedAt: time.Date(2025, 1, 2, 3, 4, 5, 600, time.UTC),
+	}
+
+	tests := []struct {
+		name           string
+		header         map[string]string
+		expectedStatus int
+	}{
+		{
+			name:           "unconditional",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "matching etag",
+			header:         map[string]string{"If-None-Match": `"0000000000000001", W/"af63bd4c8601b7be"`},
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			name:           "any etag",
+			header:         map[string]string{"If-None-Match": "*"},
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			name:           "other etag",
+			header:         map[string]string{"If-None-Match": `"0000000000000001"`},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "not modified since",
+			header:         map[string]string{"If-Modified-Since": "Thu, 02 Jan 2025 03:04:05 GMT"},
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			name:           "modified since",
+			header:         map[string]string{"If-Modified-Since": "Thu, 02 Jan 2025 03:04:04 GMT"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "etag takes precedence",
+			header: map[string]string{
+				"If-None-Match":     `"0000000000000001"`,
+				"If-Modified-Since": "Thu, 02 Jan 2025 03:04:05 GMT",
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := &MockKVMetadataStorage{}
+			mockStorage.On("GetWithMetadata", "test-key").Return("value", meta, nil)
+
+			handler := NewKV(logger, mockStorage, "/api/v1/kv", nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/kv/test-key", nil)
+			req.SetPathValue("key", "test-key")
+			for name, value := range tt.header {
+				req.Header.Set(name, value)
+			}
+			w := httptest.NewRecorder()
+
+			handler.Get(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, `"af63bd4c8601b7be"`, w.Header().Get("ETag"))
+			assert.Equal(t, "Thu, 02 Jan 2025 03:04:05 GMT", w.Header().Get("Last-Modified"))
+			if tt.expectedStatus == http.StatusNotModified {
+				assert.Empty(t, w.Body.Bytes())
+			}
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+type MockKVStreamStorage struct {
+	MockKVStorage
+}
+
+func (m *MockKVStreamStorage) OpenValue(key string) (io.ReadCloser, storage.Metadata, error) {
+	args := m.Called(key)
+	value, _ := args.Get(0).(io.ReadCloser)
+	return value, args.Get(1).(storage.Metadata), args.Error(2)
+}
+
+// streamedValue is a value opened for streaming that records its closing.
+type streamedValue struct {
+	io.Reader
+	closed bool
+}
+
+func (v *streamedValue) Close() error {
+	v.closed = true
+	return nil
+}
+
+func TestKV_GetStream(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	tests := []struct {
+		name                string
+		data                string
+		meta                storage.Metadata
+		err                 error
+		header              map[string]string
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "json value",
+			data:                `{"a":[1,2]}`,
+			meta:                storage.Metadata{Version: 1},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json; charset=utf-8",
+			expectedBody:        `{"status":"success","code":200,"details":{"key":"test-key","value":{"a":[1,2]}}}` + "\n",
+		},
+		{
+			name:                "blob",
+			data:                "\x89PNG",
+			meta:                storage.Metadata{Version: 1, ContentType: "image/png"},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "image/png",
+			expectedBody:        "\x89PNG",
+		},
+		{
+			name:           "not modified",
+			data:           `"v"`,
+			meta:           storage.Metadata{Version: 1},
+			header:         map[string]string{"If-None-Match": `"0000000000000001"`},
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			name:           "not found",
+			err:            storage.ErrKeyNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := &streamedValue{Reader: strings.NewReader(tt.data)}
+			mockStorage := &MockKVStreamStorage{}
+			if tt.err != nil {
+				mockStorage.On("OpenValue", "test-key").Return(nil, storage.Metadata{}, tt.err)
+			} else {
+				mockStorage.On("OpenValue", "test-key").Return(value, tt.meta, nil)
+			}
+
+			handler := NewKV(logger, mockStorage, "/api/v1/kv", nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/kv/test-key", nil)
+			req.SetPathValue("key", "test-key")
+			for name, value := range tt.header {
+				req.Header.Set(name, value)
+			}
+			w := httptest.NewRecorder()
+
+			handler.Get(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedContentType, w.Header().Get("Content-Type"))
+				assert.Equal(t, tt.expectedBody, w.Body.String())
+				var decoded any
+				if tt.meta.ContentType == "" {
+					assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &decoded))
+				}
+			}
+			if tt.err == nil {
+				assert.True(t, value.closed, "value must be closed")
+				assert.Equal(t, `"0000000000000001"`, w.Header().Get("ETag"))
+			}
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestKV_Head(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	tests := []struct {
+		name                string
+		query               string
+		header              map[string]string
+		mockSetup           func(*MockKVMetadataStorage)
+		expectedStatus      int
+		expectedContentType string
+	}{
+		{
+			name: "json value",
+			mockSetup: func(ms *MockKVMetadataStorage) {
+				ms.On("Metadata", "test-key").Return(storage.Metadata{Version: 1}, nil)
+			},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json; charset=utf-8",
+		},
+		{
+			name: "blob",
+			mockSetup: func(ms *MockKVMetadataStorage) {
+				ms.On("Metadata", "test-key").Return(storage.Metadata{Version: 1, ContentType: "image/png"}, nil)
+			},
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "image/png",
+		},
+		{
+			name:   "not modified",
+			header: map[string]string{"If-None-Match": `"0000000000000001"`},
+			mockSetup: func(ms *MockKVMetadataStorage) {
+				ms.On("Metadata", "test-key").Return(storage.Metadata{Version: 1}, nil)
+			},
+			expectedStatus: http.StatusNotModified,
+		},
+		{
+			name: "not found",
+			mockSetup: func(ms *MockKVMetadataStorage) {
+				ms.On("Metadata", "test-key").Return(storage.Metadata{}, storage.ErrKeyNotFound)
+			},
+			expectedStatus:      http.StatusNotFound,
+			expectedContentType: "application/json",
+		},
+		{
+			name:  "deleted key is gone",
+			query: "?include_deleted",
+			mockSetup: func(ms *MockKVMetadataStorage) {
+				ms.On("Metadata", "test-key").Return(storage.Metadata{}, storage.ErrKeyDeleted)
+			},
+			expectedStatus:      http.StatusGone,
+			expectedContentType: "application/json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := &MockKVMetadataStorage{}
+			tt.mockSetup(mockStorage)
+
+			handler := NewKV(logger, mockStorage, "/api/v1/kv", nil)
+
+			req := httptest.NewRequest(http.MethodHead, "/api/v1/kv/test-key"+tt.query, nil)
+			req.SetPathValue("key", "test-key")
+			for name, value := range tt.header {
+				req.Header.Set(name, value)
+			}
+			w := httptest.NewRecorder()
+
+			handler.Head(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedContentType != "" {
+				assert.Contains(t, w.Header().Get("Content-Type"), tt.expectedContentType)
+			}
+			mockStorage.AssertExpectations(t)
+		})
+	}
+
+	t.Run("storage without metadata", func(t *testing.T) {
+		mockStorage := &MockKVStorage{}
+		mockStorage.On("Get", "test-key").Return(storage.Blob{ContentType: "image/png", Data: []byte("data")}, nil)
+
+		handler := NewKV(logger, mockStorage, "/api/v1/kv", nil)
+
+		req := httptest.NewRequest(http.MethodHead, "/api/v1/kv/test-key", nil)
+		req.SetPathValue("key", "test-key")
+		w := httptest.NewRecorder()
+
+		handler.Head(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+		assert.Empty(t, w.Header().Get("ETag"))
+		mockStorage.AssertExpectations(t)
+	})
+}
+
+// MockKVModifierStorage modifies the value the Modify call returns.
+type MockKVModifierStorage struct {
+	MockKVStorage
+}
+
+func (m *MockKVModifierStorage) Modify(key string, fn func(value any) (any, error)) error {
+	args := m.Called(key)
+	if err := args.Error(1); err != nil {
+		return err
+	}
+	_, err := fn(args.Get(0))
+	return err
+}
+
+func TestKV_Patch(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	current := map[string]any{"name": "John", "age": float64(20)}
+
+	registry := schema.NewRegistry()
+	assert.NoError(t, registry.Register("test-", []byte(`{"properties": {"age": {"minimum": 0}}}`)))
+	padding := strings.Repeat(" ", maxPatchSize)
+
+	tests := []struct {
+		name           string
+		contentType    string
+		body           string
+		value          any
+		err            error
+		validator      ValueValidator
+		expectedStatus int
+		expectedValue  any
+	}{
+		{
+			name:           "merge patch",
+			contentType:    "application/merge-patch+json",
+			body:           `{"age":21,"name":null}`,
+			value:          current,
+			expectedStatus: http.StatusOK,
+			expectedValue:  map[string]any{"age": float64(21)},
+		},
+		{
+			name:           "json patch",
+			contentType:    "application/json-patch+json",
+			body:           `[{"op":"test","path":"/age","value":20},{"op":"replace","path":"/age","value":21}]`,
+			value:          current,
+			expectedStatus: http.StatusOK,
+			expectedValue:  map[string]any{"name": "John", "age": float64(21)},
+		},
+		{
+			name:           "json patch test fails",
+			contentType:    "application/json-patch+json",
+			body:           `[{"op":"test","path":"/age","value":30},{"op":"replace","path":"/age","value":31}]`,
+			value:          current,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "json patch path not found",
+			contentType:    "application/json-patch+json",
+			body:           `[{"op":"remove","path":"/email"}]`,
+			value:          current,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "malformed json patch",
+			contentType:    "application/json-patch+json",
+			body:           `[{"op":"rename","path":"/age"}]`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "malformed merge patch",
+			contentType:    "application/merge-patch+json",
+			body:           `{"age":`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "merge patch too large",
+			contentType:    "application/merge-patch+json",
+			body:           padding + `{"age":21}`,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "json patch too large",
+			contentType:    "application/json-patch+json",
+			body:           padding + `[]`,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "unsupported content type",
+			contentType:    "application/json",
+			body:           `{"age":21}`,
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:           "key not found",
+			contentType:    "application/merge-patch+json",
+			body:           `{"age":21}`,
+			err:            storage.ErrKeyNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "conflicts with concurrent writes",
+			contentType:    "application/merge-patch+json",
+			body:           `{"age":21}`,
+			err:            fmt.Errorf("%w: aborted", storage.ErrConflict),
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "blob value",
+			contentType:    "application/merge-patch+json",
+			body:           `{"age":21}`,
+			value:          storage.Blob{ContentType: "image/png", Data: []byte("data")},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "patched value does not match schema",
+			contentType:    "application/merge-patch+json",
+			body:           `{"age":-1}`,
+			value:          current,
+			validator:      registry,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := &MockKVModifierStorage{}
+			if tt.value != nil || tt.err != nil {
+				mockStorage.On("Modify", "test-key").Return(tt.value, tt.err)
+			}
+
+			handler := NewKV(logger, mockStorage, "/api/v1/kv", tt.validator)
+
+			req := httptest.NewRequest(http.MethodPatch, "/api/v1/kv/test-key", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			req.SetPathValue("key", "test-key")
+			w := httptest.NewRecorder()
+
+			handler.Patch(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedValue != nil {
+				var resp struct {
+					Details struct {
+						Value any `json:"value"`
+					} `json:"details"`
+				}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, tt.expectedValue, resp.Details.Value)
+			}
+			assert.Equal(t, map[string]any{"name": "John", "age": float64(20)}, current, "stored value must be left as it is")
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+type MockKVCompareStorage struct {
+	MockKVMetadataStorage
+}
+
+func (m *MockKVCompareStorage) CompareAndSwap(key string, version uint64, value any) error {
+	args := m.Called(key, version, value)
+	return args.Error(0)
+}
+
+func TestKV_UpdateIfMatch(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	meta := storage.Metadata{Version: 0xaf63bd4c8601b7be}
+
+	tests := []struct {
+		name           string
+		ifMatch        string
+		mockSetup      func(*MockKVCompareStorage)
+		expectedStatus int
+	}{
+		{
+			name:    "matching entity tag",
+			ifMatch: `"0000000000000001", "af63bd4c8601b7be"`,
+			mockSetup: func(ms *MockKVCompareStorage) {
+				ms.On("Metadata", "test-key").Return(meta, nil)
+				ms.On("CompareAndSwap", "test-key", meta.Version, "value").Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:    "other entity tag",
+			ifMatch: `"0000000000000001"`,
+			mockSetup: func(ms *MockKVCompareStorage) {
+				ms.On("Metadata", "test-key").Return(meta, nil)
+			},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:    "weak entity tag",
+			ifMatch: `W/"af63bd4c8601b7be"`,
+			mockSetup: func(ms *MockKVCompareStorage) {
+				ms.On("Metadata", "test-key").Return(meta, nil)
+			},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:    "changed meanwhile",
+			ifMatch: `"af63bd4c8601b7be"`,
+			mockSetup: func(ms *MockKVCompareStorage) {
+				ms.On("Metadata", "test-key").Return(meta, nil)
+				ms.On("CompareAndSwap", "test-key", meta.Version, "value").Return(storage.ErrVersionMismatch)
+			},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:    "key not found",
+			ifMatch: `"af63bd4c8601b7be"`,
+			mockSetup: func(ms *MockKVCompareStorage) {
+				ms.On("Metadata", "test-key").Return(storage.Metadata{}, storage.ErrKeyNotFound)
+			},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := &MockKVCompareStorage{}
+			tt.mockSetup(mockStorage)
+
+			handler := NewKV(logger, mockStorage, "/api/v1/kv", nil)
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/kv/test-key", bytes.NewBufferString(`{"value":"value"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("If-Match", tt.ifMatch)
+			req.SetPathValue("key", "test-key")
+			w := httptest.NewRecorder()
+
+			handler.Update(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
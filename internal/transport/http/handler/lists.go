@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// maxListPopCount is the maximum number of values popped at once.
+const maxListPopCount = 1000
+
+// ListStorage is the contract for the storage of lists.
+type ListStorage interface {
+	// ListPush pushes the values to the head of the list if left is set or
+	// to its tail otherwise and returns the length of the list.
+	ListPush(key string, left bool, values []any) (int, error)
+	// ListPop removes up to count values from the head of the list if left
+	// is set or from its tail otherwise and returns them.
+	ListPop(key string, left bool, count int) ([]any, error)
+	// ListRange returns the values of the list from start to stop inclusive.
+	ListRange(key string, start, stop int) ([]any, error)
+}
+
+// Lists is the HTTP handler for lists.
+type Lists struct {
+	log     *slog.Logger
+	storage ListStorage
+}
+
+// NewLists creates a new HTTP handler for lists.
+func NewLists(log *slog.Logger, storage ListStorage) *Lists {
+	return &Lists{
+		log:     log,
+		storage: storage,
+	}
+}
+
+type listPushRequest struct {
+	Values []any `json:"values"`
+	Left   bool  `json:"left"`
+}
+
+type listPopRequest struct {
+	Count *int `json:"count"`
+	Left  bool `json:"left"`
+}
+
+// Push pushes the values to the list, creating it if need be.
+func (h *Lists) Push(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	var req listPushRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONErr(h.log, w, http.StatusUnprocessableEntity, fmt.Sprintf("decode request: %s", err.Error()))
+		return
+	}
+
+	if len(req.Values) == 0 {
+		writeJSONErr(h.log, w, http.StatusBadRequest, "values cannot be empty")
+		return
+	}
+
+	length, err := h.storage.ListPush(key, req.Left, req.Values)
+	if err != nil {
+		h.log.Error("failed to push to list", slog.String("error", err.Error()))
+		writeStructureErr(h.log, w, err)
+		return
+	}
+
+	writeJSONSuccess(h.log, w, http.StatusOK, map[string]any{"key": key, "length": length})
+}
+
+// Pop removes values from the list and returns them. One value is popped
+// unless the count is set.
+func (h *Lists) Pop(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	var req listPopRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONErr(h.log, w, http.StatusUnprocessableEntity, fmt.Sprintf("decode request: %s", err.Error()))
+		return
+	}
+
+	count := 1
+	if req.Count != nil {
+		count = *req.Count
+	}
+	if count < 1 || count > maxListPopCount {
+		writeJSONErr(h.log, w, http.StatusBadRequest, fmt.Sprintf("count must be between 1 and %d", maxListPopCount))
+		return
+	}
+
+	values, err := h.storage.ListPop(key, req.Left, count)
+	if err != nil {
+		h.log.Error("failed to pop from list", slog.String("error", err.Error()))
+		writeStructureErr(h.log, w, err)
+		return
+	}
+
+	writeJSONSuccess(h.log, w, http.StatusOK, map[string]any{"key": key, "values": values})
+}
+
+// Range returns the values of the list from start to stop inclusive, the
+// whole list by default. Negative indexes count from the tail.
+func (h *Lists) Range(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	query := r.URL.Query()
+
+	start, stop := 0, -1
+	for name, index := range map[string]*int{"start": &start, "stop": &stop} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+
+		var err error
+		if *index, err = strconv.Atoi(raw); err != nil {
+			writeJSONErr(h.log, w, http.StatusBadRequest, fmt.Sprintf("%s must be an integer", name))
+			return
+		}
+	}
+
+	values, err := h.storage.ListRange(key, start, stop)
+	if err != nil {
+		h.log.Error("failed to get list range", slog.String("error", err.Error()))
+		writeStructureErr(h.log, w, err)
+		return
+	}
+
+	writeJSONSuccess(h.log, w, http.StatusOK, map[string]any{"key": key, "values": values})
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+type MockListStorage struct {
+	mock.Mock
+}
+
+func (m *MockListStorage) ListPush(key string, left bool, values []any) (int, error) {
+	args := m.Called(key, left, values)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockListStorage) ListPop(key string, left bool, count int) ([]any, error) {
+	args := m.Called(key, left, count)
+	return args.Get(0).([]any), args.Error(1)
+}
+
+func (m *MockListStorage) ListRange(key string, start, stop int) ([]any, error) {
+	args := m.Called(key, start, stop)
+	return args.Get(0).([]any), args.Error(1)
+}
+
+func TestLists(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	tests := []struct {
+		name           string
+		call           func(h *Lists) http.HandlerFunc
+		target         string
+		body           string
+		mockSetup      func(*MockListStorage)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "push",
+			call: func(h *Lists) http.HandlerFunc { return h.Push },
+			body: `{"values": ["a", 1], "left": true}`,
+			mockSetup: func(m *MockListStorage) {
+				m.On("ListPush", "queue", true, []any{"a", float64(1)}).Return(3, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","code":200,"details":{"key":"queue","length":3}}`,
+		},
+		{
+			name:           "push without values",
+			call:           func(h *Lists) http.HandlerFunc { return h.Push },
+			body:           `{"values": []}`,
+			mockSetup:      func(m *MockListStorage) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "push to key of another type",
+			call: func(h *Lists) http.HandlerFunc { return h.Push },
+			body: `{"values": ["a"]}`,
+			mockSetup: func(m *MockListStorage) {
+				m.On("ListPush", "queue", false, []any{"a"}).Return(0, storage.ErrWrongType)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name: "pop one value by default",
+			call: func(h *Lists) http.HandlerFunc { return h.Pop },
+			body: `{}`,
+			mockSetup: func(m *MockListStorage) {
+				m.On("ListPop", "queue", false, 1).Return([]any{"a"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","code":200,"details":{"key":"queue","values":["a"]}}`,
+		},
+		{
+			name:           "pop invalid count",
+			call:           func(h *Lists) http.HandlerFunc { return h.Pop },
+			body:           `{"count": 0}`,
+			mockSetup:      func(m *MockListStorage) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "range",
+			call:   func(h *Lists) http.HandlerFunc { return h.Range },
+			target: "?start=1&stop=-2",
+			mockSetup: func(m *MockListStorage) {
+				m.On("ListRange", "queue", 1, -2).Return([]any{"b"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "whole range by default",
+			call:   func(h *Lists) http.HandlerFunc { return h.Range },
+			target: "",
+			mockSetup: func(m *MockListStorage) {
+				m.On("ListRange", "queue", 0, -1).Return([]any{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","code":200,"details":{"key":"queue","values":[]}}`,
+		},
+		{
+			name:           "range invalid start",
+			call:           func(h *Lists) http.HandlerFunc { return h.Range },
+			target:         "?start=a",
+			mockSetup:      func(m *MockListStorage) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lists := &MockListStorage{}
+			tt.mockSetup(lists)
+
+			handler := NewLists(logger, lists)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/lists/queue"+tt.target, bytes.NewBufferString(tt.body))
+			req.SetPathValue("key", "queue")
+			w := httptest.NewRecorder()
+
+			tt.call(handler)(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+			lists.AssertExpectations(t)
+		})
+	}
+}
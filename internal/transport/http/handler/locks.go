@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+// maxLockTTL is the maximum duration of a lease.
+const maxLockTTL = 24 * time.Hour
+
+// LockManager is the contract for the storage of lock leases.
+type LockManager interface {
+	// Acquire acquires the lock for ttl or returns an error if it is held.
+	Acquire(name string, ttl time.Duration) (storage.Lease, error)
+	// Renew extends the lease of the lock held with the token.
+	Renew(name, token string, ttl time.Duration) (storage.Lease, error)
+	// Release releases the lock held with the token.
+	Release(name, token string) error
+	// Get returns the current lease of the lock without its token.
+	Get(name string) (storage.Lease, error)
+}
+
+// Locks is the HTTP handler for distributed locks.
+type Locks struct {
+	log   *slog.Logger
+	locks LockManager
+}
+
+// NewLocks creates a new HTTP handler for distributed locks.
+func NewLocks(log *slog.Logger, locks LockManager) *Locks {
+	return &Locks{
+		log:   log,
+		locks: locks,
+	}
+}
+
+type lockRequest struct {
+	Token string `json:"token"`
+	// TTL is the duration of the lease in seconds.
+	TTL float64 `json:"ttl"`
+}
+
+// Acquire acquires the lock and returns the lease with the token to renew
+// and release it.
+func (h *Locks) Acquire(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	_, ttl, ok := h.decode(w, r, false)
+	if !ok {
+		return
+	}
+
+	lease, err := h.locks.Acquire(name, ttl)
+	if err != nil {
+		h.log.Error("failed to acquire lock", slog.String("error", err.Error()))
+		h.handleStorageError(w, err)
+		return
+	}
+
+	writeJSONSuccess(h.log, w, http.StatusCreated, lease)
+}
+
+// Renew extends the lease of the lock held with the token.
+func (h *Locks) Renew(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	req, ttl, ok := h.decode(w, r, true)
+	if !ok {
+		return
+	}
+
+	lease, err := h.locks.Renew(name, req.Token, ttl)
+	if err != nil {
+		h.log.Error("failed to renew lock", slog.String("error", err.Error()))
+		h.handleStorageError(w, err)
+		return
+	}
+
+	writeJSONSuccess(h.log, w, http.StatusOK, lease)
+}
+
+// Release releases the lock held with the token.
+func (h *Locks) Release(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	var req lockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONErr(h.log, w, http.StatusUnprocessableEntity, fmt.Sprintf("decode request: %s", err.Error()))
+		return
+	}
+
+	if req.Token == "" {
+		writeJSONErr(h.log, w, http.StatusBadRequest, "token cannot be empty")
+		return
+	}
+
+	if err := h.locks.Release(name, req.Token); err != nil {
+		h.log.Error("failed to release lock", slog.String("error", err.Error()))
+		h.handleStorageError(w, err)
+		return
+	}
+
+	writeJSONSuccess(h.log, w, http.StatusOK, map[string]any{"name": name})
+}
+
+// Get returns the current lease of the lock.
+func (h *Locks) Get(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	lease, err := h.locks.Get(name)
+	if errors.Is(err, storage.ErrLockNotHeld) {
+		writeJSONErr(h.log, w, http.StatusNotFound, "lock is not held")
+		return
+	}
+	if err != nil {
+		h.log.Error("failed to get lock", slog.String("error", err.Error()))
+		h.handleStorageError(w, err)
+		return
+	}
+
+	writeJSONSuccess(h.log, w, http.StatusOK, lease)
+}
+
+// decode decodes the request with the TTL of the lease and, if withToken is
+// set, the token, and writes the error response if it is not valid.
+func (h *Locks) decode(w http.ResponseWriter, r *http.Request, withToken bool) (lockRequest, time.Duration, bool) {
+	var req lockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONErr(h.log, w, http.StatusUnprocessableEntity, fmt.Sprintf("decode request: %s", err.Error()))
+		return lockRequest{}, 0, false
+	}
+
+	if withToken && req.Token == "" {
+		writeJSONErr(h.log, w, http.StatusBadRequest, "token cannot be empty")
+		return lockRequest{}, 0, false
+	}
+
+	ttl := time.Duration(req.TTL * float64(time.Second))
+	if ttl <= 0 || ttl > maxLockTTL {
+		writeJSONErr(h.log, w, http.StatusBadRequest, fmt.Sprintf("ttl must be positive and at most %d seconds", int(maxLockTTL.Seconds())))
+		return lockRequest{}, 0, false
+	}
+
+	return req, ttl, true
+}
+
+func (h *Locks) handleStorageError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrLockHeld):
+		writeJSONErrCode(h.log, w, http.StatusConflict, errCodeLockHeld, "lock is held")
+	case errors.Is(err, storage.ErrLockNotHeld):
+		writeJSONErrCode(h.log, w, http.StatusConflict, errCodeLockNotHeld, "lock is not held")
+	default:
+		writeStorageFailure(h.log, w, err)
+	}
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+type MockLockManager struct {
+	mock.Mock
+}
+
+func (m *MockLockManager) Acquire(name string, ttl time.Duration) (storage.Lease, error) {
+	args := m.Called(name, ttl)
+	return args.Get(0).(storage.Lease), args.Error(1)
+}
+
+func (m *MockLockManager) Renew(name, token string, ttl time.Duration) (storage.Lease, error) {
+	args := m.Called(name, token, ttl)
+	return args.Get(0).(storage.Lease), args.Error(1)
+}
+
+func (m *MockLockManager) Release(name, token string) error {
+	args := m.Called(name, token)
+	return args.Error(0)
+}
+
+func (m *MockLockManager) Get(name string) (storage.Lease, error) {
+	args := m.Called(name)
+	return args.Get(0).(storage.Lease), args.Error(1)
+}
+
+func TestLocks(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	lease := storage.Lease{Name: "job", Token: "abc", FencingToken: 7, ExpiresAt: time.Now().Add(time.Minute)}
+
+	tests := []struct {
+		name           string
+		call           func(h *Locks) http.HandlerFunc
+		body           string
+		mockSetup      func(*MockLockManager)
+		expectedStatus int
+	}{
+		{
+			name: "acquire",
+			call: func(h *Locks) http.HandlerFunc { return h.Acquire },
+			body: `{"ttl": 30}`,
+			mockSetup: func(m *MockLockManager) {
+				m.On("Acquire", "job", 30*time.Second).Return(lease, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "acquire held lock",
+			call: func(h *Locks) http.HandlerFunc { return h.Acquire },
+			body: `{"ttl": 0.5}`,
+			mockSetup: func(m *MockLockManager) {
+				m.On("Acquire", "job", 500*time.Millisecond).Return(storage.Lease{}, storage.ErrLockHeld)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "acquire without ttl",
+			call:           func(h *Locks) http.HandlerFunc { return h.Acquire },
+			body:           `{}`,
+			mockSetup:      func(m *MockLockManager) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "renew",
+			call: func(h *Locks) http.HandlerFunc { return h.Renew },
+			body: `{"token": "abc", "ttl": 30}`,
+			mockSetup: func(m *MockLockManager) {
+				m.On("Renew", "job", "abc", 30*time.Second).Return(lease, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "renew without token",
+			call:           func(h *Locks) http.HandlerFunc { return h.Renew },
+			body:           `{"ttl": 30}`,
+			mockSetup:      func(m *MockLockManager) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "release with wrong token",
+			call: func(h *Locks) http.HandlerFunc { return h.Release },
+			body: `{"token": "other"}`,
+			mockSetup: func(m *MockLockManager) {
+				m.On("Release", "job", "other").Return(storage.ErrLockNotHeld)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name: "get free lock",
+			call: func(h *Locks) http.HandlerFunc { return h.Get },
+			mockSetup: func(m *MockLockManager) {
+				m.On("Get", "job").Return(storage.Lease{}, storage.ErrLockNotHeld)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locks := &MockLockManager{}
+			tt.mockSetup(locks)
+
+			handler := NewLocks(logger, locks)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/locks/job", bytes.NewBufferString(tt.body))
+			req.SetPathValue("name", "job")
+			w := httptest.NewRecorder()
+
+			tt.call(handler)(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			locks.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package handler
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/tmybsv/tarantool-kv/internal/schema"
+)
+
+// maxValidatedResponseSize is the size of the largest response body
+// validated against the spec, the bodies of larger responses are not.
+const maxValidatedResponseSize = 1 << 20
+
+// specPrefix maps the base path of routes to the path of the spec.
+type specPrefix struct {
+	path string
+	spec string
+}
+
+// SpecValidator validates the requests to the operations of the OpenAPI spec
+// and, if enabled, the responses to them. Requests to the paths the spec does
+// not describe are passed as they are.
+type SpecValidator struct {
+	log               *slog.Logger
+	router            routers.Router
+	prefixes          []specPrefix
+	validateResponses bool
+}
+
+// NewSpecValidator returns the validator of the spec. The paths map the base
+// paths the routes are served under to the paths of the spec, such as
+// /api/v1/kv to /kv. Responses not matching the spec are logged only, they
+// are validated if validateResponses is set.
+func NewSpecValidator(log *slog.Logger, doc *openapi3.T, paths map[string]string, validateResponses bool) (*SpecValidator, error) {
+	// The servers of the spec are replaced with the paths, so the routes are
+	// matched by the path of the spec alone.
+	spec := *doc
+	spec.Servers = nil
+	router, err := gorillamux.NewRouter(&spec)
+	if err != nil {
+		return nil, fmt.Errorf("build router of the spec: %w", err)
+	}
+
+	prefixes := make([]specPrefix, 0, len(paths))
+	for path, specPath := range paths {
+		prefixes = append(prefixes, specPrefix{path: strings.TrimSuffix(path, "/"), spec: strings.TrimSuffix(specPath, "/")})
+	}
+	slices.SortFunc(prefixes, func(a, b specPrefix) int {
+		return cmp.Compare(len(b.path), len(a.path))
+	})
+
+	return &SpecValidator{
+		log:               log,
+		router:            router,
+		prefixes:          prefixes,
+		validateResponses: validateResponses,
+	}, nil
+}
+
+// Handler returns the middleware validating the requests to next. Requests
+// not matching the spec are answered with 400 and the violations of the spec.
+// Bodies that are not JSON are not validated, nor are the malformed JSON ones,
+// the handlers report them.
+func (v *SpecValidator) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		input, ok := v.input(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+			if violations := requestViolations(err); len(violations) > 0 {
+				writeJSONErrCode(v.log, w, http.StatusBadRequest, errCodeInvalidRequest, map[string]any{
+					"message":    "request does not match the API spec",
+					"violations": violations,
+				})
+				return
+			}
+		}
+		// The body read by the validation is buffered in the request of
+		// the input.
+		r.Body = input.Request.Body
+
+		if !v.validateResponses {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		sw := &specResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		v.validateResponse(input, sw)
+	})
+}
+
+// input returns the validation input of the request, false if the spec has
+// no operation of the request.
+func (v *SpecValidator) input(r *http.Request) (*openapi3filter.RequestValidationInput, bool) {
+	path, ok := v.specPath(r.URL.EscapedPath())
+	if !ok {
+		return nil, false
+	}
+
+	req := r.Clone(r.Context())
+	req.URL.RawPath = path
+	var err error
+	if req.URL.Path, err = url.PathUnescape(path); err != nil {
+		return nil, false
+	}
+
+	route, params, err := v.router.FindRoute(req)
+	if err != nil {
+		return nil, false
+	}
+
+	return &openapi3filter.RequestValidationInput{
+		Request:    req,
+		PathParams: params,
+		Route:      route,
+		Options: &openapi3filter.Options{
+			ExcludeRequestBody:  !isJSONMediaType(r.Header.Get("Content-Type")),
+			MultiError:          true,
+			SkipSettingDefaults: true,
+			AuthenticationFunc:  openapi3filter.NoopAuthenticationFunc,
+		},
+	}, true
+}
+
+// specPath returns the path of the spec for the escaped path of the request,
+// false if the path is not under any of the base paths.
+func (v *SpecValidator) specPath(path string) (string, bool) {
+	for _, prefix := range v.prefixes {
+		if path == prefix.path || strings.HasPrefix(path, prefix.path+"/") {
+			return prefix.spec + path[len(prefix.path):], true
+		}
+	}
+	return "", false
+}
+
+// validateResponse logs the mismatches between the response and the spec.
+// Streams and problems, which the spec does not describe per operation, are
+// not validated.
+func (v *SpecValidator) validateResponse(input *openapi3filter.RequestValidationInput, w *specResponseWriter) {
+	if w.status == 0 {
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if mediaType == "text/event-stream" || mediaType == "application/problem+json" {
+		return
+	}
+
+	resp := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 w.status,
+		Header:                 w.Header(),
+		Options: &openapi3filter.Options{
+			ExcludeResponseBody: mediaType != "application/json" || w.truncated,
+			MultiError:          true,
+		},
+	}
+	resp.SetBodyBytes(w.body.Bytes())
+
+	if err := openapi3filter.ValidateResponse(input.Request.Context(), resp); err != nil {
+		v.log.Warn("response does not match the API spec",
+			slog.String("method", input.Request.Method),
+			slog.String("path", input.Route.Path),
+			slog.Int("status", w.status),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// isJSONMediaType reports whether the content type is the JSON one. Unlike
+// isJSONContentType, requests without a content type are not JSON as the
+// spec does not describe them.
+func isJSONMediaType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
+
+// requestViolations returns the violations of the spec by the request. Body
+// parse errors are skipped, the handlers report malformed bodies.
+func requestViolations(err error) []schema.Violation {
+	var violations []schema.Violation
+	for _, err := range flattenErrors(err) {
+		var reqErr *openapi3filter.RequestError
+		if !errors.As(err, &reqErr) {
+			violations = append(violations, schema.Violation{Message: err.Error()})
+			continue
+		}
+
+		switch {
+		case reqErr.Parameter != nil:
+			violations = append(violations, schema.Violation{
+				Message: fmt.Sprintf("%s parameter %q: %s", reqErr.Parameter.In, reqErr.Parameter.Name, errorReason(reqErr)),
+			})
+		case reqErr.RequestBody != nil:
+			var parseErr *openapi3filter.ParseError
+			if errors.As(reqErr.Err, &parseErr) {
+				continue
+			}
+			var schemaErrs []*openapi3.SchemaError
+			for _, err := range flattenErrors(reqErr.Err) {
+				var schemaErr *openapi3.SchemaError
+				if errors.As(err, &schemaErr) {
+					schemaErrs = append(schemaErrs, schemaErr)
+				}
+			}
+			if len(schemaErrs) == 0 {
+				violations = append(violations, schema.Violation{Message: "request body: " + errorReason(reqErr)})
+			}
+			for _, schemaErr := range schemaErrs {
+				violations = append(violations, schema.Violation{
+					Path:    jsonPointer(schemaErr.JSONPointer()),
+					Message: schemaErr.Reason,
+				})
+			}
+		default:
+			violations = append(violations, schema.Violation{Message: errorReason(reqErr)})
+		}
+	}
+	return violations
+}
+
+// flattenErrors returns the errors of the nested multi errors. The errors
+// wrapping multi errors are not flattened.
+func flattenErrors(err error) []error {
+	me, ok := err.(openapi3.MultiError)
+	if !ok {
+		return []error{err}
+	}
+
+	var errs []error
+	for _, err := range me {
+		errs = append(errs, flattenErrors(err)...)
+	}
+	return errs
+}
+
+// errorReason returns the reason of the request error without the dumps of
+// the schemas the errors of the schemas have.
+func errorReason(err *openapi3filter.RequestError) string {
+	if err.Err == nil {
+		return err.Reason
+	}
+
+	reasons := make([]string, 0, 1)
+	for _, err := range flattenErrors(err.Err) {
+		var schemaErr *openapi3.SchemaError
+		if errors.As(err, &schemaErr) {
+			reasons = append(reasons, schemaErr.Reason)
+		} else {
+			reasons = append(reasons, err.Error())
+		}
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// jsonPointer returns the JSON pointer of the path of the value.
+func jsonPointer(path []string) string {
+	var b strings.Builder
+	for _, token := range path {
+		b.WriteByte('/')
+		b.WriteString(strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1"))
+	}
+	return b.String()
+}
+
+// specResponseWriter records the status code and the body of the response to
+// validate them against the spec.
+type specResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+	// truncated is set once the body exceeds maxValidatedResponseSize.
+	truncated bool
+}
+
+func (w *specResponseWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *specResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.truncated {
+		if w.body.Len()+len(b) > maxValidatedResponseSize {
+			w.truncated = true
+			w.body.Reset()
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the original response writer for http.ResponseController.
+func (w *specResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
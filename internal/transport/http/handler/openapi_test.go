@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmybsv/tarantool-kv/api"
+)
+
+func newTestSpecValidator(t *testing.T, log *slog.Logger, base string, validateResponses bool) *SpecValidator {
+	t.Helper()
+
+	doc, err := api.LoadOpenAPI()
+	require.NoError(t, err)
+
+	validator, err := NewSpecValidator(log, doc, map[string]string{
+		base + "/kv":    "/kv",
+		base + "/locks": "/locks",
+	}, validateResponses)
+	require.NoError(t, err)
+	return validator
+}
+
+func TestSpecValidator_Handler(t *testing.T) {
+	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	validator := newTestSpecValidator(t, log, "/api/v1", false)
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		contentType    string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "valid request",
+			method:         http.MethodGet,
+			target:         "/api/v1/kv/_keys?prefix=user:&limit=10",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "flag without value",
+			method:         http.MethodGet,
+			target:         "/api/v1/kv/user:1?include_deleted",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid query parameter",
+			method:         http.MethodGet,
+			target:         "/api/v1/kv/_keys?limit=0",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{
+				"status": "error",
+				"code": 400,
+				"error_code": "invalid_request",
+				"details": {
+					"message": "request does not match the API spec",
+					"violations": [
+						{"path": "", "message": "query parameter \"limit\": number must be at least 1"}
+					]
+				}
+			}`,
+		},
+		{
+			name:           "invalid body",
+			method:         http.MethodPost,
+			target:         "/api/v1/locks/jobs",
+			contentType:    "application/json",
+			body:           `{"ttl": "30"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{
+				"status": "error",
+				"code": 400,
+				"error_code": "invalid_request",
+				"details": {
+					"message": "request does not match the API spec",
+					"violations": [
+						{"path": "/ttl", "message": "value must be a number"}
+					]
+				}
+			}`,
+		},
+		{
+			name:           "malformed body",
+			method:         http.MethodPost,
+			target:         "/api/v1/locks/jobs",
+			contentType:    "application/json",
+			body:           `{"ttl": `,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "body of other content type",
+			method:         http.MethodPut,
+			target:         "/api/v1/kv/avatar",
+			contentType:    "image/png",
+			body:           "\x89PNG",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "path outside the spec",
+			method:         http.MethodGet,
+			target:         "/api/v1/unknown?limit=0",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "method outside the spec",
+			method:         http.MethodPatch,
+			target:         "/api/v1/kv/_keys?limit=0",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body []byte
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var err error
+				body, err = io.ReadAll(r.Body)
+				require.NoError(t, err)
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+
+			validator.Handler(next).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+				return
+			}
+			assert.Equal(t, tt.body, string(body), "body must reach the handler")
+		})
+	}
+}
+
+func TestSpecValidator_Handler_problems(t *testing.T) {
+	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	validator := newTestSpecValidator(t, log, "/api/v2", true)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSONErrCode(log, w, http.StatusConflict, errCodeLockHeld, "lock is held")
+	})
+	handler := Problems(log, validator.Handler(next))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/locks/jobs", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"type": "urn:tarantool-kv:problem:invalid_request",
+		"title": "Bad Request",
+		"status": 400,
+		"detail": "request does not match the API spec",
+		"instance": "/api/v2/locks/jobs",
+		"code": "invalid_request",
+		"errors": [
+			{"pointer": "/ttl", "detail": "property \"ttl\" is missing"}
+		]
+	}`, w.Body.String())
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v2/locks/jobs", strings.NewReader(`{"ttl": 30}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"), "errors of the handlers must stay problems")
+}
+
+func TestSpecValidator_Handler_responses(t *testing.T) {
+	tests := []struct {
+		name              string
+		validateResponses bool
+		response          string
+		expectedLog       bool
+	}{
+		{
+			name:              "matching response",
+			validateResponses: true,
+			response:          `{"status": "success", "code": 200, "details": {"key": "user:1"}}`,
+		},
+		{
+			name:              "mismatching response",
+			validateResponses: true,
+			response:          `{"key": "user:1"}`,
+			expectedLog:       true,
+		},
+		{
+			name:     "validation disabled",
+			response: `{"key": "user:1"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			log := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{
+				Level: slog.LevelWarn,
+			}))
+			validator := newTestSpecValidator(t, log, "/api/v1", tt.validateResponses)
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json; charset=utf-8")
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(tt.response))
+			})
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/kv/user:1", nil)
+			w := httptest.NewRecorder()
+			validator.Handler(next).ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.JSONEq(t, tt.response, w.Body.String())
+			if tt.expectedLog {
+				assert.Contains(t, logs.String(), "response does not match the API spec")
+			} else {
+				assert.Empty(t, logs.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/tmybsv/tarantool-kv/internal/schema"
+	"github.com/tmybsv/tarantool-kv/internal/transport/http/middleware"
+)
+
+// problemTypeBase is the prefix of the type URIs of problems, followed by
+// the error code.
+const problemTypeBase = "urn:tarantool-kv:problem:"
+
+// errCodeValidation is the error code of values not matching their schema.
+const errCodeValidation = "validation_failed"
+
+// fieldError is a violation of the schema by a part of the value.
+type fieldError struct {
+	// Pointer is the JSON pointer to the mismatching part of the value.
+	Pointer string `json:"pointer"`
+	Detail  string `json:"detail"`
+}
+
+// Problems returns a middleware answering the errors of the handlers with
+// RFC 7807 problem details rather than the error envelope, including the
+// errors written by http.Error, such as the ones of http.ServeMux for
+// unknown routes. Success responses are left as they are.
+func Problems(log *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&problemWriter{ResponseWriter: w, log: log, r: r}, r)
+	})
+}
+
+// problemWriter is the response writer of the routes answering errors with
+// problem details, see writeJSONErrCode.
+type problemWriter struct {
+	http.ResponseWriter
+	log *slog.Logger
+	r   *http.Request
+	// discard is set once a plain text error is replaced with a problem, to
+	// drop the text.
+	discard bool
+}
+
+// WriteHeader replaces the plain text errors of http.Error with problems.
+func (w *problemWriter) WriteHeader(statusCode int) {
+	if statusCode >= http.StatusBadRequest && strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		w.discard = true
+		w.writeProblem(w.log, statusCode, "", http.StatusText(statusCode))
+		return
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *problemWriter) Write(b []byte) (int, error) {
+	if w.discard {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the original response writer for http.ResponseController.
+func (w *problemWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// problemWriterOf returns the problem writer w is or wraps, false if the
+// route does not answer errors with problems.
+func problemWriterOf(w http.ResponseWriter) (*problemWriter, bool) {
+	for {
+		if pw, ok := w.(*problemWriter); ok {
+			return pw, true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil, false
+		}
+		w = u.Unwrap()
+	}
+}
+
+// writeProblem writes the problem of the error. The message of structured
+// details is the detail of the problem, schema violations are its errors and
+// the rest of the details are its extension members.
+func (w *problemWriter) writeProblem(log *slog.Logger, statusCode int, errorCode string, details any) {
+	problem := map[string]any{
+		"title":    http.StatusText(statusCode),
+		"status":   statusCode,
+		"instance": w.r.URL.Path,
+	}
+	if id := middleware.RequestIDFromContext(w.r.Context()); id != "" {
+		problem["request_id"] = id
+	}
+
+	switch details := details.(type) {
+	case string:
+		problem["detail"] = details
+	case map[string]any:
+		for name, value := range details {
+			switch name {
+			case "message":
+				problem["detail"] = value
+			case "violations":
+				violations, _ := value.([]schema.Violation)
+				errs := make([]fieldError, 0, len(violations))
+				for _, v := range violations {
+					errs = append(errs, fieldError{Pointer: v.Path, Detail: v.Message})
+				}
+				problem["errors"] = errs
+				if errorCode == "" {
+					errorCode = errCodeValidation
+				}
+			default:
+				problem[name] = value
+			}
+		}
+	default:
+		problem["details"] = details
+	}
+
+	if errorCode == "" {
+		errorCode = statusErrorCode(statusCode)
+	}
+	problem["type"] = problemTypeBase + errorCode
+	problem["code"] = errorCode
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(statusCode)
+	if err := json.NewEncoder(w.ResponseWriter).Encode(problem); err != nil {
+		log.Error("failed to encode problem", slog.String("error", err.Error()))
+	}
+}
+
+// statusErrorCode returns the error code of the errors that have no code of
+// their own, derived from the status code.
+func statusErrorCode(statusCode int) string {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return errCodeInvalidRequest
+	case http.StatusRequestEntityTooLarge:
+		return "payload_too_large"
+	case http.StatusInternalServerError:
+		return errCodeInternal
+	}
+	text := http.StatusText(statusCode)
+	if text == "" {
+		return "error"
+	}
+	return strings.ReplaceAll(strings.ToLower(strings.ReplaceAll(text, "-", " ")), " ", "_")
+}
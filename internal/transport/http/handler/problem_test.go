@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmybsv/tarantool-kv/internal/schema"
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+	"github.com/tmybsv/tarantool-kv/internal/transport/http/middleware"
+)
+
+func TestProblems(t *testing.T) {
+	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	registry := schema.NewRegistry()
+	require.NoError(t, registry.Register("user:", []byte(`{"type": "object", "required": ["name"]}`)))
+
+	mockStorage := &MockKVStorage{}
+	mockStorage.On("Get", "missing").Return(nil, storage.ErrKeyNotFound)
+	mockStorage.On("Get", "present").Return("value", nil)
+	kv := NewKV(log, mockStorage, "/api/v2/kv", registry)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v2/kv/{key}", kv.Get)
+	mux.HandleFunc("POST /api/v2/kv", kv.Set)
+	handler := middleware.RequestID(Problems(log, mux))
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "coded error",
+			method:         http.MethodGet,
+			target:         "/api/v2/kv/missing",
+			expectedStatus: http.StatusNotFound,
+			expectedBody: `{
+				"type": "urn:tarantool-kv:problem:key_not_found",
+				"title": "Not Found",
+				"status": 404,
+				"detail": "key not found",
+				"instance": "/api/v2/kv/missing",
+				"code": "key_not_found",
+				"request_id": "req-1"
+			}`,
+		},
+		{
+			name:           "uncoded error",
+			method:         http.MethodPost,
+			target:         "/api/v2/kv",
+			body:           `{"key": "", "value": 1}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{
+				"type": "urn:tarantool-kv:problem:invalid_request",
+				"title": "Bad Request",
+				"status": 400,
+				"detail": "key cannot be empty",
+				"instance": "/api/v2/kv",
+				"code": "invalid_request",
+				"request_id": "req-1"
+			}`,
+		},
+		{
+			name:           "validation error",
+			method:         http.MethodPost,
+			target:         "/api/v2/kv",
+			body:           `{"key": "user:1", "value": {"age": 20}}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody: `{
+				"type": "urn:tarantool-kv:problem:validation_failed",
+				"title": "Unprocessable Entity",
+				"status": 422,
+				"detail": "value does not match schema for prefix \"user:\"",
+				"instance": "/api/v2/kv",
+				"code": "validation_failed",
+				"request_id": "req-1",
+				"errors": [{"pointer": "", "detail": "missing property 'name'"}]
+			}`,
+		},
+		{
+			name:           "unknown route",
+			method:         http.MethodGet,
+			target:         "/api/v2/unknown",
+			expectedStatus: http.StatusNotFound,
+			expectedBody: `{
+				"type": "urn:tarantool-kv:problem:not_found",
+				"title": "Not Found",
+				"status": 404,
+				"detail": "Not Found",
+				"instance": "/api/v2/unknown",
+				"code": "not_found",
+				"request_id": "req-1"
+			}`,
+		},
+		{
+			name:           "method not allowed",
+			method:         http.MethodPatch,
+			target:         "/api/v2/kv",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedBody: `{
+				"type": "urn:tarantool-kv:problem:method_not_allowed",
+				"title": "Method Not Allowed",
+				"status": 405,
+				"detail": "Method Not Allowed",
+				"instance": "/api/v2/kv",
+				"code": "method_not_allowed",
+				"request_id": "req-1"
+			}`,
+		},
+		{
+			name:           "success",
+			method:         http.MethodGet,
+			target:         "/api/v2/kv/present",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","code":200,"details":{"key":"present","value":"value"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, bytes.NewBufferString(tt.body))
+			req.Header.Set(middleware.RequestIDHeader, "req-1")
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			if tt.expectedStatus >= http.StatusBadRequest {
+				assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestStatusErrorCode(t *testing.T) {
+	assert.Equal(t, "invalid_request", statusErrorCode(http.StatusBadRequest))
+	assert.Equal(t, "payload_too_large", statusErrorCode(http.StatusRequestEntityTooLarge))
+	assert.Equal(t, "unsupported_media_type", statusErrorCode(http.StatusUnsupportedMediaType))
+	assert.Equal(t, "precondition_failed", statusErrorCode(http.StatusPreconditionFailed))
+	assert.Equal(t, "error", statusErrorCode(599))
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+const (
+	defaultQueryLimit = 100
+	maxQueryLimit     = 1000
+)
+
+// KVQuerier is the contract for the storage of keys searchable by indexed
+// fields of their values.
+type KVQuerier interface {
+	// Query returns a page of keys which values have the indexed field equal
+	// to the value, starting after the cursor.
+	Query(path, value string, limit uint32, cursor string) (storage.QueryPage, error)
+}
+
+// Query is the HTTP handler for querying keys by indexed value fields.
+type Query struct {
+	log     *slog.Logger
+	storage KVQuerier
+}
+
+// NewQuery creates a new HTTP handler for querying keys.
+func NewQuery(log *slog.Logger, storage KVQuerier) *Query {
+	return &Query{
+		log:     log,
+		storage: storage,
+	}
+}
+
+// List returns a page of keys which values have the field equal to eq.
+func (h *Query) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	field := query.Get("field")
+	if field == "" {
+		writeJSONErr(h.log, w, http.StatusBadRequest, "field cannot be empty")
+		return
+	}
+
+	if !query.Has("eq") {
+		writeJSONErr(h.log, w, http.StatusBadRequest, "eq cannot be empty")
+		return
+	}
+
+	limit := uint64(defaultQueryLimit)
+	if raw := query.Get("limit"); raw != "" {
+		var err error
+		limit, err = strconv.ParseUint(raw, 10, 32)
+		if err != nil || limit == 0 || limit > maxQueryLimit {
+			writeJSONErr(h.log, w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+	}
+
+	page, err := h.storage.Query(field, query.Get("eq"), uint32(limit), query.Get("cursor"))
+	if err != nil {
+		h.log.Error("failed to query keys", slog.String("error", err.Error()))
+		switch {
+		case errors.Is(err, storage.ErrFieldNotIndexed),
+			errors.Is(err, storage.ErrInvalidQueryValue),
+			errors.Is(err, storage.ErrInvalidCursor):
+			writeJSONErr(h.log, w, http.StatusBadRequest, err.Error())
+		default:
+			writeStorageFailure(h.log, w, err)
+		}
+		return
+	}
+
+	writeJSONSuccess(h.log, w, http.StatusOK, page)
+}
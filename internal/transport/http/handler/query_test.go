@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+type MockKVQuerier struct {
+	mock.Mock
+}
+
+func (m *MockKVQuerier) Query(path, value string, limit uint32, cursor string) (storage.QueryPage, error) {
+	args := m.Called(path, value, limit, cursor)
+	return args.Get(0).(storage.QueryPage), args.Error(1)
+}
+
+func TestQuery_List(t *testing.T) {
+	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	tests := []struct {
+		name           string
+		query          string
+		mockSetup      func(*MockKVQuerier)
+		expectedStatus int
+	}{
+		{
+			name:  "successful query",
+			query: "?field=country&eq=DE&limit=10&cursor=abc",
+			mockSetup: func(ms *MockKVQuerier) {
+				ms.On("Query", "country", "DE", uint32(10), "abc").Return(storage.QueryPage{
+					Items: []storage.KeyValue{{Key: "user:1", Value: map[string]any{"country": "DE"}}},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "default limit",
+			query: "?field=country&eq=DE",
+			mockSetup: func(ms *MockKVQuerier) {
+				ms.On("Query", "country", "DE", uint32(defaultQueryLimit), "").Return(storage.QueryPage{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "empty field",
+			query:          "?eq=DE",
+			mockSetup:      func(ms *MockKVQuerier) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid limit",
+			query:          "?field=country&eq=DE&limit=5000",
+			mockSetup:      func(ms *MockKVQuerier) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "field not indexed",
+			query: "?field=age&eq=1",
+			mockSetup: func(ms *MockKVQuerier) {
+				ms.On("Query", "age", "1", uint32(defaultQueryLimit), "").Return(storage.QueryPage{}, storage.ErrFieldNotIndexed)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := &MockKVQuerier{}
+			tt.mockSetup(mockStorage)
+
+			handler := NewQuery(log, mockStorage)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/kv/_query"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			handler.List(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/tmybsv/tarantool-kv/internal/schema"
+)
+
+// maxSchemaSize is the maximum size of a JSON Schema accepted by the admin
+// endpoint.
+const maxSchemaSize = 1 << 20
+
+// SchemaRegistry is the contract for the registry of JSON Schemas bound to
+// key prefixes.
+type SchemaRegistry interface {
+	// Register binds the schema to the key prefix.
+	Register(prefix string, schema []byte) error
+	// Remove unbinds the schema from the key prefix and reports whether there
+	// was one.
+	Remove(prefix string) (bool, error)
+	// List returns the registered schemas.
+	List() []schema.Schema
+}
+
+// Schemas is the HTTP handler for managing JSON Schemas of values.
+type Schemas struct {
+	log      *slog.Logger
+	registry SchemaRegistry
+}
+
+// NewSchemas creates a new HTTP handler for managing JSON Schemas.
+func NewSchemas(log *slog.Logger, registry SchemaRegistry) *Schemas {
+	return &Schemas{
+		log:      log,
+		registry: registry,
+	}
+}
+
+// List returns the registered schemas.
+func (h *Schemas) List(w http.ResponseWriter, r *http.Request) {
+	writeJSONSuccess(h.log, w, http.StatusOK, map[string]any{"schemas": h.registry.List()})
+}
+
+// Put binds the JSON Schema to the key prefix, both from the request body.
+// The prefix is not a path value, as it may hold slashes.
+func (h *Schemas) Put(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSchemaSize))
+	if err != nil {
+		writeJSONErr(h.log, w, http.StatusRequestEntityTooLarge, "schema is too large")
+		return
+	}
+
+	var req struct {
+		Prefix *string         `json:"prefix"`
+		Schema json.RawMessage `json:"schema"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeJSONErr(h.log, w, http.StatusUnprocessableEntity, fmt.Sprintf("decode request: %s", err.Error()))
+		return
+	}
+	if req.Prefix == nil || len(req.Schema) == 0 {
+		writeJSONErr(h.log, w, http.StatusBadRequest, "prefix and schema are required")
+		return
+	}
+	prefix := *req.Prefix
+
+	if err := h.registry.Register(prefix, req.Schema); err != nil {
+		if errors.Is(err, schema.ErrInvalidSchema) {
+			writeJSONErr(h.log, w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.log.Error("failed to register schema", slog.String("error", err.Error()))
+		writeJSONErr(h.log, w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	h.log.Info("schema registered", slog.String("prefix", prefix))
+	writeJSONSuccess(h.log, w, http.StatusOK, map[string]any{"prefix": prefix})
+}
+
+// Delete unbinds the JSON Schema from the key prefix of the query.
+func (h *Schemas) Delete(w http.ResponseWriter, r *http.Request) {
+	if !r.URL.Query().Has("prefix") {
+		writeJSONErr(h.log, w, http.StatusBadRequest, "prefix is required")
+		return
+	}
+	prefix := r.URL.Query().Get("prefix")
+
+	removed, err := h.registry.Remove(prefix)
+	if err != nil {
+		h.log.Error("failed to remove schema", slog.String("error", err.Error()))
+		writeJSONErr(h.log, w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if !removed {
+		writeJSONErr(h.log, w, http.StatusNotFound, "schema not found")
+		return
+	}
+
+	h.log.Info("schema removed", slog.String("prefix", prefix))
+	writeJSONSuccess(h.log, w, http.StatusOK, map[string]any{"prefix": prefix})
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tmybsv/tarantool-kv/internal/schema"
+)
+
+func TestSchemas(t *testing.T) {
+	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	registry := schema.NewRegistry()
+	handler := NewSchemas(log, registry)
+
+	put := func(body string) int {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/admin/schemas", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		handler.Put(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnprocessableEntity, put(`{"prefix":`))
+	assert.Equal(t, http.StatusBadRequest, put(`{"schema": {"type": "object"}}`))
+	assert.Equal(t, http.StatusBadRequest, put(`{"prefix": "users/", "schema": {"type": 1}}`))
+	assert.Equal(t, http.StatusOK, put(`{"prefix": "users/", "schema": {"type": "object"}}`))
+	assert.Equal(t, []schema.Schema{{Prefix: "users/", Schema: []byte(`{"type": "object"}`)}}, registry.List())
+
+	del := func(query string) int {
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/admin/schemas"+query, nil)
+		w := httptest.NewRecorder()
+		handler.Delete(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusBadRequest, del(""))
+	assert.Equal(t, http.StatusOK, del("?prefix="+url.QueryEscape("users/")))
+	assert.Equal(t, http.StatusNotFound, del("?prefix="+url.QueryEscape("users/")))
+}
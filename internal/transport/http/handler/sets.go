@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// SetStorage is the contract for the storage of sets.
+type SetStorage interface {
+	// SetAdd adds the members to the set and returns the number of new
+	// members.
+	SetAdd(key string, members []string) (int, error)
+	// SetRemove removes the members from the set and returns the number of
+	// removed members.
+	SetRemove(key string, members []string) (int, error)
+	// SetMembers returns the members of the set.
+	SetMembers(key string) ([]string, error)
+	// SetIsMember reports whether the member is in the set.
+	SetIsMember(key, member string) (bool, error)
+}
+
+// Sets is the HTTP handler for sets.
+type Sets struct {
+	log     *slog.Logger
+	storage SetStorage
+}
+
+// NewSets creates a new HTTP handler for sets.
+func NewSets(log *slog.Logger, storage SetStorage) *Sets {
+	return &Sets{
+		log:     log,
+		storage: storage,
+	}
+}
+
+type setAddRequest struct {
+	Members []string `json:"members"`
+}
+
+// Add adds the members to the set, creating it if need be.
+func (h *Sets) Add(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	var req setAddRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONErr(h.log, w, http.StatusUnprocessableEntity, fmt.Sprintf("decode request: %s", err.Error()))
+		return
+	}
+
+	if len(req.Members) == 0 {
+		writeJSONErr(h.log, w, http.StatusBadRequest, "members cannot be empty")
+		return
+	}
+
+	added, err := h.storage.SetAdd(key, req.Members)
+	if err != nil {
+		h.log.Error("failed to add set members", slog.String("error", err.Error()))
+		writeStructureErr(h.log, w, err)
+		return
+	}
+
+	writeJSONSuccess(h.log, w, http.StatusOK, map[string]any{"key": key, "added": added})
+}
+
+// Members returns the members of the set.
+func (h *Sets) Members(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	members, err := h.storage.SetMembers(key)
+	if err != nil {
+		h.log.Error("failed to get set members", slog.String("error", err.Error()))
+		writeStructureErr(h.log, w, err)
+		return
+	}
+
+	writeJSONSuccess(h.log, w, http.StatusOK, map[string]any{"key": key, "members": members})
+}
+
+// IsMember reports whether the member is in the set.
+func (h *Sets) IsMember(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	member := r.PathValue("member")
+
+	isMember, err := h.storage.SetIsMember(key, member)
+	if err != nil {
+		h.log.Error("failed to check set member", slog.String("error", err.Error()))
+		writeStructureErr(h.log, w, err)
+		return
+	}
+
+	writeJSONSuccess(h.log, w, http.StatusOK, map[string]any{"key": key, "member": member, "is_member": isMember})
+}
+
+// Remove removes the member from the set. Removing the last member deletes
+// the set.
+func (h *Sets) Remove(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	member := r.PathValue("member")
+
+	removed, err := h.storage.SetRemove(key, []string{member})
+	if err != nil {
+		h.log.Error("failed to remove set member", slog.String("error", err.Error()))
+		writeStructureErr(h.log, w, err)
+		return
+	}
+
+	writeJSONSuccess(h.log, w, http.StatusOK, map[string]any{"key": key, "removed": removed})
+}
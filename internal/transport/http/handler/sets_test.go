@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+type MockSetStorage struct {
+	mock.Mock
+}
+
+func (m *MockSetStorage) SetAdd(key string, members []string) (int, error) {
+	args := m.Called(key, members)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockSetStorage) SetRemove(key string, members []string) (int, error) {
+	args := m.Called(key, members)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockSetStorage) SetMembers(key string) ([]string, error) {
+	args := m.Called(key)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockSetStorage) SetIsMember(key, member string) (bool, error) {
+	args := m.Called(key, member)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestSets(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	tests := []struct {
+		name           string
+		call           func(h *Sets) http.HandlerFunc
+		body           string
+		mockSetup      func(*MockSetStorage)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "add",
+			call: func(h *Sets) http.HandlerFunc { return h.Add },
+			body: `{"members": ["a", "b"]}`,
+			mockSetup: func(m *MockSetStorage) {
+				m.On("SetAdd", "tags", []string{"a", "b"}).Return(1, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","code":200,"details":{"key":"tags","added":1}}`,
+		},
+		{
+			name:           "add without members",
+			call:           func(h *Sets) http.HandlerFunc { return h.Add },
+			body:           `{}`,
+			mockSetup:      func(m *MockSetStorage) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "add non-string members",
+			call:           func(h *Sets) http.HandlerFunc { return h.Add },
+			body:           `{"members": [1]}`,
+			mockSetup:      func(m *MockSetStorage) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "add to key of another type",
+			call: func(h *Sets) http.HandlerFunc { return h.Add },
+			body: `{"members": ["a"]}`,
+			mockSetup: func(m *MockSetStorage) {
+				m.On("SetAdd", "tags", []string{"a"}).Return(0, storage.ErrWrongType)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name: "members",
+			call: func(h *Sets) http.HandlerFunc { return h.Members },
+			mockSetup: func(m *MockSetStorage) {
+				m.On("SetMembers", "tags").Return([]string{"a", "b"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","code":200,"details":{"key":"tags","members":["a","b"]}}`,
+		},
+		{
+			name: "is member",
+			call: func(h *Sets) http.HandlerFunc { return h.IsMember },
+			mockSetup: func(m *MockSetStorage) {
+				m.On("SetIsMember", "tags", "a").Return(true, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","code":200,"details":{"key":"tags","member":"a","is_member":true}}`,
+		},
+		{
+			name: "remove",
+			call: func(h *Sets) http.HandlerFunc { return h.Remove },
+			mockSetup: func(m *MockSetStorage) {
+				m.On("SetRemove", "tags", []string{"a"}).Return(1, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"success","code":200,"details":{"key":"tags","removed":1}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sets := &MockSetStorage{}
+			tt.mockSetup(sets)
+
+			handler := NewSets(logger, sets)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/sets/tags", bytes.NewBufferString(tt.body))
+			req.SetPathValue("key", "tags")
+			req.SetPathValue("member", "a")
+			w := httptest.NewRecorder()
+
+			tt.call(handler)(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+			sets.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Spec is the HTTP handler serving the OpenAPI spec and its docs page.
+type Spec struct {
+	log  *slog.Logger
+	yaml []byte
+	json []byte
+	docs []byte
+}
+
+// NewSpec creates a new HTTP handler serving the spec, given in YAML and
+// parsed, and the docs page rendering it.
+func NewSpec(log *slog.Logger, yaml []byte, doc *openapi3.T, docs []byte) (*Spec, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("encode spec: %w", err)
+	}
+
+	return &Spec{
+		log:  log,
+		yaml: yaml,
+		json: data,
+		docs: docs,
+	}, nil
+}
+
+// YAML returns the spec as it is written.
+func (h *Spec) YAML(w http.ResponseWriter, r *http.Request) {
+	h.write(w, "application/yaml", h.yaml)
+}
+
+// JSON returns the spec in JSON, which the docs page renders.
+func (h *Spec) JSON(w http.ResponseWriter, r *http.Request) {
+	h.write(w, "application/json", h.json)
+}
+
+// Docs returns the docs page.
+func (h *Spec) Docs(w http.ResponseWriter, r *http.Request) {
+	h.write(w, "text/html; charset=utf-8", h.docs)
+}
+
+func (h *Spec) write(w http.ResponseWriter, contentType string, data []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(data); err != nil {
+		h.log.Error("failed to write spec", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmybsv/tarantool-kv/api"
+)
+
+func TestSpec(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	doc, err := api.LoadOpenAPI()
+	require.NoError(t, err)
+	spec, err := NewSpec(logger, api.OpenAPI, doc, api.Docs)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name                string
+		handle              http.HandlerFunc
+		target              string
+		expectedContentType string
+		expectedBody        []byte
+	}{
+		{
+			name:                "yaml",
+			handle:              spec.YAML,
+			target:              "/openapi.yml",
+			expectedContentType: "application/yaml",
+			expectedBody:        api.OpenAPI,
+		},
+		{
+			name:                "docs",
+			handle:              spec.Docs,
+			target:              "/docs",
+			expectedContentType: "text/html; charset=utf-8",
+			expectedBody:        api.Docs,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			w := httptest.NewRecorder()
+
+			tt.handle(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.expectedContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.expectedBody, w.Body.Bytes())
+		})
+	}
+
+	t.Run("json", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+		w := httptest.NewRecorder()
+
+		spec.JSON(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, "3.0.0", body["openapi"])
+		assert.Contains(t, body["paths"], "/kv/{key}")
+	})
+}
@@ -0,0 +1,255 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/tmybsv/tarantool-kv/internal/backup"
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+const (
+	// importBatchSize is the number of records written to the storage in a
+	// single transaction.
+	importBatchSize = 256
+	// maxImportLine is the maximum length of an imported line, enough for
+	// the largest blob encoded in base64.
+	maxImportLine = maxBlobSize/3*4 + 64<<10
+	// maxImportErrors is the maximum number of failed records reported in
+	// the import response.
+	maxImportErrors = 100
+)
+
+// KVTransfer is the contract for the storage exporting and importing keys.
+type KVTransfer interface {
+	// Export calls fn for every live key starting with the prefix.
+	Export(prefix string, fn func(storage.ExportRecord) error) error
+	// Import writes the batch of records and returns the result of every
+	// record or an error if the batch as a whole failed.
+	Import(records []storage.ImportRecord, mode storage.ImportMode) ([]storage.ImportResult, error)
+}
+
+// Transfer is the HTTP handler exporting and importing keys as
+// newline-delimited JSON.
+type Transfer struct {
+	log       *slog.Logger
+	storage   KVTransfer
+	validator ValueValidator
+}
+
+// NewTransfer creates a new HTTP handler for exporting and importing keys.
+// Imported values are stored without validation if validator is nil.
+func NewTransfer(log *slog.Logger, storage KVTransfer, validator ValueValidator) *Transfer {
+	return &Transfer{
+		log:       log,
+		storage:   storage,
+		validator: validator,
+	}
+}
+
+// Export streams the keys starting with the prefix from the query, one
+// record per line. A failure once the stream has started aborts the
+// response, so the client sees it truncated rather than complete.
+func (h *Transfer) Export(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		h.log.Error("failed to reset write deadline", slog.String("error", err.Error()))
+	}
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	started := false
+	err := h.storage.Export(r.URL.Query().Get("prefix"), func(rec storage.ExportRecord) error {
+		if !started {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		if err := r.Context().Err(); err != nil {
+			return err
+		}
+		return enc.Encode(backup.NewRecord(rec))
+	})
+	if err == nil {
+		if !started {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.WriteHeader(http.StatusOK)
+		}
+		err = bw.Flush()
+	}
+	if err == nil {
+		return
+	}
+
+	h.log.Error("failed to export keys", slog.String("error", err.Error()))
+	if !started {
+		writeJSONErr(h.log, w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	panic(http.ErrAbortHandler)
+}
+
+// importFailure is a record that was not imported.
+type importFailure struct {
+	Line    int    `json:"line"`
+	Key     string `json:"key,omitempty"`
+	Message string `json:"message"`
+}
+
+// importSummary is the response of an import.
+type importSummary struct {
+	Created int             `json:"created"`
+	Updated int             `json:"updated"`
+	Skipped int             `json:"skipped"`
+	Failed  int             `json:"failed"`
+	Errors  []importFailure `json:"errors,omitempty"`
+}
+
+func (s *importSummary) fail(line int, key, message string) {
+	s.Failed++
+	if len(s.Errors) < maxImportErrors {
+		s.Errors = append(s.Errors, importFailure{Line: line, Key: key, Message: message})
+	}
+}
+
+// Import reads the records of the request body, one per line in the format
+// of Export, and writes them in batches. The mode query parameter tells
+// what to do with the keys that exist: fail the record (the default), skip
+// it or overwrite the value. Invalid records are reported as failed along
+// with the rest of the summary.
+func (h *Transfer) Import(w http.ResponseWriter, r *http.Request) {
+	mode := storage.ImportMode(r.URL.Query().Get("mode"))
+	switch mode {
+	case "":
+		mode = storage.ImportFail
+	case storage.ImportFail, storage.ImportSkip, storage.ImportOverwrite:
+	default:
+		writeJSONErr(h.log, w, http.StatusBadRequest, "mode must be one of fail, skip or overwrite")
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		h.log.Error("failed to reset read deadline", slog.String("error", err.Error()))
+	}
+
+	var (
+		summary importSummary
+		batch   []storage.ImportRecord
+		lines   []int
+	)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		results, err := h.storage.Import(batch, mode)
+		if err != nil {
+			return err
+		}
+		for i, res := range results {
+			switch res.Outcome {
+			case storage.ImportCreated:
+				summary.Created++
+			case storage.ImportUpdated:
+				summary.Updated++
+			case storage.ImportSkipped:
+				summary.Skipped++
+			default:
+				summary.fail(lines[i], batch[i].Key, importErrorMessage(res.Err))
+			}
+		}
+		batch, lines = nil, nil
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 64<<10), maxImportLine)
+	for n := 1; scanner.Scan(); n++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		rec, err := h.importRecord(scanner.Bytes())
+		if err != nil {
+			summary.fail(n, rec.Key, err.Error())
+			continue
+		}
+
+		batch, lines = append(batch, rec), append(lines, n)
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				h.importFailed(w, &summary, err)
+				return
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		// The records read so far are imported all the same.
+		if err := flush(); err != nil {
+			h.importFailed(w, &summary, err)
+			return
+		}
+		writeJSONErrDetails(h.log, w, http.StatusBadRequest, map[string]any{
+			"message": fmt.Sprintf("read records: %s", err),
+			"summary": summary,
+		})
+		return
+	}
+	if err := flush(); err != nil {
+		h.importFailed(w, &summary, err)
+		return
+	}
+
+	writeJSONSuccess(h.log, w, http.StatusOK, summary)
+}
+
+// importRecord decodes and validates the line. The key of the returned
+// record is set as soon as it is known, to report the failure of the line.
+func (h *Transfer) importRecord(line []byte) (storage.ImportRecord, error) {
+	var raw backup.Record
+	if err := json.Unmarshal(line, &raw); err != nil {
+		return storage.ImportRecord{}, errors.New("invalid record")
+	}
+
+	rec, err := raw.ImportRecord()
+	if err != nil {
+		return rec, err
+	}
+	if h.validator != nil {
+		if err := h.validator.Validate(rec.Key, rec.Value); err != nil {
+			return rec, err
+		}
+	}
+	return rec, nil
+}
+
+// importFailed writes the error response of an import stopped by a failed
+// batch, with the summary of the records imported before it.
+func (h *Transfer) importFailed(w http.ResponseWriter, summary *importSummary, err error) {
+	h.log.Error("failed to import keys", slog.String("error", err.Error()))
+	writeJSONErrDetails(h.log, w, http.StatusInternalServerError, map[string]any{
+		"message": "internal error",
+		"summary": summary,
+	})
+}
+
+func importErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, storage.ErrKeyAlreadyExists):
+		return "key already exists"
+	case errors.Is(err, storage.ErrFieldTypeMismatch):
+		return "value does not match indexed field type"
+	case errors.Is(err, storage.ErrWrongType):
+		return "key holds a value of another type"
+	case err != nil:
+		return err.Error()
+	default:
+		return "not imported"
+	}
+}
@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tmybsv/tarantool-kv/internal/schema"
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+type MockKVTransfer struct {
+	mock.Mock
+}
+
+func (m *MockKVTransfer) Export(prefix string, fn func(storage.ExportRecord) error) error {
+	args := m.Called(prefix, fn)
+	return args.Error(0)
+}
+
+func (m *MockKVTransfer) Import(records []storage.ImportRecord, mode storage.ImportMode) ([]storage.ImportResult, error) {
+	args := m.Called(records, mode)
+	return args.Get(0).([]storage.ImportResult), args.Error(1)
+}
+
+func TestTransfer_Export(t *testing.T) {
+	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	expiresAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := []storage.ExportRecord{
+		{Key: "user:1", Value: []byte(`{"name":"Alice"}`), ExpiresAt: expiresAt},
+		{Key: "user:2", Value: []byte("\x89PNG"), ContentType: "image/png"},
+	}
+
+	tests := []struct {
+		name           string
+		mockSetup      func(*MockKVTransfer)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "records",
+			mockSetup: func(mt *MockKVTransfer) {
+				mt.On("Export", "user:", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+					fn := args.Get(1).(func(storage.ExportRecord) error)
+					for _, rec := range records {
+						require.NoError(t, fn(rec))
+					}
+				})
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"key":"user:1","value":{"name":"Alice"},"metadata":{"expires_at":"2025-01-01T00:00:00Z"}}` + "\n" +
+				`{"key":"user:2","value":"iVBORw==","metadata":{"content_type":"image/png"}}` + "\n",
+		},
+		{
+			name: "no records",
+			mockSetup: func(mt *MockKVTransfer) {
+				mt.On("Export", "user:", mock.Anything).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "",
+		},
+		{
+			name: "storage error",
+			mockSetup: func(mt *MockKVTransfer) {
+				mt.On("Export", "user:", mock.Anything).Return(errors.New("boom"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"status":"error","code":500,"details":"internal error"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := &MockKVTransfer{}
+			tt.mockSetup(mockStorage)
+
+			handler := NewTransfer(log, mockStorage, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/kv/_export?prefix=user:", nil)
+			w := httptest.NewRecorder()
+
+			handler.Export(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTransfer_Export_abort(t *testing.T) {
+	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	mockStorage := &MockKVTransfer{}
+	mockStorage.On("Export", "", mock.Anything).Return(errors.New("boom")).Run(func(args mock.Arguments) {
+		fn := args.Get(1).(func(storage.ExportRecord) error)
+		require.NoError(t, fn(storage.ExportRecord{Key: "a", Value: []byte("1")}))
+	})
+
+	handler := NewTransfer(log, mockStorage, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/kv/_export", nil)
+	w := httptest.NewRecorder()
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() { handler.Export(w, req) })
+}
+
+func TestTransfer_Import(t *testing.T) {
+	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	registry := schema.NewRegistry()
+	require.NoError(t, registry.Register("user:", []byte(`{"type":"object","required":["name"]}`)))
+
+	body := strings.Join([]string{
+		`{"key":"user:1","value":{"name":"Alice"},"metadata":{"expires_at":"2025-01-01T00:00:00Z"}}`,
+		``,
+		`{"key":"avatar","value":"iVBORw==","metadata":{"content_type":"image/png"}}`,
+		`{"key":"user:2","value":{"age":42}}`,
+		`not json`,
+		`{"key":"blob","value":"%%%","metadata":{"content_type":"image/png"}}`,
+		`{"key":"count","value":7}`,
+		`{"key":"user:3","value":"AQ==","metadata":{"content_type":"image/png"}}`,
+	}, "\n")
+
+	expectedRecords := []storage.ImportRecord{
+		{Key: "user:1", Value: map[string]any{"name": "Alice"}, ExpiresAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Key: "avatar", Value: storage.Blob{ContentType: "image/png", Data: []byte("\x89PNG")}},
+		{Key: "count", Value: float64(7)},
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		mockSetup      func(*MockKVTransfer)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "default mode",
+			query: "",
+			mockSetup: func(mt *MockKVTransfer) {
+				mt.On("Import", expectedRecords, storage.ImportFail).Return([]storage.ImportResult{
+					{Outcome: storage.ImportCreated},
+					{Outcome: storage.ImportFailed, Err: storage.ErrKeyAlreadyExists},
+					{Outcome: storage.ImportCreated},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"status":"success","code":200,"details":{"created":2,"updated":0,"skipped":0,"failed":5,"errors":[
+				{"line":4,"key":"user:2","message":"value does not match schema for prefix \"user:\""},
+				{"line":5,"message":"invalid record"},
+				{"line":6,"key":"blob","message":"blob value must be a base64 string"},
+				{"line":8,"key":"user:3","message":"value does not match schema for prefix \"user:\""},
+				{"line":3,"key":"avatar","message":"key already exists"}
+			]}}`,
+		},
+		{
+			name:  "skip",
+			query: "?mode=skip",
+			mockSetup: func(mt *MockKVTransfer) {
+				mt.On("Import", expectedRecords, storage.ImportSkip).Return([]storage.ImportResult{
+					{Outcome: storage.ImportSkipped},
+					{Outcome: storage.ImportCreated},
+					{Outcome: storage.ImportSkipped},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"status":"success","code":200,"details":{"created":1,"updated":0,"skipped":2,"failed":4,"errors":[
+				{"line":4,"key":"user:2","message":"value does not match schema for prefix \"user:\""},
+				{"line":5,"message":"invalid record"},
+				{"line":6,"key":"blob","message":"blob value must be a base64 string"},
+				{"line":8,"key":"user:3","message":"value does not match schema for prefix \"user:\""}
+			]}}`,
+		},
+		{
+			name:  "storage error",
+			query: "?mode=overwrite",
+			mockSetup: func(mt *MockKVTransfer) {
+				mt.On("Import", expectedRecords, storage.ImportOverwrite).Return([]storage.ImportResult(nil), errors.New("boom"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody: `{"status":"error","code":500,"details":{"message":"internal error","summary":{"created":0,"updated":0,"skipped":0,"failed":4,"errors":[
+				{"line":4,"key":"user:2","message":"value does not match schema for prefix \"user:\""},
+				{"line":5,"message":"invalid record"},
+				{"line":6,"key":"blob","message":"blob value must be a base64 string"},
+				{"line":8,"key":"user:3","message":"value does not match schema for prefix \"user:\""}
+			]}}}`,
+		},
+		{
+			name:           "unknown mode",
+			query:          "?mode=merge",
+			mockSetup:      func(mt *MockKVTransfer) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"status":"error","code":400,"details":"mode must be one of fail, skip or overwrite"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := &MockKVTransfer{}
+			tt.mockSetup(mockStorage)
+
+			handler := NewTransfer(log, mockStorage, registry)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/kv/_import"+tt.query, strings.NewReader(body))
+			w := httptest.NewRecorder()
+
+			handler.Import(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+// watchHeartbeatInterval is the interval of comments sent to keep idle event
+// streams open through proxies.
+const watchHeartbeatInterval = 15 * time.Second
+
+// EventSubscriber is the contract for the source of key change events.
+type EventSubscriber interface {
+	// Subscribe returns a channel of events with IDs greater than afterID or
+	// only new events if afterID is zero.
+	Subscribe(ctx context.Context, afterID uint64) (<-chan storage.Event, error)
+}
+
+// Watch is the HTTP handler streaming key changes as Server-Sent Events.
+type Watch struct {
+	log       *slog.Logger
+	events    EventSubscriber
+	heartbeat time.Duration
+}
+
+// NewWatch creates a new HTTP handler for watching keys.
+func NewWatch(log *slog.Logger, events EventSubscriber) *Watch {
+	return &Watch{
+		log:       log,
+		events:    events,
+		heartbeat: watchHeartbeatInterval,
+	}
+}
+
+// Key streams the changes of the key from the path.
+func (h *Watch) Key(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	h.stream(w, r, func(k string) bool { return k == key })
+}
+
+// Prefix streams the changes of the keys starting with the prefix from the
+// query. An empty prefix matches every key.
+func (h *Watch) Prefix(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	h.stream(w, r, func(k string) bool { return strings.HasPrefix(k, prefix) })
+}
+
+func (h *Watch) stream(w http.ResponseWriter, r *http.Request, match func(key string) bool) {
+	lastID, err := lastEventID(r)
+	if err != nil {
+		writeJSONErr(h.log, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		h.log.Error("failed to reset write deadline", slog.String("error", err.Error()))
+	}
+
+	events, err := h.events.Subscribe(r.Context(), lastID)
+	if err != nil {
+		h.log.Error("failed to subscribe to events", slog.String("error", err.Error()))
+		writeJSONErr(h.log, w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		h.log.Error("failed to flush event stream", slog.String("error", err.Error()))
+		return
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if !match(event.Key) {
+				continue
+			}
+			if err := writeSSEEvent(w, event); err != nil {
+				h.log.Error("failed to write event", slog.String("error", err.Error()))
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func lastEventID(r *http.Request) (uint64, error) {
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = r.URL.Query().Get("last_event_id")
+	}
+	if raw == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid last event ID: %q", raw)
+	}
+	return id, nil
+}
+
+func writeSSEEvent(w http.ResponseWriter, event storage.Event) error {
+	data, err := json.Marshal(map[string]any{
+		"key":   event.Key,
+		"value": event.Value,
+		"time":  event.Time,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
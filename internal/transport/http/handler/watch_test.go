@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+type fakeSubscriber struct {
+	afterID uint64
+	events  []storage.Event
+}
+
+func (s *fakeSubscriber) Subscribe(ctx context.Context, afterID uint64) (<-chan storage.Event, error) {
+	s.afterID = afterID
+	ch := make(chan storage.Event, len(s.events))
+	for _, e := range s.events {
+		ch <- e
+	}
+	close(ch)
+	return ch, nil
+}
+
+func TestWatch_Key(t *testing.T) {
+	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	subscriber := &fakeSubscriber{events: []storage.Event{
+		{ID: 5, Type: storage.EventSet, Key: "other", Value: "x"},
+		{ID: 6, Type: storage.EventUpdate, Key: "test-key", Value: "y"},
+		{ID: 7, Type: storage.EventDelete, Key: "test-key"},
+	}}
+	handler := NewWatch(log, subscriber)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/kv/test-key/watch", nil)
+	req.SetPathValue("key", "test-key")
+	req.Header.Set("Last-Event-ID", "4")
+	w := httptest.NewRecorder()
+
+	handler.Key(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, uint64(4), subscriber.afterID)
+	body := w.Body.String()
+	assert.NotContains(t, body, `"other"`)
+	assert.Contains(t, body, "id: 6\nevent: update\ndata: {\"key\":\"test-key\"")
+	assert.Contains(t, body, "id: 7\nevent: delete\n")
+}
+
+func TestWatch_Prefix(t *testing.T) {
+	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	subscriber := &fakeSubscriber{events: []storage.Event{
+		{ID: 1, Type: storage.EventSet, Key: "user:1"},
+		{ID: 2, Type: storage.EventSet, Key: "order:1"},
+	}}
+	handler := NewWatch(log, subscriber)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/kv/_watch?prefix=user:", nil)
+	w := httptest.NewRecorder()
+
+	handler.Prefix(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"user:1"`)
+	assert.NotContains(t, w.Body.String(), `"order:1"`)
+}
+
+func TestWatch_InvalidLastEventID(t *testing.T) {
+	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	handler := NewWatch(log, &fakeSubscriber{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/kv/_watch", nil)
+	req.Header.Set("Last-Event-ID", "abc")
+	w := httptest.NewRecorder()
+
+	handler.Prefix(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -16,11 +16,17 @@ func (w *responseWriter) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap returns the original response writer for http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // Logging returns a middleware that logs the request and response.
 func Logging(log *slog.Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		log.Info("request started",
+			slog.String("request_id", RequestIDFromContext(r.Context())),
 			slog.String("method", r.Method),
 			slog.String("path", r.URL.Path),
 			slog.String("remote_addr", r.RemoteAddr),
@@ -32,6 +38,7 @@ func Logging(log *slog.Logger, next http.Handler) http.Handler {
 		}
 		next.ServeHTTP(rw, r)
 		log.Info("request completed",
+			slog.String("request_id", RequestIDFromContext(r.Context())),
 			slog.Int("status", rw.statusCode),
 			slog.String("path", r.URL.Path),
 			slog.String("duration", time.Since(start).String()),
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{name: "client ID", header: "abc-123", keep: true},
+		{name: "no ID"},
+		{name: "non-printable ID", header: "abc\x01"},
+		{name: "too long ID", header: strings.Repeat("a", maxRequestIDLen+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = RequestIDFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if tt.keep {
+				assert.Equal(t, tt.header, got)
+			} else {
+				assert.Len(t, got, 32)
+			}
+			assert.Equal(t, got, w.Header().Get(RequestIDHeader))
+		})
+	}
+}
@@ -0,0 +1,414 @@
+package memcached
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tmybsv/tarantool-kv/internal/schema"
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+const (
+	// maxKeyLen is the maximum length of a key in bytes.
+	maxKeyLen = 250
+	// maxRelativeExptime is the maximum expiration time in seconds relative
+	// to now, larger ones are Unix timestamps.
+	maxRelativeExptime = 30 * 24 * 60 * 60
+	// flagsParam is the content type parameter of the blobs keeping the
+	// flags of items.
+	flagsParam = "memcached-flags"
+)
+
+// dispatch runs the command in the line, reading its data block from r if
+// it has one, and reports whether the connection should be closed. The
+// error is returned only if the data block cannot be read.
+func (s *Server) dispatch(r *bufio.Reader, w *bufio.Writer, line string) (bool, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		w.WriteString("ERROR\r\n")
+		return false, nil
+	}
+
+	switch fields[0] {
+	case "get":
+		s.get(w, fields[1:], false)
+	case "gets":
+		s.get(w, fields[1:], true)
+	case "set", "add", "replace", "cas":
+		return false, s.store(r, w, fields)
+	case "delete":
+		s.delete(w, fields[1:])
+	case "incr", "decr":
+		s.incr(w, fields)
+	case "touch":
+		s.touch(w, fields[1:])
+	case "version":
+		w.WriteString("VERSION 1.0.0\r\n")
+	case "quit":
+		return true, nil
+	default:
+		w.WriteString("ERROR\r\n")
+	}
+	return false, nil
+}
+
+// get writes the items of the keys that exist, with their CAS unique if cas
+// is set.
+func (s *Server) get(w *bufio.Writer, keys []string, cas bool) {
+	if len(keys) == 0 {
+		w.WriteString("ERROR\r\n")
+		return
+	}
+
+	for _, key := range keys {
+		if !validKey(key) {
+			w.WriteString("CLIENT_ERROR bad command line format\r\n")
+			return
+		}
+
+		value, version, err := s.storage.GetWithVersion(key)
+		if errors.Is(err, storage.ErrKeyNotFound) || errors.Is(err, storage.ErrKeyDeleted) {
+			continue
+		}
+		if err != nil {
+			s.writeStorageErr(w, "get", err)
+			return
+		}
+
+		data, flags, err := itemData(value)
+		if err != nil {
+			s.writeStorageErr(w, "get", err)
+			return
+		}
+
+		if cas {
+			fmt.Fprintf(w, "VALUE %s %d %d %d\r\n", key, flags, len(data), version)
+		} else {
+			fmt.Fprintf(w, "VALUE %s %d %d\r\n", key, flags, len(data))
+		}
+		w.Write(data)
+		w.WriteString("\r\n")
+	}
+	w.WriteString("END\r\n")
+}
+
+// store runs the storage commands:
+//
+//	set|add|replace <key> <flags> <exptime> <bytes> [noreply]
+//	cas <key> <flags> <exptime> <bytes> <cas unique> [noreply]
+//
+// followed by the data block. The item is stored along with its exptime at
+// once, an item that has expired is taken as missing.
+func (s *Server) store(r *bufio.Reader, w *bufio.Writer, fields []string) error {
+	cmd, args, reply := fields[0], fields[1:], replier(w, fields)
+	if len(args) > 0 && args[len(args)-1] == "noreply" {
+		args = args[:len(args)-1]
+	}
+
+	wantArgs := 4
+	if cmd == "cas" {
+		wantArgs = 5
+	}
+	if len(args) != wantArgs {
+		w.WriteString("CLIENT_ERROR bad command line format\r\n")
+		return nil
+	}
+
+	size, err := strconv.Atoi(args[3])
+	if err != nil || size < 0 {
+		w.WriteString("CLIENT_ERROR bad command line format\r\n")
+		return nil
+	}
+	if size > s.maxItemSize {
+		// The data block is swallowed so the next command can be read.
+		if _, err := r.Discard(size + 2); err != nil {
+			return err
+		}
+		w.WriteString("SERVER_ERROR object too large for cache\r\n")
+		return nil
+	}
+
+	data := make([]byte, size+2)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return err
+	}
+	if data[size] != '\r' || data[size+1] != '\n' {
+		w.WriteString("CLIENT_ERROR bad data chunk\r\n")
+		return nil
+	}
+	data = data[:size]
+
+	key := args[0]
+	flags, flagsErr := strconv.ParseUint(args[1], 10, 32)
+	exptime, exptimeErr := strconv.ParseInt(args[2], 10, 64)
+	var casUnique uint64
+	var casErr error
+	if cmd == "cas" {
+		casUnique, casErr = strconv.ParseUint(args[4], 10, 64)
+	}
+	if !validKey(key) || flagsErr != nil || exptimeErr != nil || casErr != nil {
+		w.WriteString("CLIENT_ERROR bad command line format\r\n")
+		return nil
+	}
+
+	value := itemValue(data, uint32(flags))
+	if !s.validate(w, key, value) {
+		return nil
+	}
+
+	ttl, expired := expiration(exptime)
+	if expired {
+		// The item is stored expired, so it is not found from now on.
+		ttl = -time.Second
+	}
+	opts := storage.SetOptions{Mode: storage.SetAlways, TTL: ttl}
+	switch cmd {
+	case "add":
+		opts.Mode = storage.SetIfAbsent
+	case "replace":
+		opts.Mode = storage.SetIfPresent
+	case "cas":
+		opts.Mode, opts.Version = storage.SetIfPresent, casUnique
+	}
+
+	_, err = s.storage.SetWithOptions(key, value, opts)
+	switch {
+	case cmd == "cas" && errors.Is(err, storage.ErrKeyNotFound):
+		reply("NOT_FOUND")
+		return nil
+	case errors.Is(err, storage.ErrVersionMismatch):
+		reply("EXISTS")
+		return nil
+	case errors.Is(err, storage.ErrKeyAlreadyExists), errors.Is(err, storage.ErrKeyNotFound):
+		reply("NOT_STORED")
+		return nil
+	case err != nil:
+		s.writeStorageErr(w, "store", err)
+		return nil
+	}
+
+	reply("STORED")
+	return nil
+}
+
+// delete runs delete <key> [0] [noreply].
+func (s *Server) delete(w *bufio.Writer, args []string) {
+	reply := replier(w, args)
+	if len(args) > 0 && args[len(args)-1] == "noreply" {
+		args = args[:len(args)-1]
+	}
+	if len(args) == 2 && args[1] == "0" {
+		args = args[:1]
+	}
+	if len(args) != 1 || !validKey(args[0]) {
+		w.WriteString("CLIENT_ERROR bad command line format\r\n")
+		return
+	}
+
+	removed, err := s.storage.Remove(args[0])
+	if err != nil {
+		s.writeStorageErr(w, "delete", err)
+		return
+	}
+
+	if !removed {
+		reply("NOT_FOUND")
+		return
+	}
+	reply("DELETED")
+}
+
+// incr runs incr|decr <key> <value> [noreply]. The counters are unsigned,
+// decrementing below zero gives zero.
+func (s *Server) incr(w *bufio.Writer, fields []string) {
+	args, reply := fields[1:], replier(w, fields)
+	if len(args) > 0 && args[len(args)-1] == "noreply" {
+		args = args[:len(args)-1]
+	}
+	if len(args) != 2 || !validKey(args[0]) {
+		w.WriteString("CLIENT_ERROR bad command line format\r\n")
+		return
+	}
+
+	delta, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil || delta > math.MaxInt64 {
+		w.WriteString("CLIENT_ERROR invalid numeric delta argument\r\n")
+		return
+	}
+	signed := int64(delta)
+	if fields[0] == "decr" {
+		signed = -signed
+	}
+
+	n, err := s.storage.IncrCounter(args[0], signed)
+	switch {
+	case errors.Is(err, storage.ErrKeyNotFound):
+		reply("NOT_FOUND")
+	case errors.Is(err, storage.ErrNotInteger):
+		w.WriteString("CLIENT_ERROR cannot increment or decrement non-numeric value\r\n")
+	case err != nil:
+		s.writeStorageErr(w, "increment", err)
+	default:
+		reply(strconv.FormatInt(n, 10))
+	}
+}
+
+// touch runs touch <key> <exptime> [noreply].
+func (s *Server) touch(w *bufio.Writer, args []string) {
+	reply := replier(w, args)
+	if len(args) > 0 && args[len(args)-1] == "noreply" {
+		args = args[:len(args)-1]
+	}
+	if len(args) != 2 || !validKey(args[0]) {
+		w.WriteString("CLIENT_ERROR bad command line format\r\n")
+		return
+	}
+	exptime, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		w.WriteString("CLIENT_ERROR invalid exptime argument\r\n")
+		return
+	}
+
+	// The key is checked and touched at once.
+	if ttl, expired := expiration(exptime); expired {
+		var removed bool
+		removed, err = s.storage.Remove(args[0])
+		if err == nil && !removed {
+			err = storage.ErrKeyNotFound
+		}
+	} else {
+		err = s.storage.Expire(args[0], ttl)
+	}
+	if errors.Is(err, storage.ErrKeyNotFound) {
+		reply("NOT_FOUND")
+		return
+	}
+	if err != nil {
+		s.writeStorageErr(w, "touch", err)
+		return
+	}
+	reply("TOUCHED")
+}
+
+// expiration returns the time to live of the exptime and reports whether it
+// has expired already: zero never expires and gives a zero ttl, up to 30
+// days it is relative to now, larger ones are Unix timestamps and the ones
+// in the past have expired.
+func expiration(exptime int64) (time.Duration, bool) {
+	var ttl time.Duration
+	switch {
+	case exptime == 0:
+		return 0, false
+	case exptime < 0:
+	case exptime <= maxRelativeExptime:
+		ttl = time.Duration(exptime) * time.Second
+	default:
+		ttl = time.Until(time.Unix(exptime, 0))
+	}
+	return ttl, ttl <= 0
+}
+
+// validate validates the value of the key and writes the error reply if it
+// is not valid.
+func (s *Server) validate(w *bufio.Writer, key string, value any) bool {
+	if s.validator == nil {
+		return true
+	}
+	err := s.validator.Validate(key, value)
+	if err == nil {
+		return true
+	}
+
+	var verr *schema.ValidationError
+	if errors.As(err, &verr) {
+		w.WriteString("CLIENT_ERROR " + strings.NewReplacer("\r", " ", "\n", " ").Replace(verr.Error()) + "\r\n")
+		return false
+	}
+
+	s.log.Error("failed to validate value", slog.String("error", err.Error()))
+	w.WriteString("SERVER_ERROR internal error\r\n")
+	return false
+}
+
+func (s *Server) writeStorageErr(w *bufio.Writer, action string, err error) {
+	switch {
+	case errors.Is(err, storage.ErrExpiryDisabled):
+		w.WriteString("SERVER_ERROR expiry is disabled\r\n")
+	case errors.Is(err, storage.ErrWrongType):
+		w.WriteString("SERVER_ERROR key holds a value of another type\r\n")
+	default:
+		s.log.Error(fmt.Sprintf("failed to %s key", action), slog.String("error", err.Error()))
+		w.WriteString("SERVER_ERROR internal error\r\n")
+	}
+}
+
+// replier returns the function writing a reply line unless the command
+// fields end with noreply.
+func replier(w *bufio.Writer, fields []string) func(string) {
+	noreply := len(fields) > 0 && fields[len(fields)-1] == "noreply"
+	return func(line string) {
+		if !noreply {
+			w.WriteString(line + "\r\n")
+		}
+	}
+}
+
+// validKey reports whether the key can be used in the text protocol: not
+// longer than 250 bytes and without control characters.
+func validKey(key string) bool {
+	if len(key) == 0 || len(key) > maxKeyLen {
+		return false
+	}
+	for i := range len(key) {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// itemValue returns the value to store for the item data: a string if it is
+// valid UTF-8 and has no flags, and a blob otherwise.
+func itemValue(data []byte, flags uint32) any {
+	if flags == 0 && utf8.Valid(data) {
+		return string(data)
+	}
+
+	contentType := storage.DefaultBlobContentType
+	if flags != 0 {
+		contentType = mime.FormatMediaType(contentType, map[string]string{
+			flagsParam: strconv.FormatUint(uint64(flags), 10),
+		})
+	}
+	return storage.Blob{ContentType: contentType, Data: data}
+}
+
+// itemData returns the item data and flags of the stored value: strings and
+// blobs as is and other JSON values in their JSON encoding.
+func itemData(value any) ([]byte, uint32, error) {
+	switch value := value.(type) {
+	case string:
+		return []byte(value), 0, nil
+	case storage.Blob:
+		var flags uint32
+		if _, params, err := mime.ParseMediaType(value.ContentType); err == nil {
+			if f, err := strconv.ParseUint(params[flagsParam], 10, 32); err == nil {
+				flags = uint32(f)
+			}
+		}
+		return value.Data, flags, nil
+	default:
+		data, err := json.Marshal(value)
+		return data, 0, err
+	}
+}
@@ -0,0 +1,383 @@
+package memcached
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+type MockStorage struct {
+	mock.Mock
+}
+
+func (m *MockStorage) SetWithOptions(key string, value any, opts storage.SetOptions) (bool, error) {
+	args := m.Called(key, value, opts)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) Remove(key string) (bool, error) {
+	args := m.Called(key)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) GetWithVersion(key string) (any, uint64, error) {
+	args := m.Called(key)
+	return args.Get(0), args.Get(1).(uint64), args.Error(2)
+}
+
+func (m *MockStorage) IncrCounter(key string, delta int64) (int64, error) {
+	args := m.Called(key, delta)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockStorage) Expire(key string, ttl time.Duration) error {
+	args := m.Called(key, ttl)
+	return args.Error(0)
+}
+
+func newTestServer(st Storage) *Server {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	return NewServer(logger, "127.0.0.1:0", 0, 16, st, nil)
+}
+
+func TestServer_dispatch(t *testing.T) {
+	flagged := storage.Blob{ContentType: "application/octet-stream; memcached-flags=5", Data: []byte("v")}
+
+	tests := []struct {
+		name      string
+		input     string
+		mockSetup func(*MockStorage)
+		want      string
+		wantQuit  bool
+	}{
+		{
+			name:      "unknown command",
+			input:     "flush_all\r\n",
+			mockSetup: func(m *MockStorage) {},
+			want:      "ERROR\r\n",
+		},
+		{
+			name:      "version",
+			input:     "version\r\n",
+			mockSetup: func(m *MockStorage) {},
+			want:      "VERSION 1.0.0\r\n",
+		},
+		{
+			name:      "quit",
+			input:     "quit\r\n",
+			mockSetup: func(m *MockStorage) {},
+			want:      "",
+			wantQuit:  true,
+		},
+		{
+			name:  "get multiple keys",
+			input: "get a b c\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("GetWithVersion", "a").Return("v", uint64(7), nil)
+				m.On("GetWithVersion", "b").Return(nil, uint64(0), storage.ErrKeyNotFound)
+				m.On("GetWithVersion", "c").Return(map[string]any{"x": float64(1)}, uint64(8), nil)
+			},
+			want: "VALUE a 0 1\r\nv\r\nVALUE c 0 7\r\n{\"x\":1}\r\nEND\r\n",
+		},
+		{
+			name:  "gets with flags",
+			input: "gets a\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("GetWithVersion", "a").Return(flagged, uint64(7), nil)
+			},
+			want: "VALUE a 5 1 7\r\nv\r\nEND\r\n",
+		},
+		{
+			name:  "get deleted key",
+			input: "get a\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("GetWithVersion", "a").Return(nil, uint64(0), storage.ErrKeyDeleted)
+			},
+			want: "END\r\n",
+		},
+		{
+			name:  "set new key",
+			input: "set a 0 0 1\r\nv\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("SetWithOptions", "a", "v", storage.SetOptions{}).Return(true, nil)
+			},
+			want: "STORED\r\n",
+		},
+		{
+			name:  "set existing key with flags and exptime",
+			input: "set a 5 60 1\r\nv\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("SetWithOptions", "a", flagged, storage.SetOptions{TTL: time.Minute}).Return(false, nil)
+			},
+			want: "STORED\r\n",
+		},
+		{
+			name:  "set binary value",
+			input: "set a 0 0 1\r\n\xff\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("SetWithOptions", "a", storage.Blob{ContentType: storage.DefaultBlobContentType, Data: []byte{0xff}}, storage.SetOptions{}).Return(true, nil)
+			},
+			want: "STORED\r\n",
+		},
+		{
+			name:  "set noreply",
+			input: "set a 0 0 1 noreply\r\nv\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("SetWithOptions", "a", "v", storage.SetOptions{}).Return(true, nil)
+			},
+			want: "",
+		},
+		{
+			name:  "set expired",
+			input: "set a 0 -1 1\r\nv\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("SetWithOptions", "a", "v", storage.SetOptions{TTL: -time.Second}).Return(true, nil)
+			},
+			want: "STORED\r\n",
+		},
+		{
+			name:      "set too large",
+			input:     "set a 0 0 17\r\n01234567890123456\r\n",
+			mockSetup: func(m *MockStorage) {},
+			want:      "SERVER_ERROR object too large for cache\r\n",
+		},
+		{
+			name:      "set bad data chunk",
+			input:     "set a 0 0 1\r\nvv\r\n",
+			mockSetup: func(m *MockStorage) {},
+			want:      "CLIENT_ERROR bad data chunk\r\n",
+		},
+		{
+			name:      "set bad command line",
+			input:     "set a x 0 1\r\nv\r\n",
+			mockSetup: func(m *MockStorage) {},
+			want:      "CLIENT_ERROR bad command line format\r\n",
+		},
+		{
+			name:  "set expiry disabled",
+			input: "set a 0 60 1\r\nv\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("SetWithOptions", "a", "v", storage.SetOptions{TTL: time.Minute}).Return(false, storage.ErrExpiryDisabled)
+			},
+			want: "SERVER_ERROR expiry is disabled\r\n",
+		},
+		{
+			name:  "add existing key",
+			input: "add a 0 0 1\r\nv\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("SetWithOptions", "a", "v", storage.SetOptions{Mode: storage.SetIfAbsent}).Return(false, storage.ErrKeyAlreadyExists)
+			},
+			want: "NOT_STORED\r\n",
+		},
+		{
+			name:  "replace existing key",
+			input: "replace a 0 0 1\r\nv\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("SetWithOptions", "a", "v", storage.SetOptions{Mode: storage.SetIfPresent}).Return(false, nil)
+			},
+			want: "STORED\r\n",
+		},
+		{
+			name:  "replace missing key",
+			input: "replace a 0 0 1\r\nv\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("SetWithOptions", "a", "v", storage.SetOptions{Mode: storage.SetIfPresent}).Return(false, storage.ErrKeyNotFound)
+			},
+			want: "NOT_STORED\r\n",
+		},
+		{
+			name:  "cas",
+			input: "cas a 0 0 1 7\r\nv\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("SetWithOptions", "a", "v", storage.SetOptions{Mode: storage.SetIfPresent, Version: 7}).Return(false, nil)
+			},
+			want: "STORED\r\n",
+		},
+		{
+			name:  "cas changed value",
+			input: "cas a 0 0 1 7\r\nv\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("SetWithOptions", "a", "v", storage.SetOptions{Mode: storage.SetIfPresent, Version: 7}).Return(false, storage.ErrVersionMismatch)
+			},
+			want: "EXISTS\r\n",
+		},
+		{
+			name:  "cas missing key",
+			input: "cas a 0 0 1 7\r\nv\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("SetWithOptions", "a", "v", storage.SetOptions{Mode: storage.SetIfPresent, Version: 7}).Return(false, storage.ErrKeyNotFound)
+			},
+			want: "NOT_FOUND\r\n",
+		},
+		{
+			name:  "delete",
+			input: "delete a\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("Remove", "a").Return(true, nil)
+			},
+			want: "DELETED\r\n",
+		},
+		{
+			name:  "delete missing key",
+			input: "delete a\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("Remove", "a").Return(false, nil)
+			},
+			want: "NOT_FOUND\r\n",
+		},
+		{
+			name:  "incr",
+			input: "incr a 5\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("IncrCounter", "a", int64(5)).Return(int64(15), nil)
+			},
+			want: "15\r\n",
+		},
+		{
+			name:  "decr",
+			input: "decr a 5\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("IncrCounter", "a", int64(-5)).Return(int64(0), nil)
+			},
+			want: "0\r\n",
+		},
+		{
+			name:  "incr non-numeric value",
+			input: "incr a 1\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("IncrCounter", "a", int64(1)).Return(int64(0), storage.ErrNotInteger)
+			},
+			want: "CLIENT_ERROR cannot increment or decrement non-numeric value\r\n",
+		},
+		{
+			name:  "incr missing key",
+			input: "incr a 1\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("IncrCounter", "a", int64(1)).Return(int64(0), storage.ErrKeyNotFound)
+			},
+			want: "NOT_FOUND\r\n",
+		},
+		{
+			name:      "incr invalid delta",
+			input:     "incr a -1\r\n",
+			mockSetup: func(m *MockStorage) {},
+			want:      "CLIENT_ERROR invalid numeric delta argument\r\n",
+		},
+		{
+			name:  "touch",
+			input: "touch a 10\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("Expire", "a", 10*time.Second).Return(nil)
+			},
+			want: "TOUCHED\r\n",
+		},
+		{
+			name:  "touch without exptime",
+			input: "touch a 0\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("Expire", "a", time.Duration(0)).Return(nil)
+			},
+			want: "TOUCHED\r\n",
+		},
+		{
+			name:  "touch missing key",
+			input: "touch a 10\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("Expire", "a", 10*time.Second).Return(storage.ErrKeyNotFound)
+			},
+			want: "NOT_FOUND\r\n",
+		},
+		{
+			name:  "touch expired",
+			input: "touch a -1\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("Remove", "a").Return(true, nil)
+			},
+			want: "TOUCHED\r\n",
+		},
+		{
+			name:  "touch expired missing key",
+			input: "touch a -1\r\n",
+			mockSetup: func(m *MockStorage) {
+				m.On("Remove", "a").Return(false, nil)
+			},
+			want: "NOT_FOUND\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &MockStorage{}
+			tt.mockSetup(st)
+			s := newTestServer(st)
+
+			r := bufio.NewReader(strings.NewReader(tt.input))
+			line, err := r.ReadString('\n')
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			w := bufio.NewWriter(&buf)
+			quit, err := s.dispatch(r, w, line)
+			require.NoError(t, err)
+			require.NoError(t, w.Flush())
+
+			assert.Equal(t, tt.wantQuit, quit)
+			assert.Equal(t, tt.want, buf.String())
+			st.AssertExpectations(t)
+		})
+	}
+}
+
+func TestValidKey(t *testing.T) {
+	assert.True(t, validKey("user:1"))
+	assert.False(t, validKey(""))
+	assert.False(t, validKey("a\x00b"))
+	assert.False(t, validKey(strings.Repeat("k", maxKeyLen+1)))
+}
+
+func TestServer_Serve(t *testing.T) {
+	st := &MockStorage{}
+	st.On("SetWithOptions", "k", "v", storage.SetOptions{}).Return(true, nil)
+	st.On("GetWithVersion", "k").Return("v", uint64(1), nil)
+	s := newTestServer(st)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(listener) }()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Pipelined commands.
+	_, err = conn.Write([]byte("set k 0 0 1\r\nv\r\ngets k\r\n"))
+	require.NoError(t, err)
+
+	r := bufio.NewReader(conn)
+	for _, want := range []string{"STORED\r\n", "VALUE k 0 1 1\r\n", "v\r\n", "END\r\n"} {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, want, line)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, s.Shutdown(ctx))
+	assert.ErrorIs(t, <-served, ErrServerClosed)
+	st.AssertExpectations(t)
+}
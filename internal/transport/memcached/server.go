@@ -0,0 +1,219 @@
+package memcached
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+// ErrServerClosed is returned by ListenAndServe after Shutdown.
+var ErrServerClosed = errors.New("memcached: server closed")
+
+// readBufferSize is the size of the read buffer, which also limits the
+// length of command lines.
+const readBufferSize = 16 << 10
+
+// Storage is the contract for the KV storage served over the memcached
+// protocol.
+type Storage interface {
+	// SetWithOptions sets the value for the key on the condition of the
+	// options along with its expiration time and reports whether the key
+	// was created.
+	SetWithOptions(key string, value any, opts storage.SetOptions) (bool, error)
+	// Remove removes the key from the storage and reports whether it had a
+	// value.
+	Remove(key string) (bool, error)
+	// GetWithVersion returns the value for the key along with its version.
+	GetWithVersion(key string) (any, uint64, error)
+	// IncrCounter adds delta to the unsigned integer value of the key and
+	// returns the result.
+	IncrCounter(key string, delta int64) (int64, error)
+	// Expire sets the expiration time of the key to ttl from now or removes
+	// it if ttl is zero, an error if the key is not found.
+	Expire(key string, ttl time.Duration) error
+}
+
+// ValueValidator is the contract for validating values before they are
+// stored.
+type ValueValidator interface {
+	// Validate returns an error if the value is not valid for the key.
+	Validate(key string, value any) error
+}
+
+// Server serves the KV storage to memcached clients over the text protocol.
+// Items with zero flags are stored as JSON strings if they are valid UTF-8
+// and as blobs otherwise, items with flags are stored as blobs keeping the
+// flags in the content type. The CAS unique of an item is the version of its
+// value.
+type Server struct {
+	log         *slog.Logger
+	addr        string
+	timeout     time.Duration
+	maxItemSize int
+	storage     Storage
+	validator   ValueValidator
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewServer creates a new memcached server listening on addr. Items larger
+// than maxItemSize bytes are rejected. Idle connections are closed after
+// timeout, zero disables the timeout.
+func NewServer(log *slog.Logger, addr string, timeout time.Duration, maxItemSize int, storage Storage, validator ValueValidator) *Server {
+	return &Server{
+		log:         log,
+		addr:        addr,
+		timeout:     timeout,
+		maxItemSize: maxItemSize,
+		storage:     storage,
+		validator:   validator,
+		conns:       make(map[net.Conn]struct{}),
+	}
+}
+
+// ListenAndServe listens on the address of the server and serves the
+// connections until Shutdown is called.
+func (s *Server) ListenAndServe() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve serves the connections accepted by the listener until Shutdown is
+// called.
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		listener.Close()
+		return ErrServerClosed
+	}
+	s.listener = listener
+	s.mu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return ErrServerClosed
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.serveConn(conn)
+	}
+}
+
+// Shutdown stops accepting connections and closes the open ones once their
+// current commands are served or ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	// Interrupt the connections waiting for commands.
+	for conn := range s.conns {
+		conn.SetReadDeadline(time.Now())
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		s.wg.Done()
+	}()
+
+	r := bufio.NewReaderSize(conn, readBufferSize)
+	w := bufio.NewWriter(conn)
+
+	for {
+		if s.timeout > 0 && r.Buffered() == 0 {
+			conn.SetReadDeadline(time.Now().Add(s.timeout))
+		}
+
+		line, err := r.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			w.WriteString("CLIENT_ERROR line too long\r\n")
+			w.Flush()
+			return
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, os.ErrDeadlineExceeded) && !errors.Is(err, net.ErrClosed) {
+				s.log.Error("failed to read memcached command", slog.String("error", err.Error()))
+			}
+			return
+		}
+
+		quit, err := s.dispatch(r, w, string(line))
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, os.ErrDeadlineExceeded) && !errors.Is(err, net.ErrClosed) {
+				s.log.Error("failed to read memcached data block", slog.String("error", err.Error()))
+			}
+			return
+		}
+
+		// Pipelined commands are replied to at once.
+		if r.Buffered() == 0 || quit {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+		if quit || s.isClosed() {
+			return
+		}
+	}
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.closed
+}
@@ -0,0 +1,509 @@
+package resp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tmybsv/tarantool-kv/internal/schema"
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+const (
+	// defaultScanCount is the number of keys returned by SCAN by default.
+	defaultScanCount = 10
+	// maxScanCount is the maximum number of keys returned by SCAN at once.
+	maxScanCount = 1000
+)
+
+// command is a command handler. The arity is the number of arguments
+// including the command name, a negative arity is the minimum number.
+type command struct {
+	arity  int
+	handle func(s *Server, c *session, args []string)
+}
+
+var commands = map[string]command{
+	"PING":    {-1, (*Server).ping},
+	"HELLO":   {-1, (*Server).hello},
+	"SELECT":  {2, (*Server).selectDB},
+	"CLIENT":  {-2, (*Server).client},
+	"COMMAND": {-1, (*Server).command},
+	"GET":     {2, (*Server).get},
+	"SET":     {-3, (*Server).set},
+	"DEL":     {-2, (*Server).del},
+	"EXISTS":  {-2, (*Server).exists},
+	"MGET":    {-2, (*Server).mget},
+	"INCR":    {2, (*Server).incr},
+	"EXPIRE":  {3, (*Server).expire},
+	"TTL":     {2, (*Server).ttl},
+	"SCAN":    {-2, (*Server).scan},
+}
+
+// dispatch runs the command and reports whether the connection should be
+// closed.
+func (s *Server) dispatch(c *session, args []string) bool {
+	name := strings.ToUpper(args[0])
+	if name == "QUIT" {
+		c.w.writeSimple("OK")
+		return true
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		c.w.writeError(fmt.Sprintf("ERR unknown command '%s'", args[0]))
+		return false
+	}
+	if (cmd.arity > 0 && len(args) != cmd.arity) || len(args) < -cmd.arity {
+		c.w.writeError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+		return false
+	}
+
+	cmd.handle(s, c, args)
+	return false
+}
+
+func (s *Server) ping(c *session, args []string) {
+	switch len(args) {
+	case 1:
+		c.w.writeSimple("PONG")
+	case 2:
+		c.w.writeBulk(args[1])
+	default:
+		c.w.writeError("ERR wrong number of arguments for 'ping' command")
+	}
+}
+
+// hello switches the protocol version and replies with the server
+// properties.
+func (s *Server) hello(c *session, args []string) {
+	if len(args) > 1 {
+		proto, err := strconv.Atoi(args[1])
+		if err != nil || (proto != 2 && proto != 3) {
+			c.w.writeError("NOPROTO unsupported protocol version")
+			return
+		}
+
+		for i := 2; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "AUTH":
+				c.w.writeError("ERR AUTH is not supported")
+				return
+			case "SETNAME":
+				if i+1 == len(args) {
+					c.w.writeError("ERR syntax error")
+					return
+				}
+				i++
+			default:
+				c.w.writeError("ERR syntax error")
+				return
+			}
+		}
+
+		c.w.proto = proto
+	}
+
+	c.w.writeMap(7)
+	c.w.writeBulk("server")
+	c.w.writeBulk("tarantool-kv")
+	c.w.writeBulk("version")
+	c.w.writeBulk("1.0.0")
+	c.w.writeBulk("proto")
+	c.w.writeInt(int64(c.w.proto))
+	c.w.writeBulk("id")
+	c.w.writeInt(c.id)
+	c.w.writeBulk("mode")
+	c.w.writeBulk("standalone")
+	c.w.writeBulk("role")
+	c.w.writeBulk("master")
+	c.w.writeBulk("modules")
+	c.w.writeArray(0)
+}
+
+// selectDB accepts only the default database, there is a single one.
+func (s *Server) selectDB(c *session, args []string) {
+	if args[1] != "0" {
+		c.w.writeError("ERR DB index is out of range")
+		return
+	}
+	c.w.writeSimple("OK")
+}
+
+// client accepts the client name and library info sent by clients on
+// connect, they are not kept.
+func (s *Server) client(c *session, args []string) {
+	switch strings.ToUpper(args[1]) {
+	case "SETNAME", "SETINFO":
+		c.w.writeSimple("OK")
+	case "ID":
+		c.w.writeInt(c.id)
+	default:
+		c.w.writeError(fmt.Sprintf("ERR unknown subcommand '%s'", args[1]))
+	}
+}
+
+// command replies with no command docs to the clients requesting them on
+// connect.
+func (s *Server) command(c *session, _ []string) {
+	c.w.writeArray(0)
+}
+
+func (s *Server) get(c *session, args []string) {
+	value, ok := s.getValue(c, args[1])
+	if !ok {
+		return
+	}
+	if value == nil {
+		c.w.writeNull()
+		return
+	}
+	c.w.writeBulk(*value)
+}
+
+// set sets the value of the key. NX only sets a missing key and XX an
+// existing one, EX and PX set the expiration time in seconds or milliseconds
+// and KEEPTTL keeps the expiration time of an existing key, which is
+// removed otherwise.
+func (s *Server) set(c *session, args []string) {
+	key := args[1]
+
+	var nx, xx, keepTTL bool
+	var ttl time.Duration
+	for i := 3; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); opt {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "KEEPTTL":
+			keepTTL = true
+		case "EX", "PX":
+			if ttl != 0 || i+1 == len(args) {
+				c.w.writeError("ERR syntax error")
+				return
+			}
+			i++
+			n, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil {
+				c.w.writeError("ERR value is not an integer or out of range")
+				return
+			}
+			if n <= 0 || n > int64(time.Duration(1<<63-1)/time.Second) {
+				c.w.writeError("ERR invalid expire time in 'set' command")
+				return
+			}
+			ttl = time.Duration(n) * time.Millisecond
+			if opt == "EX" {
+				ttl = time.Duration(n) * time.Second
+			}
+		default:
+			c.w.writeError("ERR syntax error")
+			return
+		}
+	}
+	if (nx && xx) || (keepTTL && ttl != 0) {
+		c.w.writeError("ERR syntax error")
+		return
+	}
+
+	value := argValue(args[2])
+	if !s.validate(c, key, value) {
+		return
+	}
+
+	opts := storage.SetOptions{TTL: ttl, KeepTTL: keepTTL}
+	switch {
+	case nx:
+		opts.Mode = storage.SetIfAbsent
+	case xx:
+		opts.Mode = storage.SetIfPresent
+	}
+
+	// The value is set along with its expiration time.
+	_, err := s.storage.SetWithOptions(key, value, opts)
+	if errors.Is(err, storage.ErrKeyAlreadyExists) || errors.Is(err, storage.ErrKeyNotFound) {
+		c.w.writeNull()
+		return
+	}
+	if err != nil {
+		s.writeStorageErr(c, "set", err)
+		return
+	}
+
+	c.w.writeSimple("OK")
+}
+
+// del deletes the keys and replies with the number of deleted ones.
+func (s *Server) del(c *session, args []string) {
+	var deleted int64
+	for _, key := range args[1:] {
+		removed, err := s.storage.Remove(key)
+		if err != nil {
+			s.writeStorageErr(c, "delete", err)
+			return
+		}
+		if removed {
+			deleted++
+		}
+	}
+	c.w.writeInt(deleted)
+}
+
+// exists replies with the number of the keys that exist, a key is counted
+// as many times as it is given.
+func (s *Server) exists(c *session, args []string) {
+	var n int64
+	for _, key := range args[1:] {
+		exists, err := s.storage.Exists(key)
+		if err != nil {
+			s.writeStorageErr(c, "check", err)
+			return
+		}
+		if exists {
+			n++
+		}
+	}
+	c.w.writeInt(n)
+}
+
+func (s *Server) mget(c *session, args []string) {
+	values := make([]*string, 0, len(args)-1)
+	for _, key := range args[1:] {
+		value, ok := s.getValue(c, key)
+		if !ok {
+			return
+		}
+		values = append(values, value)
+	}
+
+	c.w.writeArray(len(values))
+	for _, value := range values {
+		if value == nil {
+			c.w.writeNull()
+			continue
+		}
+		c.w.writeBulk(*value)
+	}
+}
+
+func (s *Server) incr(c *session, args []string) {
+	n, err := s.storage.Incr(args[1], 1)
+	if err != nil {
+		s.writeStorageErr(c, "increment", err)
+		return
+	}
+	c.w.writeInt(n)
+}
+
+// expire sets the expiration time of the key in seconds, a non-positive one
+// deletes the key. It replies with 1 if the key exists and 0 otherwise.
+func (s *Server) expire(c *session, args []string) {
+	key := args[1]
+	seconds, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil || seconds > int64(time.Duration(1<<63-1)/time.Second) {
+		c.w.writeError("ERR value is not an integer or out of range")
+		return
+	}
+
+	if seconds <= 0 {
+		removed, err := s.storage.Remove(key)
+		if err != nil {
+			s.writeStorageErr(c, "expire", err)
+			return
+		}
+		c.w.writeInt(boolInt(removed))
+		return
+	}
+
+	err = s.storage.Expire(key, time.Duration(seconds)*time.Second)
+	if errors.Is(err, storage.ErrKeyNotFound) {
+		c.w.writeInt(0)
+		return
+	}
+	if err != nil {
+		s.writeStorageErr(c, "expire", err)
+		return
+	}
+	c.w.writeInt(1)
+}
+
+// ttl replies with the seconds left until the key expires, -1 if it does
+// not expire and -2 if it does not exist.
+func (s *Server) ttl(c *session, args []string) {
+	ttl, err := s.storage.TTL(args[1])
+	switch {
+	case errors.Is(err, storage.ErrKeyNotFound):
+		c.w.writeInt(-2)
+	case errors.Is(err, storage.ErrNoExpiry):
+		c.w.writeInt(-1)
+	case err != nil:
+		s.writeStorageErr(c, "get expiration of", err)
+	default:
+		c.w.writeInt(int64((ttl + time.Second/2) / time.Second))
+	}
+}
+
+// scan replies with the next cursor and a page of the keys matching the
+// optional MATCH pattern. The cursor is zero once the scan is complete. The
+// COUNT is the number of keys scanned, so a page may have fewer keys.
+func (s *Server) scan(c *session, args []string) {
+	cursor, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		c.w.writeError("ERR invalid cursor")
+		return
+	}
+
+	var after string
+	if cursor != 0 {
+		var ok bool
+		if after, ok = s.cursors.get(cursor); !ok {
+			c.w.writeError("ERR invalid cursor")
+			return
+		}
+	}
+
+	pattern, count, onlyStrings := "", defaultScanCount, true
+	for i := 2; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			c.w.writeError("ERR syntax error")
+			return
+		}
+
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			pattern = args[i+1]
+		case "COUNT":
+			count, err = strconv.Atoi(args[i+1])
+			if err != nil || count < 1 {
+				c.w.writeError("ERR syntax error")
+				return
+			}
+			count = min(count, maxScanCount)
+		case "TYPE":
+			// Every key holds a string.
+			onlyStrings = strings.EqualFold(args[i+1], "string")
+		default:
+			c.w.writeError("ERR syntax error")
+			return
+		}
+	}
+
+	var keys []string
+	if onlyStrings {
+		if keys, err = s.storage.Scan(after, uint32(count)); err != nil {
+			s.writeStorageErr(c, "scan", err)
+			return
+		}
+	}
+
+	next := uint64(0)
+	if len(keys) == count {
+		next = s.cursors.add(keys[len(keys)-1])
+	}
+
+	matched := keys[:0]
+	for _, key := range keys {
+		if pattern == "" || matchGlob(pattern, key) {
+			matched = append(matched, key)
+		}
+	}
+
+	c.w.writeArray(2)
+	c.w.writeBulk(strconv.FormatUint(next, 10))
+	c.w.writeArray(len(matched))
+	for _, key := range matched {
+		c.w.writeBulk(key)
+	}
+}
+
+// getValue returns the value of the key as a string, nil if the key does
+// not exist. It writes the error reply if the value cannot be read.
+func (s *Server) getValue(c *session, key string) (*string, bool) {
+	value, err := s.storage.Get(key)
+	if errors.Is(err, storage.ErrKeyNotFound) || errors.Is(err, storage.ErrKeyDeleted) {
+		return nil, true
+	}
+	if err != nil {
+		s.writeStorageErr(c, "get", err)
+		return nil, false
+	}
+
+	str, err := valueString(value)
+	if err != nil {
+		s.writeStorageErr(c, "read", err)
+		return nil, false
+	}
+	return &str, true
+}
+
+// validate validates the value of the key and writes the error reply if it
+// is not valid.
+func (s *Server) validate(c *session, key string, value any) bool {
+	if s.validator == nil {
+		return true
+	}
+	err := s.validator.Validate(key, value)
+	if err == nil {
+		return true
+	}
+
+	var verr *schema.ValidationError
+	if errors.As(err, &verr) {
+		c.w.writeError("ERR " + verr.Error())
+		return false
+	}
+
+	s.log.Error("failed to validate value", slog.String("error", err.Error()))
+	c.w.writeError("ERR internal error")
+	return false
+}
+
+func (s *Server) writeStorageErr(c *session, action string, err error) {
+	switch {
+	case errors.Is(err, storage.ErrNotInteger):
+		c.w.writeError("ERR value is not an integer or out of range")
+	case errors.Is(err, storage.ErrWrongType):
+		c.w.writeError("WRONGTYPE Operation against a key holding the wrong kind of value")
+	case errors.Is(err, storage.ErrExpiryDisabled):
+		c.w.writeError("ERR expiry is disabled")
+	default:
+		s.log.Error(fmt.Sprintf("failed to %s key", action), slog.String("error", err.Error()))
+		c.w.writeError("ERR internal error")
+	}
+}
+
+// argValue returns the value to store for the argument: a string if it is
+// valid UTF-8, which JSON strings must be, and a blob otherwise.
+func argValue(arg string) any {
+	if utf8.ValidString(arg) {
+		return arg
+	}
+	return storage.Blob{ContentType: storage.DefaultBlobContentType, Data: []byte(arg)}
+}
+
+// valueString returns the stored value as a string: strings and blobs as is
+// and other JSON values in their JSON encoding.
+func valueString(value any) (string, error) {
+	switch value := value.(type) {
+	case string:
+		return value, nil
+	case storage.Blob:
+		return string(value.Data), nil
+	default:
+		data, err := json.Marshal(value)
+		return string(data), err
+	}
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,362 @@
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"log/slog"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+type MockStorage struct {
+	mock.Mock
+}
+
+func (m *MockStorage) SetWithOptions(key string, value any, opts storage.SetOptions) (bool, error) {
+	args := m.Called(key, value, opts)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) Remove(key string) (bool, error) {
+	args := m.Called(key)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) Get(key string) (any, error) {
+	args := m.Called(key)
+	return args.Get(0), args.Error(1)
+}
+
+func (m *MockStorage) Exists(key string) (bool, error) {
+	args := m.Called(key)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStorage) Incr(key string, delta int64) (int64, error) {
+	args := m.Called(key, delta)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockStorage) Expire(key string, ttl time.Duration) error {
+	args := m.Called(key, ttl)
+	return args.Error(0)
+}
+
+func (m *MockStorage) TTL(key string) (time.Duration, error) {
+	args := m.Called(key)
+	return args.Get(0).(time.Duration), args.Error(1)
+}
+
+func (m *MockStorage) Scan(after string, limit uint32) ([]string, error) {
+	args := m.Called(after, limit)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func newTestServer(st Storage) *Server {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+	return NewServer(logger, "127.0.0.1:0", 0, st, nil)
+}
+
+func TestServer_dispatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		mockSetup func(*MockStorage)
+		want      string
+	}{
+		{
+			name:      "ping",
+			args:      []string{"ping"},
+			mockSetup: func(m *MockStorage) {},
+			want:      "+PONG\r\n",
+		},
+		{
+			name:      "unknown command",
+			args:      []string{"FLUSHALL"},
+			mockSetup: func(m *MockStorage) {},
+			want:      "-ERR unknown command 'FLUSHALL'\r\n",
+		},
+		{
+			name:      "wrong number of arguments",
+			args:      []string{"GET"},
+			mockSetup: func(m *MockStorage) {},
+			want:      "-ERR wrong number of arguments for 'get' command\r\n",
+		},
+		{
+			name: "get string",
+			args: []string{"GET", "k"},
+			mockSetup: func(m *MockStorage) {
+				m.On("Get", "k").Return("v", nil)
+			},
+			want: "$1\r\nv\r\n",
+		},
+		{
+			name: "get json value",
+			args: []string{"GET", "k"},
+			mockSetup: func(m *MockStorage) {
+				m.On("Get", "k").Return(map[string]any{"a": float64(1)}, nil)
+			},
+			want: "$7\r\n{\"a\":1}\r\n",
+		},
+		{
+			name: "get missing key",
+			args: []string{"GET", "k"},
+			mockSetup: func(m *MockStorage) {
+				m.On("Get", "k").Return(nil, storage.ErrKeyNotFound)
+			},
+			want: "$-1\r\n",
+		},
+		{
+			name: "set new key",
+			args: []string{"SET", "k", "v"},
+			mockSetup: func(m *MockStorage) {
+				m.On("SetWithOptions", "k", "v", storage.SetOptions{}).Return(true, nil)
+			},
+			want: "+OK\r\n",
+		},
+		{
+			name: "set keeping expiration",
+			args: []string{"SET", "k", "v", "KEEPTTL"},
+			mockSetup: func(m *MockStorage) {
+				m.On("SetWithOptions", "k", "v", storage.SetOptions{KeepTTL: true}).Return(false, nil)
+			},
+			want: "+OK\r\n",
+		},
+		{
+			name: "set binary value as blob",
+			args: []string{"SET", "k", "\xff\x00"},
+			mockSetup: func(m *MockStorage) {
+				m.On("SetWithOptions", "k", storage.Blob{ContentType: storage.DefaultBlobContentType, Data: []byte("\xff\x00")}, storage.SetOptions{}).Return(true, nil)
+			},
+			want: "+OK\r\n",
+		},
+		{
+			name: "set nx existing key",
+			args: []string{"SET", "k", "v", "NX"},
+			mockSetup: func(m *MockStorage) {
+				m.On("SetWithOptions", "k", "v", storage.SetOptions{Mode: storage.SetIfAbsent}).Return(false, storage.ErrKeyAlreadyExists)
+			},
+			want: "$-1\r\n",
+		},
+		{
+			name: "set xx with expiration",
+			args: []string{"SET", "k", "v", "xx", "EX", "10"},
+			mockSetup: func(m *MockStorage) {
+				m.On("SetWithOptions", "k", "v", storage.SetOptions{Mode: storage.SetIfPresent, TTL: 10 * time.Second}).Return(false, nil)
+			},
+			want: "+OK\r\n",
+		},
+		{
+			name: "set xx missing key",
+			args: []string{"SET", "k", "v", "XX", "PX", "1500"},
+			mockSetup: func(m *MockStorage) {
+				m.On("SetWithOptions", "k", "v", storage.SetOptions{Mode: storage.SetIfPresent, TTL: 1500 * time.Millisecond}).Return(false, storage.ErrKeyNotFound)
+			},
+			want: "$-1\r\n",
+		},
+		{
+			name:      "set nx and xx",
+			args:      []string{"SET", "k", "v", "NX", "XX"},
+			mockSetup: func(m *MockStorage) {},
+			want:      "-ERR syntax error\r\n",
+		},
+		{
+			name:      "set invalid expiration",
+			args:      []string{"SET", "k", "v", "PX", "0"},
+			mockSetup: func(m *MockStorage) {},
+			want:      "-ERR invalid expire time in 'set' command\r\n",
+		},
+		{
+			name: "del",
+			args: []string{"DEL", "a", "b"},
+			mockSetup: func(m *MockStorage) {
+				m.On("Remove", "a").Return(true, nil)
+				m.On("Remove", "b").Return(false, nil)
+			},
+			want: ":1\r\n",
+		},
+		{
+			name: "exists counts repeated keys",
+			args: []string{"EXISTS", "a", "a", "b"},
+			mockSetup: func(m *MockStorage) {
+				m.On("Exists", "a").Return(true, nil)
+				m.On("Exists", "b").Return(false, nil)
+			},
+			want: ":2\r\n",
+		},
+		{
+			name: "mget",
+			args: []string{"MGET", "a", "b"},
+			mockSetup: func(m *MockStorage) {
+				m.On("Get", "a").Return("1", nil)
+				m.On("Get", "b").Return(nil, storage.ErrKeyDeleted)
+			},
+			want: "*2\r\n$1\r\n1\r\n$-1\r\n",
+		},
+		{
+			name: "incr",
+			args: []string{"INCR", "n"},
+			mockSetup: func(m *MockStorage) {
+				m.On("Incr", "n", int64(1)).Return(int64(5), nil)
+			},
+			want: ":5\r\n",
+		},
+		{
+			name: "incr not integer",
+			args: []string{"INCR", "n"},
+			mockSetup: func(m *MockStorage) {
+				m.On("Incr", "n", int64(1)).Return(int64(0), storage.ErrNotInteger)
+			},
+			want: "-ERR value is not an integer or out of range\r\n",
+		},
+		{
+			name: "expire missing key",
+			args: []string{"EXPIRE", "k", "10"},
+			mockSetup: func(m *MockStorage) {
+				m.On("Expire", "k", 10*time.Second).Return(storage.ErrKeyNotFound)
+			},
+			want: ":0\r\n",
+		},
+		{
+			name: "expire in the past deletes key",
+			args: []string{"EXPIRE", "k", "-1"},
+			mockSetup: func(m *MockStorage) {
+				m.On("Remove", "k").Return(true, nil)
+			},
+			want: ":1\r\n",
+		},
+		{
+			name: "ttl",
+			args: []string{"TTL", "k"},
+			mockSetup: func(m *MockStorage) {
+				m.On("TTL", "k").Return(9600*time.Millisecond, nil)
+			},
+			want: ":10\r\n",
+		},
+		{
+			name: "ttl without expiration",
+			args: []string{"TTL", "k"},
+			mockSetup: func(m *MockStorage) {
+				m.On("TTL", "k").Return(time.Duration(0), storage.ErrNoExpiry)
+			},
+			want: ":-1\r\n",
+		},
+		{
+			name: "ttl missing key",
+			args: []string{"TTL", "k"},
+			mockSetup: func(m *MockStorage) {
+				m.On("TTL", "k").Return(time.Duration(0), storage.ErrKeyNotFound)
+			},
+			want: ":-2\r\n",
+		},
+		{
+			name: "scan last page",
+			args: []string{"SCAN", "0", "MATCH", "user:*", "COUNT", "3"},
+			mockSetup: func(m *MockStorage) {
+				m.On("Scan", "", uint32(3)).Return([]string{"order:1", "user:1"}, nil)
+			},
+			want: "*2\r\n$1\r\n0\r\n*1\r\n$6\r\nuser:1\r\n",
+		},
+		{
+			name:      "scan unknown cursor",
+			args:      []string{"SCAN", "42"},
+			mockSetup: func(m *MockStorage) {},
+			want:      "-ERR invalid cursor\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &MockStorage{}
+			tt.mockSetup(st)
+			s := newTestServer(st)
+
+			var buf bytes.Buffer
+			c := &session{id: 1, w: newWriter(&buf)}
+			s.dispatch(c, tt.args)
+			require.NoError(t, c.w.flush())
+
+			assert.Equal(t, tt.want, buf.String())
+			st.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServer_scanCursor(t *testing.T) {
+	st := &MockStorage{}
+	st.On("Scan", "", uint32(2)).Return([]string{"a", "b"}, nil)
+	st.On("Scan", "b", uint32(2)).Return([]string{"c"}, nil)
+	s := newTestServer(st)
+
+	var buf bytes.Buffer
+	c := &session{id: 1, w: newWriter(&buf)}
+
+	s.dispatch(c, []string{"SCAN", "0", "COUNT", "2"})
+	require.NoError(t, c.w.flush())
+	assert.Equal(t, "*2\r\n$1\r\n1\r\n*2\r\n$1\r\na\r\n$1\r\nb\r\n", buf.String())
+
+	buf.Reset()
+	s.dispatch(c, []string{"SCAN", "1", "COUNT", "2"})
+	require.NoError(t, c.w.flush())
+	assert.Equal(t, "*2\r\n$1\r\n0\r\n*1\r\n$1\r\nc\r\n", buf.String())
+	st.AssertExpectations(t)
+}
+
+func TestServer_hello(t *testing.T) {
+	s := newTestServer(&MockStorage{})
+
+	var buf bytes.Buffer
+	c := &session{id: 7, w: newWriter(&buf)}
+
+	s.dispatch(c, []string{"HELLO", "3"})
+	s.dispatch(c, []string{"HELLO", "4"})
+	require.NoError(t, c.w.flush())
+
+	assert.Equal(t, 3, c.w.proto)
+	assert.Contains(t, buf.String(), "%7\r\n")
+	assert.Contains(t, buf.String(), "$5\r\nproto\r\n:3\r\n$2\r\nid\r\n:7\r\n")
+	assert.Contains(t, buf.String(), "-NOPROTO unsupported protocol version\r\n")
+}
+
+func TestServer_Serve(t *testing.T) {
+	st := &MockStorage{}
+	st.On("Get", "k").Return("v", nil)
+	s := newTestServer(st)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(listener) }()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Pipelined commands.
+	_, err = conn.Write([]byte("PING\r\n*2\r\n$3\r\nGET\r\n$1\r\nk\r\n"))
+	require.NoError(t, err)
+
+	r := bufio.NewReader(conn)
+	for _, want := range []string{"+PONG\r\n", "$1\r\n", "v\r\n"} {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, want, line)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, s.Shutdown(ctx))
+	assert.ErrorIs(t, <-served, ErrServerClosed)
+	st.AssertExpectations(t)
+}
@@ -0,0 +1,84 @@
+package resp
+
+// matchGlob reports whether the string matches the glob-style pattern of
+// Redis: * matches any sequence, ? any single character, [abc] and [a-z]
+// character classes negated with ^, and \ escapes the next character. Unlike
+// path.Match, the pattern has no separators and a malformed pattern simply
+// does not match.
+func matchGlob(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchGlob(pattern, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			end, ok := matchClass(pattern[1:], s[0])
+			if !ok {
+				return false
+			}
+			pattern, s = pattern[1+end:], s[1:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		}
+	}
+	return len(s) == 0
+}
+
+// matchClass matches the character against the class following '[' and
+// returns the length of the class including the closing ']'.
+func matchClass(class string, c byte) (int, bool) {
+	negate := len(class) > 0 && class[0] == '^'
+	i := 0
+	if negate {
+		i++
+	}
+
+	matched := false
+	for ; i < len(class) && class[i] != ']'; i++ {
+		switch {
+		case class[i] == '\\' && i+1 < len(class):
+			i++
+			matched = matched || class[i] == c
+		case i+2 < len(class) && class[i+1] == '-' && class[i+2] != ']':
+			lo, hi := class[i], class[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			matched = matched || (lo <= c && c <= hi)
+			i += 2
+		default:
+			matched = matched || class[i] == c
+		}
+	}
+	if i == len(class) {
+		return 0, false
+	}
+
+	return i + 1, matched != negate
+}
@@ -0,0 +1,35 @@
+package resp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{"*", "user:1/profile", true},
+		{"user:*", "user:1", true},
+		{"user:*", "order:1", false},
+		{"user:?", "user:1", true},
+		{"user:?", "user:10", false},
+		{"*:[0-9]", "user:7", true},
+		{"*:[^0-9]", "user:7", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{`a\*b`, "a*b", true},
+		{`a\*b`, "axb", false},
+		{"[abc", "a", false},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.s, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchGlob(tt.pattern, tt.s))
+		})
+	}
+}
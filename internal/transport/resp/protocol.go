@@ -0,0 +1,176 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxBulkLen is the maximum length of a bulk string in a command.
+	maxBulkLen = 64 << 20
+	// maxArgs is the maximum number of arguments of a command.
+	maxArgs = 1 << 20
+	// preallocArgs bounds the arguments allocated up front, the count sent
+	// by the client is only trusted as the arguments arrive.
+	preallocArgs = 64
+	// readBufferSize is the size of the read buffer, which also limits the
+	// length of inline commands.
+	readBufferSize = 64 << 10
+)
+
+// errProtocol is returned when the client violates the protocol. The
+// connection is closed after replying with the error.
+var errProtocol = errors.New("Protocol error")
+
+// reader reads the commands sent by a client, either as arrays of bulk
+// strings or as inline commands separated by spaces.
+type reader struct {
+	r *bufio.Reader
+}
+
+func newReader(r io.Reader) *reader {
+	return &reader{r: bufio.NewReaderSize(r, readBufferSize)}
+}
+
+// buffered reports whether a pipelined command is waiting to be read.
+func (r *reader) buffered() bool {
+	return r.r.Buffered() > 0
+}
+
+// readCommand reads the next command with its arguments, skipping empty
+// inline commands.
+func (r *reader) readCommand() ([]string, error) {
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+
+		if !strings.HasPrefix(line, "*") {
+			if args := strings.Fields(line); len(args) > 0 {
+				return args, nil
+			}
+			continue
+		}
+
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n > maxArgs {
+			return nil, fmt.Errorf("%w: invalid multibulk length", errProtocol)
+		}
+		if n <= 0 {
+			continue
+		}
+
+		args := make([]string, 0, min(n, preallocArgs))
+		for range n {
+			arg, err := r.readBulk()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+		return args, nil
+	}
+}
+
+func (r *reader) readBulk() (string, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.HasPrefix(line, "$") {
+		return "", fmt.Errorf("%w: expected '$', got '%.1s'", errProtocol, line)
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 || n > maxBulkLen {
+		return "", fmt.Errorf("%w: invalid bulk length", errProtocol)
+	}
+
+	buf := make([]byte, n+2)
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		return "", err
+	}
+	if buf[n] != '\r' || buf[n+1] != '\n' {
+		return "", fmt.Errorf("%w: bulk string is not terminated by CRLF", errProtocol)
+	}
+	return string(buf[:n]), nil
+}
+
+// readLine reads a line terminated by CRLF or LF and returns it without the
+// terminator.
+func (r *reader) readLine() (string, error) {
+	line, err := r.r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return "", fmt.Errorf("%w: too big inline request", errProtocol)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	line = line[:len(line)-1]
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		line = line[:len(line)-1]
+	}
+	return string(line), nil
+}
+
+// writer writes the replies in the protocol version negotiated by the
+// client. Write errors are reported by flush.
+type writer struct {
+	w *bufio.Writer
+	// proto is the protocol version, 2 or 3.
+	proto int
+}
+
+func newWriter(w io.Writer) *writer {
+	return &writer{w: bufio.NewWriter(w), proto: 2}
+}
+
+func (w *writer) flush() error {
+	return w.w.Flush()
+}
+
+func (w *writer) writeSimple(s string) {
+	w.w.WriteString("+" + s + "\r\n")
+}
+
+// writeError writes the error reply, msg starts with the error code such as
+// ERR.
+func (w *writer) writeError(msg string) {
+	w.w.WriteString("-" + strings.NewReplacer("\r", " ", "\n", " ").Replace(msg) + "\r\n")
+}
+
+func (w *writer) writeInt(n int64) {
+	w.w.WriteString(":" + strconv.FormatInt(n, 10) + "\r\n")
+}
+
+func (w *writer) writeBulk(s string) {
+	w.w.WriteString("$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n")
+}
+
+func (w *writer) writeNull() {
+	if w.proto == 3 {
+		w.w.WriteString("_\r\n")
+		return
+	}
+	w.w.WriteString("$-1\r\n")
+}
+
+func (w *writer) writeArray(n int) {
+	w.w.WriteString("*" + strconv.Itoa(n) + "\r\n")
+}
+
+// writeMap writes the header of a map of n pairs, which is an array of keys
+// followed by their values in RESP2.
+func (w *writer) writeMap(n int) {
+	if w.proto == 3 {
+		w.w.WriteString("%" + strconv.Itoa(n) + "\r\n")
+		return
+	}
+	w.writeArray(2 * n)
+}
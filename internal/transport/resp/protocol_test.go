@@ -0,0 +1,99 @@
+package resp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReader_readCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "multibulk",
+			input: "*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nva\r\nl\r\n",
+			want:  []string{"SET", "key", "va\r\nl"},
+		},
+		{
+			name:  "inline",
+			input: "PING  hello\r\n",
+			want:  []string{"PING", "hello"},
+		},
+		{
+			name:  "empty lines skipped",
+			input: "\r\n*0\r\nPING\n",
+			want:  []string{"PING"},
+		},
+		{
+			name:    "invalid multibulk length",
+			input:   "*x\r\n",
+			wantErr: true,
+		},
+		{
+			name:    "missing bulk",
+			input:   "*1\r\n:1\r\n",
+			wantErr: true,
+		},
+		{
+			name:    "unterminated bulk",
+			input:   "*1\r\n$3\r\nGETxx",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := newReader(strings.NewReader(tt.input)).readCommand()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, args)
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	tests := []struct {
+		name  string
+		proto int
+		want  string
+	}{
+		{
+			name:  "resp2",
+			proto: 2,
+			want:  "+OK\r\n-ERR bad input\r\n:42\r\n$3\r\nabc\r\n$-1\r\n*4\r\n",
+		},
+		{
+			name:  "resp3",
+			proto: 3,
+			want:  "+OK\r\n-ERR bad input\r\n:42\r\n$3\r\nabc\r\n_\r\n%2\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := newWriter(&buf)
+			w.proto = tt.proto
+
+			w.writeSimple("OK")
+			w.writeError("ERR bad\ninput")
+			w.writeInt(42)
+			w.writeBulk("abc")
+			w.writeNull()
+			w.writeMap(2)
+			require.NoError(t, w.flush())
+
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
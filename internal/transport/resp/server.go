@@ -0,0 +1,254 @@
+package resp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+// ErrServerClosed is returned by ListenAndServe after Shutdown.
+var ErrServerClosed = errors.New("resp: server closed")
+
+// Storage is the contract for the KV storage served over RESP.
+type Storage interface {
+	// SetWithOptions sets the value for the key along with its expiration
+	// time on the condition of the mode of the options.
+	SetWithOptions(key string, value any, opts storage.SetOptions) (bool, error)
+	// Remove removes the key from the storage and reports whether it had a
+	// value.
+	Remove(key string) (bool, error)
+	// Get returns the value for the key or an error if the key is not found.
+	Get(key string) (any, error)
+	// Exists reports whether the key has a value.
+	Exists(key string) (bool, error)
+	// Incr adds delta to the integer value of the key and returns the result.
+	Incr(key string, delta int64) (int64, error)
+	// Expire sets the expiration time of the key to ttl from now.
+	Expire(key string, ttl time.Duration) error
+	// TTL returns the time left until the key expires.
+	TTL(key string) (time.Duration, error)
+	// Scan returns up to limit keys following the after key.
+	Scan(after string, limit uint32) ([]string, error)
+}
+
+// ValueValidator is the contract for validating values before they are
+// stored.
+type ValueValidator interface {
+	// Validate returns an error if the value is not valid for the key.
+	Validate(key string, value any) error
+}
+
+// Server serves the KV storage to Redis clients over RESP2 and RESP3.
+// String values are stored as JSON strings and binary ones as blobs, other
+// JSON values are read as their JSON encoding.
+type Server struct {
+	log       *slog.Logger
+	addr      string
+	timeout   time.Duration
+	storage   Storage
+	validator ValueValidator
+	cursors   *cursors
+	clientID  atomic.Int64
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewServer creates a new RESP server listening on addr. Idle connections
+// are closed after timeout, zero disables the timeout.
+func NewServer(log *slog.Logger, addr string, timeout time.Duration, storage Storage, validator ValueValidator) *Server {
+	return &Server{
+		log:       log,
+		addr:      addr,
+		timeout:   timeout,
+		storage:   storage,
+		validator: validator,
+		cursors:   newCursors(),
+		conns:     make(map[net.Conn]struct{}),
+	}
+}
+
+// ListenAndServe listens on the address of the server and serves the
+// connections until Shutdown is called.
+func (s *Server) ListenAndServe() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve serves the connections accepted by the listener until Shutdown is
+// called.
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		listener.Close()
+		return ErrServerClosed
+	}
+	s.listener = listener
+	s.mu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return ErrServerClosed
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.serveConn(conn)
+	}
+}
+
+// Shutdown stops accepting connections and closes the open ones once their
+// current commands are served or ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	// Interrupt the connections waiting for commands.
+	for conn := range s.conns {
+		conn.SetReadDeadline(time.Now())
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// session is the state of a client connection.
+type session struct {
+	id int64
+	r  *reader
+	w  *writer
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		s.wg.Done()
+	}()
+
+	c := &session{
+		id: s.clientID.Add(1),
+		r:  newReader(conn),
+		w:  newWriter(conn),
+	}
+
+	for {
+		if s.timeout > 0 && !c.r.buffered() {
+			conn.SetReadDeadline(time.Now().Add(s.timeout))
+		}
+
+		args, err := c.r.readCommand()
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				c.w.writeError("ERR " + err.Error())
+				c.w.flush()
+			} else if !errors.Is(err, io.EOF) && !errors.Is(err, os.ErrDeadlineExceeded) && !errors.Is(err, net.ErrClosed) {
+				s.log.Error("failed to read RESP command", slog.String("error", err.Error()))
+			}
+			return
+		}
+
+		quit := s.dispatch(c, args)
+
+		// Pipelined commands are replied to at once.
+		if !c.r.buffered() || quit {
+			if err := c.w.flush(); err != nil {
+				return
+			}
+		}
+		if quit || s.isClosed() {
+			return
+		}
+	}
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.closed
+}
+
+// maxCursors is the number of the most recent SCAN cursors remembered.
+const maxCursors = 4096
+
+// cursors maps the numeric SCAN cursors handed out to clients, which Redis
+// clients expect to be integers, to the last keys returned, after which the
+// scans continue. The oldest cursors are forgotten.
+type cursors struct {
+	mu   sync.Mutex
+	last uint64
+	keys map[uint64]string
+}
+
+func newCursors() *cursors {
+	return &cursors{keys: make(map[uint64]string)}
+}
+
+func (c *cursors) add(key string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.last++
+	c.keys[c.last] = key
+	delete(c.keys, c.last-maxCursors)
+	return c.last
+}
+
+func (c *cursors) get(cursor uint64) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key, ok := c.keys[cursor]
+	return key, ok
+}
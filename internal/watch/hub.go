@@ -0,0 +1,226 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+const (
+	// fetchBatchSize is the maximum number of events read from the source at
+	// once.
+	fetchBatchSize = 256
+	// subscriberBufferSize is the number of events buffered for a subscriber
+	// before it is considered too slow and dropped.
+	subscriberBufferSize = 1024
+	// pollInterval is the interval of fetching events when no notification
+	// arrives, e.g. while the connection to Tarantool is being re-established.
+	pollInterval = 5 * time.Second
+)
+
+// ErrHubClosed is returned when subscribing to a stopped hub.
+var ErrHubClosed = errors.New("events hub is closed")
+
+// Source is the contract for the storage of change events.
+type Source interface {
+	// Events returns up to limit events with IDs greater than afterID.
+	Events(afterID uint64, limit uint32) ([]storage.Event, error)
+	// LastEventID returns the ID of the most recent event.
+	LastEventID() (uint64, error)
+}
+
+type subscriber struct {
+	ch chan storage.Event
+}
+
+// Hub fetches change events from the source and fans them out to the
+// subscribers.
+type Hub struct {
+	log    *slog.Logger
+	source Source
+	notify chan struct{}
+
+	mu     sync.Mutex
+	subs   map[*subscriber]struct{}
+	lastID uint64
+	closed bool
+}
+
+// NewHub creates a new events hub.
+func NewHub(log *slog.Logger, source Source) *Hub {
+	return &Hub{
+		log:    log,
+		source: source,
+		notify: make(chan struct{}, 1),
+		subs:   make(map[*subscriber]struct{}),
+	}
+}
+
+// Start loads the ID of the most recent event and starts dispatching new
+// events until ctx is done.
+func (h *Hub) Start(ctx context.Context) error {
+	lastID, err := h.source.LastEventID()
+	if err != nil {
+		return err
+	}
+	h.lastID = lastID
+
+	go h.run(ctx)
+	return nil
+}
+
+// Notify wakes the hub up to fetch new events. It never blocks.
+func (h *Hub) Notify() {
+	select {
+	case h.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Subscribe returns a channel of events with IDs greater than afterID. If
+// afterID is zero, only events that happen after the call are delivered. The
+// channel is closed when ctx is done, the hub is stopped or the subscriber
+// falls too far behind, in which case it is expected to resubscribe from the
+// last received event.
+func (h *Hub) Subscribe(ctx context.Context, afterID uint64) (<-chan storage.Event, error) {
+	sub := &subscriber{ch: make(chan storage.Event, subscriberBufferSize)}
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil, ErrHubClosed
+	}
+	h.subs[sub] = struct{}{}
+	head := h.lastID
+	h.mu.Unlock()
+
+	cursor := afterID
+	if cursor == 0 || cursor > head {
+		cursor = head
+	}
+
+	var backlog []storage.Event
+	for cursor < head {
+		events, err := h.source.Events(cursor, fetchBatchSize)
+		if err != nil {
+			h.unsubscribe(sub)
+			return nil, err
+		}
+		if len(events) == 0 {
+			break
+		}
+		backlog = append(backlog, events...)
+		cursor = events[len(events)-1].ID
+	}
+
+	out := make(chan storage.Event)
+	go func() {
+		defer close(out)
+		defer h.unsubscribe(sub)
+
+		for _, event := range backlog {
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		for {
+			select {
+			case event, ok := <-sub.ch:
+				if !ok {
+					return
+				}
+				if event.ID <= cursor {
+					continue
+				}
+				cursor = event.ID
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (h *Hub) unsubscribe(sub *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
+
+func (h *Hub) run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			h.close()
+			return
+		case <-h.notify:
+		case <-ticker.C:
+		}
+		h.dispatch()
+	}
+}
+
+func (h *Hub) dispatch() {
+	for {
+		h.mu.Lock()
+		lastID := h.lastID
+		h.mu.Unlock()
+
+		events, err := h.source.Events(lastID, fetchBatchSize)
+		if err != nil {
+			h.log.Error("failed to fetch events", slog.String("error", err.Error()))
+			return
+		}
+		if len(events) == 0 {
+			return
+		}
+
+		h.mu.Lock()
+		for _, event := range events {
+			for sub := range h.subs {
+				select {
+				case sub.ch <- event:
+				default:
+					h.log.Warn("dropping slow events subscriber")
+					delete(h.subs, sub)
+					close(sub.ch)
+				}
+			}
+		}
+		h.lastID = events[len(events)-1].ID
+		h.mu.Unlock()
+
+		if len(events) < fetchBatchSize {
+			return
+		}
+	}
+}
+
+func (h *Hub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subs {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
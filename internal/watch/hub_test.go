@@ -0,0 +1,123 @@
+package watch
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmybsv/tarantool-kv/internal/storage"
+)
+
+type fakeSource struct {
+	mu     sync.Mutex
+	events []storage.Event
+}
+
+func (s *fakeSource) Events(afterID uint64, limit uint32) ([]storage.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []storage.Event
+	for _, e := range s.events {
+		if e.ID > afterID && uint32(len(res)) < limit {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+
+func (s *fakeSource) LastEventID() (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.events) == 0 {
+		return 0, nil
+	}
+	return s.events[len(s.events)-1].ID, nil
+}
+
+func (s *fakeSource) add(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, storage.Event{
+		ID:   uint64(len(s.events) + 1),
+		Type: storage.EventSet,
+		Key:  key,
+	})
+}
+
+func receive(t *testing.T, ch <-chan storage.Event) storage.Event {
+	t.Helper()
+	select {
+	case e, ok := <-ch:
+		require.True(t, ok, "channel closed")
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+	return storage.Event{}
+}
+
+func TestHub_Subscribe(t *testing.T) {
+	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	source := &fakeSource{}
+	source.add("a")
+	source.add("b")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hub := NewHub(log, source)
+	require.NoError(t, hub.Start(ctx))
+
+	t.Run("new events only", func(t *testing.T) {
+		events, err := hub.Subscribe(ctx, 0)
+		require.NoError(t, err)
+
+		source.add("c")
+		hub.Notify()
+
+		assert.Equal(t, "c", receive(t, events).Key)
+	})
+
+	t.Run("resume from last event ID", func(t *testing.T) {
+		events, err := hub.Subscribe(ctx, 1)
+		require.NoError(t, err)
+
+		assert.Equal(t, "b", receive(t, events).Key)
+		assert.Equal(t, "c", receive(t, events).Key)
+
+		source.add("d")
+		hub.Notify()
+
+		assert.Equal(t, "d", receive(t, events).Key)
+	})
+}
+
+func TestHub_StopClosesSubscribers(t *testing.T) {
+	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	hub := NewHub(log, &fakeSource{})
+	require.NoError(t, hub.Start(ctx))
+
+	events, err := hub.Subscribe(context.Background(), 0)
+	require.NoError(t, err)
+
+	cancel()
+
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("subscriber channel was not closed")
+	}
+}